@@ -6,6 +6,7 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/AlphaHat/gcp-alpha-hat/component"
 
@@ -16,60 +17,62 @@ import (
 	"github.com/AlphaHat/gcp-alpha-hat/term"
 )
 
-func SetTimeRangeParameters(tr component.QueryComponent) component.QueryComponent {
+// SetTimeRangeParameters resolves the dates of a time range, with relative
+// ranges such as LTM ending on the business day before asOf
+func SetTimeRangeParameters(tr component.QueryComponent, asOf time.Time) component.QueryComponent {
 
 	switch tr.QueryComponentCanonicalName {
 	case "from {Date} to {Date}":
-		tr.QueryComponentParams[0] = timeseries.ParseToFirstCD(tr.QueryComponentParams[0])
-		tr.QueryComponentParams[1] = timeseries.ParseToLastCD(tr.QueryComponentParams[1])
+		tr.QueryComponentParams[0] = timeseries.ParseToFirstCD(tr.QueryComponentParams[0], asOf)
+		tr.QueryComponentParams[1] = timeseries.ParseToLastCD(tr.QueryComponentParams[1], asOf)
 	case "since {Date}":
-		tr.QueryComponentParams[0] = timeseries.ParseToLastCD(tr.QueryComponentParams[0])
-		tr.QueryComponentParams = append(tr.QueryComponentParams, timeseries.GetLastBD())
+		tr.QueryComponentParams[0] = timeseries.ParseToLastCD(tr.QueryComponentParams[0], asOf)
+		tr.QueryComponentParams = append(tr.QueryComponentParams, timeseries.GetLastBD(asOf))
 	case "on {Date}":
-		tr.QueryComponentParams[0] = timeseries.ParseToFirstCD(tr.QueryComponentParams[0])
-		tr.QueryComponentParams = append(tr.QueryComponentParams, timeseries.ParseToLastCD(tr.QueryComponentParams[0]))
+		tr.QueryComponentParams[0] = timeseries.ParseToFirstCD(tr.QueryComponentParams[0], asOf)
+		tr.QueryComponentParams = append(tr.QueryComponentParams, timeseries.ParseToLastCD(tr.QueryComponentParams[0], asOf))
 	case "YTD":
-		date1, date2 := timeseries.GetYTD()
+		date1, date2 := timeseries.GetYTD(asOf)
 		tr.QueryComponentParams = make([]string, 2, 2)
 		tr.QueryComponentParams[0] = date1
 		tr.QueryComponentParams[1] = date2
 	case "LTM":
-		date1, date2 := timeseries.GetLTM()
+		date1, date2 := timeseries.GetLTM(asOf)
 		tr.QueryComponentParams = make([]string, 2, 2)
 		tr.QueryComponentParams[0] = date1
 		tr.QueryComponentParams[1] = date2
 	case "Last Month":
-		date1, date2 := timeseries.GetLastMonths(1)
+		date1, date2 := timeseries.GetLastMonths(1, asOf)
 		tr.QueryComponentParams = make([]string, 2, 2)
 		tr.QueryComponentParams[0] = date1
 		tr.QueryComponentParams[1] = date2
 	case "Last Three Months":
-		date1, date2 := timeseries.GetLastMonths(3)
+		date1, date2 := timeseries.GetLastMonths(3, asOf)
 		tr.QueryComponentParams = make([]string, 2, 2)
 		tr.QueryComponentParams[0] = date1
 		tr.QueryComponentParams[1] = date2
 	case "Last Six Months":
-		date1, date2 := timeseries.GetLastMonths(6)
+		date1, date2 := timeseries.GetLastMonths(6, asOf)
 		tr.QueryComponentParams = make([]string, 2, 2)
 		tr.QueryComponentParams[0] = date1
 		tr.QueryComponentParams[1] = date2
 	case "Last Two Years":
-		date1, date2 := timeseries.GetLastMonths(24)
+		date1, date2 := timeseries.GetLastMonths(24, asOf)
 		tr.QueryComponentParams = make([]string, 2, 2)
 		tr.QueryComponentParams[0] = date1
 		tr.QueryComponentParams[1] = date2
 	case "Last Three Years":
-		date1, date2 := timeseries.GetLastMonths(36)
+		date1, date2 := timeseries.GetLastMonths(36, asOf)
 		tr.QueryComponentParams = make([]string, 2, 2)
 		tr.QueryComponentParams[0] = date1
 		tr.QueryComponentParams[1] = date2
 	case "Last Five Years":
-		date1, date2 := timeseries.GetLastMonths(60)
+		date1, date2 := timeseries.GetLastMonths(60, asOf)
 		tr.QueryComponentParams = make([]string, 2, 2)
 		tr.QueryComponentParams[0] = date1
 		tr.QueryComponentParams[1] = date2
 	case "Last Ten Years":
-		date1, date2 := timeseries.GetLastMonths(120)
+		date1, date2 := timeseries.GetLastMonths(120, asOf)
 		tr.QueryComponentParams = make([]string, 2, 2)
 		tr.QueryComponentParams[0] = date1
 		tr.QueryComponentParams[1] = date2
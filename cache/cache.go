@@ -8,8 +8,8 @@ import (
 	"sync"
 	"time"
 
-	"google.golang.org/appengine/log"
-	"google.golang.org/appengine/memcache"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+	"github.com/AlphaHat/gcp-alpha-hat/platform/log"
 )
 
 type GenericCache struct {
@@ -45,14 +45,11 @@ func hash(key string) string {
 }
 
 func (c *GenericCache) store(ctx context.Context, key string, value interface{}) {
-	item := &memcache.Item{
-		Key: c.keyType + ":" + hash(key),
-		Object: CacheObject{
-			LastUpdated: time.Now(),
-			Value:       value,
-		},
+	item := CacheObject{
+		LastUpdated: time.Now(),
+		Value:       value,
 	}
-	err := memcache.Gob.Set(ctx, item)
+	err := platform.CacheSetGob(ctx, c.keyType+":"+hash(key), item, 0)
 	if err != nil {
 		log.Infof(ctx, "Error adding err = %s", err)
 	} else {
@@ -63,7 +60,7 @@ func (c *GenericCache) store(ctx context.Context, key string, value interface{})
 func (c *GenericCache) Retrieve(ctx context.Context, key string) (interface{}, bool) {
 	// log.Infof(c.ctx, "Retrieving from memcache")
 	var item0 CacheObject
-	_, err := memcache.Gob.Get(ctx, c.keyType+":"+hash(key), &item0)
+	err := platform.CacheGetGob(ctx, c.keyType+":"+hash(key), &item0)
 	if err == nil {
 		if time.Since(item0.LastUpdated) < c.timeout {
 			return item0.Value, true
@@ -75,7 +72,10 @@ func (c *GenericCache) Retrieve(ctx context.Context, key string) (interface{}, b
 	log.Infof(ctx, "Using miss Function")
 
 	dataFound, found := c.missFunction(ctx, key)
-	c.store(ctx, key, dataFound)
+	// A miss isn't stored, otherwise the next Retrieve would report it as found
+	if found {
+		c.store(ctx, key, dataFound)
+	}
 
 	return dataFound, found
 }
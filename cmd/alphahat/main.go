@@ -0,0 +1,162 @@
+// Command alphahat executes saved trees outside of App Engine.
+//
+//	alphahat run [flags] tree.json
+//
+// The tree is the JSON returned by the tree endpoint. The result is written
+// as JSON, CSV or XLSX to standard output or to the file given with -o.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	stdlog "log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+	"github.com/AlphaHat/gcp-alpha-hat/run"
+)
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: alphahat run [flags] tree.json\n\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Usage = usage
+
+	if len(os.Args) < 2 || os.Args[1] != "run" {
+		usage()
+		os.Exit(2)
+	}
+
+	if err := runCommand(os.Args[2:]); err != nil {
+		fmt.Fprintf(os.Stderr, "alphahat: %s\n", err)
+		os.Exit(1)
+	}
+}
+
+func runCommand(args []string) error {
+	fs := flag.NewFlagSet("run", flag.ExitOnError)
+	fs.Usage = usage
+
+	format := fs.String("format", "", "output format: json, csv or xlsx (default from the -o extension, otherwise json)")
+	output := fs.String("o", "", "file to write the result to (default standard output)")
+	asOf := fs.String("asof", "", "date to evaluate the tree on as YYYY-MM-DD (default today)")
+	dataDir := fs.String("data", "", "directory to read provider responses from instead of the network, laid out as <dir>/<host>/<path>")
+	trace := fs.Bool("trace", false, "print the execution trace to standard error")
+	verbose := fs.Bool("v", false, "log to standard error")
+
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		usage()
+		os.Exit(2)
+	}
+
+	if *format == "" {
+		*format = strings.TrimPrefix(filepath.Ext(*output), ".")
+		if *format == "" {
+			*format = "json"
+		}
+	}
+
+	if *format != "json" && *format != "csv" && *format != "xlsx" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	b, err := ioutil.ReadFile(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var t run.ExecutionNode
+	if err := json.Unmarshal(b, &t); err != nil {
+		return fmt.Errorf("unable to decode %s: %s", fs.Arg(0), err)
+	}
+
+	if result := t.Validate(); !result.Valid {
+		messages := make([]string, len(result.Errors))
+		for i, v := range result.Errors {
+			messages[i] = v.Path + ": " + v.Error
+		}
+		return fmt.Errorf("invalid tree:\n%s", strings.Join(messages, "\n"))
+	}
+
+	services := platform.Local(nil)
+
+	logOutput := ioutil.Discard
+	if *verbose {
+		logOutput = os.Stderr
+	}
+	services.Log = platform.NewStdLogger(stdlog.New(logOutput, "", stdlog.LstdFlags), *verbose)
+
+	if *dataDir != "" {
+		services.Fetch = platform.NewDirFetcher(*dataDir)
+	}
+
+	ctx := platform.WithServices(context.Background(), services)
+
+	if *asOf != "" {
+		date, err := time.Parse("2006-01-02", *asOf)
+		if err != nil {
+			return fmt.Errorf("invalid as-of date %q", *asOf)
+		}
+		ctx = run.WithAsOf(ctx, date)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, run.RunTimeout)
+	defer cancel()
+
+	med, runTrace, runErr := run.ExecuteLocally(ctx, t, filepath.Base(fs.Arg(0)))
+
+	if *trace {
+		if tb, err := runTrace.Marshal(); err == nil {
+			fmt.Fprintf(os.Stderr, "%s\n", tb)
+		}
+	}
+
+	if runErr != nil {
+		return runErr
+	}
+
+	if *output == "" {
+		return writeResult(os.Stdout, *format, med)
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+
+	if err := writeResult(f, *format, med); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func writeResult(w io.Writer, format string, med run.MultiEntityData) error {
+	switch format {
+	case "csv":
+		return run.WriteCSV(w, run.ConvertToSheet(med))
+	case "xlsx":
+		return run.WriteExcel(w, run.ConvertToSheet(med))
+	}
+
+	b, err := json.MarshalIndent(med, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", b)
+
+	return err
+}
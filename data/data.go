@@ -2,6 +2,7 @@ package data
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"regexp"
@@ -19,7 +20,7 @@ import (
 
 	"reflect"
 
-	"google.golang.org/appengine/log"
+	"github.com/AlphaHat/gcp-alpha-hat/platform/log"
 )
 
 func extractTickerFromUniverseMember(universeMember component.QueryComponent) (string, error) {
@@ -72,45 +73,69 @@ func constructQuandlTicker(universeMember component.QueryComponent, concept comp
 	return "", ""
 }
 
+// ErrNotFound means the provider has no data for the requested series, so
+// asking again won't help. Any other error from GetData may be transient.
+var ErrNotFound = errors.New("no data found")
+
 func GetDataFull(ctx context.Context, universeMember component.QueryComponent, concept component.QueryComponent) (*timeseries.TimeSeries, string) {
+	ticker, _ := constructQuandlTicker(universeMember, concept)
+	ts, _ := GetData(ctx, universeMember, concept)
+
+	return ts, ticker
+}
+
+// GetData is GetDataFull with the reason the series couldn't be retrieved
+func GetData(ctx context.Context, universeMember component.QueryComponent, concept component.QueryComponent) (*timeseries.TimeSeries, error) {
 	ticker, seriesName := constructQuandlTicker(universeMember, concept)
 
 	if ticker == "" {
 		log.Errorf(ctx, "Error extracting ticker from %s", universeMember)
-		return nil, ""
+		return nil, ErrNotFound
 	}
 
-	return GetQuandlDataFull(ctx, ticker, seriesName), ticker
+	return GetQuandlData(ctx, ticker, seriesName)
 }
 
 func GetQuandlDataFull(ctx context.Context, ticker string, seriesName string) *timeseries.TimeSeries {
-	c := quandlConnect(seriesName)
+	ts, _ := GetQuandlData(ctx, ticker, seriesName)
+
+	return ts
+}
+
+// GetQuandlData is GetQuandlDataFull with the reason the series couldn't be retrieved
+func GetQuandlData(ctx context.Context, ticker string, seriesName string) (*timeseries.TimeSeries, error) {
+	var missErr error
+	c := quandlConnect(seriesName, &missErr)
 
 	if c == nil {
 		log.Errorf(ctx, "Unable to set up quandl connection")
-		return nil
+		return nil, errors.New("unable to set up quandl connection")
 	}
 
 	tsRaw, found := c.Retrieve(ctx, ticker)
 
 	if !found {
 		log.Infof(ctx, "%s not found", ticker)
-		return nil
+		if missErr == nil {
+			missErr = ErrNotFound
+		}
+		return nil, missErr
 	}
 
 	switch t := tsRaw.(type) {
 	case timeseries.TimeSeries:
 		// t.DisplayName = seriesName
 		// t.Units = "$"
-		return &t
+		return &t, nil
 	}
 
 	log.Errorf(ctx, "%s not a timeseries. It is a %s", ticker, reflect.TypeOf(tsRaw).Name())
 
-	return nil
+	return nil, ErrNotFound
 }
 
-func quandlConnect(columnName string) *cache.GenericCache {
+// quandlConnect sets missErr when the miss function can't retrieve the ticker
+func quandlConnect(columnName string, missErr *error) *cache.GenericCache {
 	quandl.SetAuthToken(os.Getenv("QUANDL_KEY"))
 
 	c := cache.NewGenericCache(time.Hour*8, "quandl", func(ctx context.Context, ticker string) (interface{}, bool) {
@@ -124,7 +149,11 @@ func quandlConnect(columnName string) *cache.GenericCache {
 
 		q, err := quandl.GetAllHistory(ctx, ticker)
 
-		if err == nil && q != nil {
+		if err == quandl.ErrNotFound {
+			*missErr = ErrNotFound
+		} else if err != nil {
+			*missErr = err
+		} else if q != nil {
 			date, data := q.GetTimeSeries(ctx, columnName)
 
 			if len(data) == 0 {
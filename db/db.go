@@ -3,8 +3,8 @@ package db
 import (
 	"context"
 
-	"google.golang.org/appengine/datastore"
-	"google.golang.org/appengine/log"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+	"github.com/AlphaHat/gcp-alpha-hat/platform/log"
 )
 
 const (
@@ -12,6 +12,10 @@ const (
 	RunTree      = "tree"
 	Quandl       = "quandl"
 	ChartOptions = "chartoptions"
+	RunStatus    = "runstatus"
+	RunTrace     = "runtrace"
+	Schedule     = "schedule"
+	ScheduledRun = "scheduledrun"
 )
 
 func logError(ctx context.Context, err error) bool {
@@ -25,25 +29,19 @@ func logError(ctx context.Context, err error) bool {
 
 // data should be a pointer
 func DatabaseInsert(ctx context.Context, kind string, data interface{}, parent string) string {
-	var key *datastore.Key
+	store := platform.From(ctx).Store
 
-	if parent != "" {
-		parentKey, err := datastore.DecodeKey(parent)
+	returnKey, err := store.Insert(ctx, kind, parent, data)
 
-		if logError(ctx, err) {
-			key = datastore.NewIncompleteKey(ctx, kind, parentKey)
-		} else {
-			key = datastore.NewIncompleteKey(ctx, kind, nil)
-		}
-	} else {
-		key = datastore.NewIncompleteKey(ctx, kind, nil)
+	// As before, a parent that can't be decoded is ignored
+	if err == platform.ErrInvalidKey && parent != "" {
+		logError(ctx, err)
+		returnKey, err = store.Insert(ctx, kind, "", data)
 	}
 
-	returnKey, err := datastore.Put(ctx, key, data)
-
 	if err == nil {
-		log.Infof(ctx, "Return key %s = %s", kind, returnKey.Encode())
-		return returnKey.Encode()
+		log.Infof(ctx, "Return key %s = %s", kind, returnKey)
+		return returnKey
 	} else {
 		log.Warningf(ctx, "Error inserting %s = %s", kind, err)
 	}
@@ -54,16 +52,12 @@ func DatabaseInsert(ctx context.Context, kind string, data interface{}, parent s
 func DatabaseUpdate(ctx context.Context, data interface{}, keyString string) {
 
 	if keyString != "" {
-		key, err := datastore.DecodeKey(keyString)
+		err := platform.From(ctx).Store.Update(ctx, keyString, data)
 
-		if logError(ctx, err) {
-			_, err := datastore.Put(ctx, key, data)
-
-			if err == nil {
-				log.Infof(ctx, "Updated key %s", keyString)
-			} else {
-				log.Warningf(ctx, "Error updating key %s = %s", keyString, err)
-			}
+		if err == nil {
+			log.Infof(ctx, "Updated key %s", keyString)
+		} else {
+			log.Warningf(ctx, "Error updating key %s = %s", keyString, err)
 		}
 	} else {
 		log.Errorf(ctx, "No key provided")
@@ -74,16 +68,12 @@ func DatabaseUpdate(ctx context.Context, data interface{}, keyString string) {
 func DatabaseDelete(ctx context.Context, keyString string) {
 
 	if keyString != "" {
-		key, err := datastore.DecodeKey(keyString)
-
-		if logError(ctx, err) {
-			err := datastore.Delete(ctx, key)
+		err := platform.From(ctx).Store.Delete(ctx, keyString)
 
-			if err == nil {
-				log.Infof(ctx, "Deleted key %s", keyString)
-			} else {
-				log.Warningf(ctx, "Error deleting key %s = %s", keyString, err)
-			}
+		if err == nil {
+			log.Infof(ctx, "Deleted key %s", keyString)
+		} else {
+			log.Warningf(ctx, "Error deleting key %s = %s", keyString, err)
 		}
 	} else {
 		log.Errorf(ctx, "No key provided")
@@ -92,49 +82,19 @@ func DatabaseDelete(ctx context.Context, keyString string) {
 }
 
 func GetFromKey(ctx context.Context, keyString string, v interface{}) error {
-	key, err := datastore.DecodeKey(keyString)
-
-	if logError(ctx, err) {
-		return datastore.Get(ctx, key, v)
-	}
-
-	return err
+	return platform.From(ctx).Store.Get(ctx, keyString, v)
 }
 
-func GetFromField(ctx context.Context, tableName string, fieldName string, fieldValue string, v interface{}) (*datastore.Key, error) {
-	query := datastore.NewQuery(tableName).Filter(fieldName+" =", fieldValue)
-
-	iter := query.Run(ctx)
-
-	key, err := iter.Next(v)
-
-	if err == datastore.Done {
-		return key, nil
-	}
-
-	return key, err
+// GetFromField loads the first entity whose field has the value and returns
+// its key, which is empty when there is none
+func GetFromField(ctx context.Context, tableName string, fieldName string, fieldValue string, v interface{}) (string, error) {
+	return platform.From(ctx).Store.FindOne(ctx, tableName, fieldName, fieldValue, v)
 }
 
 func GetAllFromField(ctx context.Context, tableName string, fieldName string, fieldValue string, v interface{}) error {
-	query := datastore.NewQuery(tableName).Filter(fieldName+" =", fieldValue)
-
-	_, err := query.GetAll(ctx, v)
-
-	if err == datastore.Done {
-		return nil
-	}
-
-	return err
+	return platform.From(ctx).Store.FindAll(ctx, tableName, fieldName, fieldValue, 0, v)
 }
 
 func GetAll(ctx context.Context, tableName string, v interface{}) error {
-	query := datastore.NewQuery(tableName).Limit(100)
-
-	_, err := query.GetAll(ctx, v)
-
-	if err == datastore.Done {
-		return nil
-	}
-
-	return err
-}
\ No newline at end of file
+	return platform.From(ctx).Store.FindAll(ctx, tableName, "", "", 100, v)
+}
@@ -0,0 +1,25 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+)
+
+type testDocument struct {
+	Name string
+}
+
+func TestDatabaseInsertIgnoresInvalidParent(t *testing.T) {
+	ctx := platform.WithServices(context.Background(), platform.Local(nil))
+
+	parent := DatabaseInsert(ctx, "doc", &testDocument{"parent"}, "")
+
+	if key := DatabaseInsert(ctx, "doc", &testDocument{"child"}, parent); key != parent+"/doc/2" {
+		t.Errorf("key under %s = %s", parent, key)
+	}
+	if key := DatabaseInsert(ctx, "doc", &testDocument{"orphan"}, "not a key"); key != "doc/3" {
+		t.Errorf("key under an invalid parent = %s", key)
+	}
+}
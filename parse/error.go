@@ -0,0 +1,219 @@
+package parse
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// SyntaxError locates where a formula stops matching the Calculator grammar.
+// Line and Column count runes from 1 and Offset counts them from 0, so that a
+// front-end can underline Token.
+type SyntaxError struct {
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Offset   int      `json:"offset"`
+	Token    string   `json:"token"`
+	Expected []string `json:"expected"`
+}
+
+func (e *SyntaxError) Error() string {
+	token := "end of formula"
+	if e.Token != "" {
+		token = fmt.Sprintf("%q", e.Token)
+	}
+
+	s := fmt.Sprintf("Unexpected %s at line %d, column %d", token, e.Line, e.Column)
+	if len(e.Expected) > 0 {
+		expected := make([]string, len(e.Expected))
+		for i, v := range e.Expected {
+			expected[i] = quoteExpected(v)
+		}
+		s += ", expected " + strings.Join(expected, ", ")
+	}
+
+	return s
+}
+
+// quoteExpected quotes the alternatives that are written as they are, so that
+// punctuation can't be mistaken for the commas between them. Descriptions
+// such as "a number" are left alone.
+func quoteExpected(name string) string {
+	if strings.HasPrefix(name, "a ") {
+		return name
+	}
+
+	return fmt.Sprintf("%q", name)
+}
+
+// syntaxProbes are what a formula can continue with. Each one is tried where
+// a parse failed to tell which of them the grammar would have accepted. A
+// probe is skipped when the one it requires wasn't accepted, since the grammar
+// never allows it without that one, which saves most of the re-parsing.
+var syntaxProbes = []struct {
+	text     string
+	name     string
+	requires string
+}{
+	{"1", "a number", ""},
+	{"val", "val", "a number"},
+	{"this[t]", "this", "a number"},
+	{"category", "category", ""},
+	{`field("x")`, "a field", "a number"},
+	{`""`, "a string", ""},
+	{"f(", "a function", "a number"},
+	{"(", "(", ""},
+	{")", ")", ""},
+	{"[", "[", ""},
+	{"]", "]", ""},
+	{":", ":", ""},
+	{",", ",", ""},
+	{"+", "+", ""},
+	{"-", "-", ""},
+	{"*", "*", "+"},
+	{"/", "/", "+"},
+	{"%", "%", "+"},
+	{"^", "^", "+"},
+	{"=", "=", ""},
+	{"!=", "!=", "="},
+	{"<", "<", "="},
+	{">", ">", "="},
+	{"t", "t", "a number"},
+	{"begin", "begin", "a number"},
+	{"end", "end", "a number"},
+	{"if", "if", "a number"},
+	{"then", "then", ""},
+	{"else", "else", ""},
+	{"and", "and", ""},
+	{"or", "or", "and"},
+	{"not", "not", "a number"},
+	{"true", "true", ""},
+	{"false", "false", ""},
+}
+
+// Parse compiles a formula, returning a *SyntaxError when it doesn't match
+// the grammar
+func Parse(expression string) (*Expression, error) {
+	calc := &Calculator{Buffer: expression}
+	calc.Init()
+	calc.Expression.Init(expression)
+
+	if err := calc.Parse(); err != nil {
+		return nil, newSyntaxError(expression, calc.farthest)
+	}
+
+	calc.Execute()
+
+	return &calc.Expression, nil
+}
+
+// ParseFolded is Parse for a formula written in any case. The grammar only
+// knows lower case, so the formula is lowered a rune at a time to keep the
+// offsets of a *SyntaxError pointing into the formula as it was written.
+func ParseFolded(expression string) (*Expression, error) {
+	runes := []rune(expression)
+	folded := make([]rune, len(runes))
+	for i, r := range runes {
+		folded[i] = unicode.ToLower(r)
+	}
+
+	e, err := Parse(string(folded))
+	if syntaxErr, ok := err.(*SyntaxError); ok {
+		syntaxErr.Token = tokenAt(runes[syntaxErr.Offset:])
+	}
+
+	return e, err
+}
+
+// farthest returns how many runes of the expression the parser got through
+func farthest(expression string) int {
+	calc := &Calculator{Buffer: expression}
+	calc.Init()
+	calc.Expression.Init(expression)
+	calc.Parse()
+
+	return calc.farthest
+}
+
+func newSyntaxError(expression string, offset int) *SyntaxError {
+	runes := []rune(expression)
+	if offset > len(runes) {
+		offset = len(runes)
+	}
+
+	e := &SyntaxError{
+		Line:     1,
+		Column:   1,
+		Offset:   offset,
+		Token:    tokenAt(runes[offset:]),
+		Expected: make([]string, 0),
+	}
+
+	for _, r := range runes[:offset] {
+		if r == '\n' {
+			e.Line++
+			e.Column = 1
+		} else {
+			e.Column++
+		}
+	}
+
+	// A word or number straight after another would only lengthen it, so
+	// those are tried after a space
+	afterWord := offset > 0 && isWordRune(runes[offset-1])
+
+	accepted := make(map[string]bool)
+	prefix := string(runes[:offset])
+	for _, v := range syntaxProbes {
+		if v.requires != "" && !accepted[v.requires] {
+			continue
+		}
+
+		text, reached := prefix+v.text, offset
+		if afterWord && isWordRune([]rune(v.text)[0]) {
+			text, reached = prefix+" "+v.text, offset+1
+		}
+
+		if farthest(text) > reached {
+			accepted[v.name] = true
+			e.Expected = append(e.Expected, v.name)
+		}
+	}
+
+	return e
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.'
+}
+
+var twoRuneOperators = map[string]bool{
+	"==": true, "!=": true, "<>": true, ">=": true, "<=": true, "&&": true, "||": true,
+}
+
+// tokenAt returns the word, number, string or operator at the start of runes
+func tokenAt(runes []rune) string {
+	if len(runes) == 0 {
+		return ""
+	}
+
+	end := 1
+
+	switch r := runes[0]; {
+	case isWordRune(r):
+		for end < len(runes) && isWordRune(runes[end]) {
+			end++
+		}
+	case r == '"':
+		for end < len(runes) && runes[end] != '"' && runes[end] != '\n' {
+			end++
+		}
+		if end < len(runes) && runes[end] == '"' {
+			end++
+		}
+	case len(runes) > 1 && twoRuneOperators[string(runes[:2])]:
+		end = 2
+	}
+
+	return string(runes[:end])
+}
@@ -0,0 +1,56 @@
+package parse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSyntaxError(t *testing.T) {
+	tests := []struct {
+		formula  string
+		line     int
+		column   int
+		offset   int
+		token    string
+		expected []string
+	}{
+		{"val +", 1, 6, 5, "", []string{"a number", "val", "this", "a field", "a function", "(", "-"}},
+		{"val + * 2", 1, 7, 6, "*", []string{"a number", "val", "this", "a field", "a function", "(", "-"}},
+		{"1 2", 1, 3, 2, "2", []string{"+", "-", "*", "/", "%", "^"}},
+		{"val[t-1", 1, 8, 7, "", []string{"]", "+", "-"}},
+		{"(1 + 2", 1, 7, 6, "", []string{")", "+", "-", "*", "/", "%", "^"}},
+	}
+
+	for _, v := range tests {
+		_, err := Parse(v.formula)
+		e, ok := err.(*SyntaxError)
+		if !ok {
+			t.Errorf("Parse(%q) err = %v, want a *SyntaxError", v.formula, err)
+			continue
+		}
+
+		if e.Line != v.line || e.Column != v.column || e.Offset != v.offset || e.Token != v.token {
+			t.Errorf("Parse(%q) = line %d, column %d, offset %d, token %q, want %d, %d, %d, %q", v.formula, e.Line, e.Column, e.Offset, e.Token, v.line, v.column, v.offset, v.token)
+		}
+		if !reflect.DeepEqual(e.Expected, v.expected) {
+			t.Errorf("Parse(%q) expected %v, want %v", v.formula, e.Expected, v.expected)
+		}
+	}
+}
+
+func TestParseFolded(t *testing.T) {
+	if _, err := ParseFolded("VAL[T-1] + Sum(val[BEGIN:END])"); err != nil {
+		t.Errorf("err = %s", err)
+	}
+
+	// The offset counts runes of the formula as written, and the token is
+	// taken from it rather than from the lowered copy
+	_, err := ParseFolded(`if category = "Ünïcödé" then 1 else 2 ÅB`)
+	e, ok := err.(*SyntaxError)
+	if !ok {
+		t.Fatalf("err = %v, want a *SyntaxError", err)
+	}
+	if e.Offset != 38 || e.Column != 39 || e.Token != "ÅB" {
+		t.Errorf("got offset %d, column %d, token %q, want 38, 39, \"ÅB\"", e.Offset, e.Column, e.Token)
+	}
+}
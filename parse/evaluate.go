@@ -0,0 +1,288 @@
+package parse
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Series gives a formula access to the dates and values of a series
+type Series interface {
+	Len() int
+	Time(i int) time.Time
+	Value(i int) float64
+}
+
+// Context is what a formula is evaluated against
+type Context interface {
+	// Series returns the series referred to by a specific identifier, where
+	// val1 is series 0
+	Series(n int) (Series, error)
+	// Current returns the series a general identifier such as val refers to
+	Current() (Series, error)
+	// This returns what the formula computed for the points before Index
+	This() Series
+	// Category returns the category on the date of point i of Current
+	Category(i int) string
+	// Index is the position of the point being computed
+	Index() int
+	// Function looks up a function called by the formula, scalar being
+	// whether the call passes numbers rather than ranges
+	Function(name string, scalar bool) (Function, bool)
+}
+
+// window is the part of a series a range such as val[t-5:t] refers to
+type window struct {
+	s     Series
+	first int
+	last  int
+}
+
+func (w window) Len() int {
+	return w.last - w.first + 1
+}
+
+func (w window) Time(i int) time.Time {
+	return w.s.Time(w.first + i)
+}
+
+func (w window) Value(i int) float64 {
+	return w.s.Value(w.first + i)
+}
+
+// Evaluate computes the formula for the point ctx.Index()
+func (e *Expression) Evaluate(ctx Context) (float64, error) {
+	stack, top := make([]float64, len(e.Code)), 0
+	booleanStack := make([]bool, len(e.Code))
+	arrayStack, arrayStackTop := make([]Series, len(e.Code)), 0
+
+	currentIndex := ctx.Index()
+
+	var string1, string2 string
+	for i := 0; i < e.Top; i++ {
+		code := e.Code[i]
+		switch code.T {
+		case TypeNumber:
+			stack[top] = code.Float
+			top++
+			continue
+		case TypeString:
+			if string1 == "" {
+				string1 = strings.TrimSpace(code.Str)
+			} else {
+				string2 = strings.TrimSpace(code.Str)
+			}
+			continue
+		case TypeIdentifierCategory:
+			val, err := ctx.Current()
+			if err != nil {
+				return 0, err
+			}
+			idx, _, err := code.EvaluateIndex(currentIndex, val.Len())
+			if err == nil {
+				category := ctx.Category(idx)
+
+				if string1 == "" {
+					string1 = strings.TrimSpace(category)
+				} else {
+					string2 = strings.TrimSpace(category)
+				}
+			}
+
+			continue
+		case TypeIdentifierSpecific, TypeIdentifierGeneral, TypeIdentifierThis:
+			val, err := e.series(ctx, code)
+			if err != nil {
+				return 0, err
+			}
+			idx, _, err := code.EvaluateIndex(currentIndex, val.Len())
+			if err != nil {
+				return 0, err
+			}
+			stack[top] = val.Value(idx)
+			top++
+			continue
+		case TypeIdentifierSpecificRange, TypeIdentifierGeneralRange, TypeIdentifierThisRange:
+			val, err := e.series(ctx, code)
+			if err != nil {
+				return 0, err
+			}
+			idx1, idx2, err := code.EvaluateIndex(currentIndex, val.Len())
+			if err != nil {
+				return 0, err
+			}
+			arrayStack[arrayStackTop] = window{val, idx1, idx2}
+			arrayStackTop++
+			continue
+		case TypeNegation:
+			stack[top-1] = -1 * stack[top-1]
+			continue
+		case TypeTrue:
+			booleanStack[top] = true
+			top++
+			continue
+		case TypeFalse:
+			booleanStack[top] = false
+			top++
+			continue
+		case TypeNot:
+			booleanStack[top-1] = !booleanStack[top-1]
+			continue
+		case TypeStringEqual:
+			booleanStack[top] = strings.ToLower(string1) == strings.ToLower(string2)
+			string1, string2 = "", ""
+			top++
+			continue
+		case TypeStringNotEqual:
+			booleanStack[top] = strings.ToLower(string1) != strings.ToLower(string2)
+			string1, string2 = "", ""
+			top++
+			continue
+		case TypeTimeEqual:
+			booleanStack[top] = currentIndex == 0
+			top++
+			continue
+		case TypeFunctionCall:
+			fn, ok := ctx.Function(code.Str, code.isScalarCall())
+			if err := code.checkCall(fn, ok); err != nil {
+				return 0, err
+			}
+
+			args := make([]Series, len(code.Args))
+			for j := len(code.Args) - 1; j >= 0; j-- {
+				if code.Args[j] == TypeNumber {
+					top--
+					args[j] = number(stack[top])
+				} else {
+					arrayStackTop--
+					args[j] = arrayStack[arrayStackTop]
+				}
+			}
+			stack[top] = fn.Fn(args)
+			top++
+			continue
+		}
+
+		switch code.T {
+		case TypeAdd:
+			stack[top-2] = stack[top-2] + stack[top-1]
+		case TypeSubtract:
+			stack[top-2] = stack[top-2] - stack[top-1]
+		case TypeMultiply:
+			stack[top-2] = stack[top-2] * stack[top-1]
+		case TypeDivide:
+			stack[top-2] = stack[top-2] / stack[top-1]
+		case TypeModulus:
+			stack[top-2] = math.Mod(stack[top-2], stack[top-1])
+		case TypeExponentiation:
+			stack[top-2] = math.Pow(stack[top-2], stack[top-1])
+		case TypeLogicalEqual:
+			booleanStack[top-2] = booleanStack[top-2] == booleanStack[top-1]
+		case TypeLogicalNotEqual:
+			booleanStack[top-2] = booleanStack[top-2] != booleanStack[top-1]
+		case TypeAnd:
+			booleanStack[top-2] = booleanStack[top-2] && booleanStack[top-1]
+		case TypeOr:
+			booleanStack[top-2] = booleanStack[top-2] || booleanStack[top-1]
+		case TypeEqual:
+			booleanStack[top-2] = stack[top-2] == stack[top-1]
+		case TypeNotEqual:
+			booleanStack[top-2] = stack[top-2] != stack[top-1]
+		case TypeGreaterThan:
+			booleanStack[top-2] = stack[top-2] > stack[top-1]
+		case TypeGreaterThanEqual:
+			booleanStack[top-2] = stack[top-2] >= stack[top-1]
+		case TypeLessThan:
+			booleanStack[top-2] = stack[top-2] < stack[top-1]
+		case TypeLessThanEqual:
+			booleanStack[top-2] = stack[top-2] <= stack[top-1]
+		case TypeThen:
+			if booleanStack[0] {
+				// Continue on to the next operation
+			} else {
+				// Branch to the else
+				i = e.FindElseAfter(i)
+			}
+		case TypeElse:
+			return stack[0], nil
+		}
+		top--
+	}
+	return stack[0], nil
+}
+
+// series returns the series an identifier refers to
+func (e *Expression) series(ctx Context, code ByteCode) (Series, error) {
+	switch code.T {
+	case TypeIdentifierSpecific, TypeIdentifierSpecificRange:
+		if code.isNamed() && code.Int < 0 {
+			return nil, errors.New("Unknown field [" + code.Str + "]")
+		}
+		return ctx.Series(code.Int)
+	case TypeIdentifierThis, TypeIdentifierThisRange:
+		return ctx.This(), nil
+	}
+
+	return ctx.Current()
+}
+
+// CheckCalls reports the first function the expression calls that doesn't
+// exist or is passed the wrong number or kind of arguments
+func (e *Expression) CheckCalls() error {
+	for _, code := range e.Code[0:e.Top] {
+		if code.T != TypeFunctionCall {
+			continue
+		}
+
+		fn, ok := LookupFunction(code.Str, code.isScalarCall())
+		if err := code.checkCall(fn, ok); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isScalarCall is whether a function call only passes numbers
+func (code *ByteCode) isScalarCall() bool {
+	for _, kind := range code.Args {
+		if kind != TypeNumber {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (code *ByteCode) checkCall(fn Function, ok bool) error {
+	if !ok {
+		return errors.New("Unknown function " + code.Str)
+	}
+
+	kind := TypeTimeRange
+	noun := "range"
+	if fn.Scalar {
+		kind = TypeNumber
+		noun = "number"
+	}
+
+	wrong := len(code.Args) != fn.Arity
+	for _, k := range code.Args {
+		wrong = wrong || k != kind
+	}
+	if wrong {
+		return errors.New(code.Str + " needs " + pluralize(fn.Arity, noun))
+	}
+
+	return nil
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+
+	return strconv.Itoa(n) + " " + noun + "s"
+}
@@ -0,0 +1,153 @@
+package parse
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// values is a daily series starting on 2026-01-01
+type values []float64
+
+func (v values) Len() int {
+	return len(v)
+}
+
+func (v values) Time(i int) time.Time {
+	return time.Date(2026, 1, 1+i, 0, 0, 0, 0, time.UTC)
+}
+
+func (v values) Value(i int) float64 {
+	return v[i]
+}
+
+type testContext struct {
+	series     []values
+	current    int
+	this       values
+	categories []string
+	index      int
+}
+
+func (c testContext) Series(n int) (Series, error) {
+	if n < 0 || n >= len(c.series) {
+		return nil, errors.New("no series")
+	}
+
+	return c.series[n], nil
+}
+
+func (c testContext) Current() (Series, error) {
+	return c.Series(c.current)
+}
+
+func (c testContext) This() Series {
+	return c.this
+}
+
+func (c testContext) Category(i int) string {
+	return c.categories[i]
+}
+
+func (c testContext) Index() int {
+	return c.index
+}
+
+func (c testContext) Function(name string, scalar bool) (Function, bool) {
+	return LookupFunction(name, scalar)
+}
+
+func TestEvaluate(t *testing.T) {
+	ctx := testContext{
+		series:     []values{{1, 2, 3, 4}, {10, 20, 30, 40}},
+		current:    1,
+		this:       values{5, 6},
+		categories: []string{"Retail", "Retail", "Tech", "Tech"},
+		index:      2,
+	}
+
+	cases := []struct {
+		formula string
+		want    float64
+	}{
+		{"val1[t-1] + val2", 32},
+		{"val[t] / val1[t] - 1", 9},
+		{"sum(val1[t-2:t])", 6},
+		{"sumproduct(val1[begin:t], val2[begin:t])", 140},
+		{"this[t-1] * 2", 12},
+		{"if val1 > 2 then 1 else 0", 1},
+		{`if category == "tech" then 1 else 0`, 1},
+		{"-val2[end] ^ 2 % 7", 4},
+		{"abs(val1[t-1] - val2)", 28},
+		{"round(val2 / 7, 2)", 4.29},
+		{"max(val1, val2[t-1]) + min(val2[t-2:t])", 30},
+		{"sqrt(sum(val1[begin:t]) + 3)", 3},
+	}
+
+	for _, v := range cases {
+		e, err := Parse(v.formula)
+		if err != nil {
+			t.Errorf("Parse(%q) err = %s", v.formula, err)
+			continue
+		}
+
+		got, err := e.Evaluate(ctx)
+		if err != nil {
+			t.Errorf("%q err = %s", v.formula, err)
+		} else if got != v.want {
+			t.Errorf("%q = %v, want %v", v.formula, got, v.want)
+		}
+	}
+
+	for _, formula := range []string{"abs(val1[t-2:t])", "round(val1)", "sum(val1)", "nosuch(val[t-1:t])"} {
+		e, _ := Parse(formula)
+		if err := e.CheckCalls(); err == nil {
+			t.Errorf("%q should not check", formula)
+		}
+	}
+
+	for _, formula := range []string{"val3", "val1[t+5]", "nosuch(val[t-1:t])", "round(val1)"} {
+		e, err := Parse(formula)
+		if err != nil {
+			t.Errorf("Parse(%q) err = %s", formula, err)
+			continue
+		}
+
+		if _, err := e.Evaluate(ctx); err == nil {
+			t.Errorf("%q should fail", formula)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	ctx := testContext{series: []values{{1, 2, 3, 4}, {10, 20, 30, 40}}, index: 2}
+	fields := map[string]int{"price": 0, "short interest": 1}
+	lookup := func(name string) (int, error) {
+		if n, ok := fields[name]; ok {
+			return n, nil
+		}
+		return -1, errors.New("no field " + name)
+	}
+
+	e, err := Parse(`[price][t-1] + sum(field("short interest")[t-1:t])`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := e.Evaluate(ctx); err == nil {
+		t.Error("an unresolved field should fail")
+	}
+
+	resolved, err := e.Resolve(lookup)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := resolved.Evaluate(ctx); err != nil || got != 52 {
+		t.Errorf("got %v, %v, want 52", got, err)
+	}
+
+	e, _ = Parse("[volume] * 2")
+	if _, err := e.Resolve(lookup); err == nil {
+		t.Error("[volume] should not resolve")
+	}
+}
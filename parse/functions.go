@@ -0,0 +1,281 @@
+package parse
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// Function computes a number from ranges of series or, for a Scalar function,
+// from numbers. A number is passed as a Series with a single point.
+type Function struct {
+	Arity  int
+	Scalar bool
+	Fn     func(args []Series) float64
+}
+
+func function1(fn func(Series) float64) Function {
+	return Function{Arity: 1, Fn: func(args []Series) float64 {
+		return fn(args[0])
+	}}
+}
+
+func function2(fn func(Series, Series) float64) Function {
+	return Function{Arity: 2, Fn: func(args []Series) float64 {
+		return fn(args[0], args[1])
+	}}
+}
+
+func scalar1(fn func(float64) float64) Function {
+	return Function{Arity: 1, Scalar: true, Fn: func(args []Series) float64 {
+		return fn(args[0].Value(0))
+	}}
+}
+
+func scalar2(fn func(float64, float64) float64) Function {
+	return Function{Arity: 2, Scalar: true, Fn: func(args []Series) float64 {
+		return fn(args[0].Value(0), args[1].Value(0))
+	}}
+}
+
+// number is a scalar argument of a function
+type number float64
+
+func (n number) Len() int {
+	return 1
+}
+
+func (n number) Time(i int) time.Time {
+	return time.Time{}
+}
+
+func (n number) Value(i int) float64 {
+	return float64(n)
+}
+
+// Functions are the functions formulas can call
+var Functions = map[string]Function{
+	"sum":        function1(sum),
+	"count":      function1(count),
+	"average":    function1(average),
+	"mean":       function1(average),
+	"avg":        function1(average),
+	"variance":   function1(variance),
+	"var":        function1(variance),
+	"stddev":     function1(stdDev),
+	"stdev":      function1(stdDev),
+	"median":     function1(median),
+	"med":        function1(median),
+	"maximum":    function1(maximum),
+	"max":        function1(maximum),
+	"minimum":    function1(minimum),
+	"min":        function1(minimum),
+	"compound":   function1(compound),
+	"cagr":       function1(cagr),
+	"product":    function1(product),
+	"sumproduct": function2(sumProduct),
+	"medianif":   function2(medianIf),
+	"sumif":      function2(sumIf),
+	"averageif":  function2(averageIf),
+}
+
+// ScalarFunctions are the functions formulas can call on numbers. min and max
+// of two numbers share their names with the range functions.
+var ScalarFunctions = map[string]Function{
+	"abs":   scalar1(math.Abs),
+	"log":   scalar1(math.Log),
+	"exp":   scalar1(math.Exp),
+	"sqrt":  scalar1(math.Sqrt),
+	"round": scalar2(round),
+	"min":   scalar2(math.Min),
+	"max":   scalar2(math.Max),
+}
+
+// LookupFunction finds the function called by name, preferring a scalar one
+// when the call passes numbers
+func LookupFunction(name string, scalar bool) (Function, bool) {
+	first, second := Functions, ScalarFunctions
+	if scalar {
+		first, second = second, first
+	}
+
+	if fn, ok := first[name]; ok {
+		return fn, true
+	}
+	fn, ok := second[name]
+
+	return fn, ok
+}
+
+// round rounds x to the given number of decimal places
+func round(x float64, places float64) float64 {
+	scale := math.Pow(10, math.Trunc(places))
+
+	return math.Round(x*scale) / scale
+}
+
+func sum(d Series) float64 {
+	var sum float64
+
+	for i := 0; i < d.Len(); i++ {
+		sum = sum + d.Value(i)
+	}
+
+	return sum
+}
+
+func count(d Series) float64 {
+	return float64(d.Len())
+}
+
+func average(d Series) float64 {
+	sum := sum(d)
+	count := count(d)
+
+	if count > 0 {
+		return sum / count
+	}
+	return 0
+}
+
+func variance(d Series) float64 {
+	mean := average(d)
+	count := count(d)
+
+	var sumOfSquares float64
+
+	for i := 0; i < d.Len(); i++ {
+		diffFromMean := (d.Value(i) - mean)
+		sumOfSquares = sumOfSquares + (diffFromMean * diffFromMean)
+	}
+
+	if count > 0 {
+		return sumOfSquares / count
+	}
+
+	return 0
+}
+
+func stdDev(d Series) float64 {
+	return math.Sqrt(variance(d))
+}
+
+func sumProduct(d1 Series, d2 Series) float64 {
+	var sum float64
+
+	for i := 0; i < d1.Len() && i < d2.Len(); i++ {
+		sum = sum + (d1.Value(i) * d2.Value(i))
+	}
+
+	return sum
+}
+
+func median(d Series) float64 {
+	floatArr := make([]float64, d.Len(), d.Len())
+
+	for i, _ := range floatArr {
+		floatArr[i] = d.Value(i)
+	}
+	sort.Float64s(floatArr)
+
+	if len(floatArr)%2 == 0 {
+		idx1 := int(len(floatArr) / 2)
+		idx2 := idx1 - 1
+		return (floatArr[idx1] + floatArr[idx2]) / 2.0
+	}
+	idx := int(len(floatArr) / 2)
+	return floatArr[idx]
+}
+
+// selection is the points of a series picked by an if function
+type selection struct {
+	Series
+	points []int
+}
+
+func (s selection) Len() int {
+	return len(s.points)
+}
+
+func (s selection) Time(i int) time.Time {
+	return s.Series.Time(s.points[i])
+}
+
+func (s selection) Value(i int) float64 {
+	return s.Series.Value(s.points[i])
+}
+
+func getIf(d1 Series, d2 Series) Series {
+	s := selection{d1, make([]int, 0, d1.Len())}
+	for i := 0; i < d1.Len() && i < d2.Len(); i++ {
+		if d2.Value(i) > 0 {
+			s.points = append(s.points, i)
+		}
+	}
+
+	return s
+}
+
+func medianIf(d1 Series, d2 Series) float64 {
+	return median(getIf(d1, d2))
+}
+
+func averageIf(d1 Series, d2 Series) float64 {
+	return average(getIf(d1, d2))
+}
+
+func sumIf(d1 Series, d2 Series) float64 {
+	return sum(getIf(d1, d2))
+}
+
+func maximum(d Series) float64 {
+	var max = math.Inf(-1)
+	for i := 0; i < d.Len(); i++ {
+		if d.Value(i) > max {
+			max = d.Value(i)
+		}
+	}
+
+	return max
+}
+
+func minimum(d Series) float64 {
+	var min = math.Inf(1)
+	for i := 0; i < d.Len(); i++ {
+		if d.Value(i) < min {
+			min = d.Value(i)
+		}
+	}
+
+	return min
+}
+
+func compound(d Series) float64 {
+	var compounded float64 = 1
+
+	for i := 0; i < d.Len(); i++ {
+		compounded = compounded * (1 + d.Value(i))
+	}
+
+	return compounded - 1
+}
+
+func cagr(d Series) float64 {
+	first, last := 0, d.Len()-1
+
+	years := d.Time(last).Sub(d.Time(first)).Hours() / 24.0 / 365.0
+
+	data := math.Pow(d.Value(last)/d.Value(first), 1.0/years) - 1
+
+	return data
+}
+
+func product(d Series) float64 {
+	var product float64 = 1
+
+	for i := 0; i < d.Len(); i++ {
+		product = product * d.Value(i)
+	}
+
+	return product
+}
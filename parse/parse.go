@@ -1,9 +1,9 @@
 package parse
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
-	"math"
 	"net/http"
 	"sort"
 	"strconv"
@@ -65,6 +65,9 @@ type ByteCode struct {
 	Int     int
 	Str     string
 	IndexOp []IndexCode
+	// Args is whether each argument of a function call is a range
+	// (TypeTimeRange) or a number (TypeNumber)
+	Args []Type
 }
 
 func (code *IndexCode) String() string {
@@ -189,6 +192,10 @@ type Expression struct {
 	Code                []ByteCode
 	Top                 int
 	CurrentFunctionName string
+
+	farthest int
+	// calls are the function calls being parsed, innermost last
+	calls []ByteCode
 }
 
 func (e *Expression) IsAppliedOverAllSeries() bool {
@@ -258,10 +265,24 @@ func intIsUnique(arr []int) bool {
 
 func (e *Expression) Init(expression string) {
 	e.Code = make([]ByteCode, len(expression))
+	e.farthest = 0
+	e.calls = nil
+}
+
+// reach is called from the grammar at the end of every token, so that a
+// failed parse knows how far it got. A name on its own doesn't count since any
+// word matches functionName.
+func (e *Expression) reach(position uint32) bool {
+	if int(position) > e.farthest {
+		e.farthest = int(position)
+	}
+
+	return true
 }
 
-func (e *Expression) AddFunctionArgument() {
-	// Do nothing for now
+func (e *Expression) AddFunctionArgument(kind Type) {
+	call := &e.calls[len(e.calls)-1]
+	call.Args = append(call.Args, kind)
 }
 
 func (e *Expression) AddIndexOperator(operator Type) {
@@ -288,13 +309,16 @@ func (e *Expression) AddOperator(operator Type) {
 
 func (e *Expression) AddFunctionName(name string) {
 	e.CurrentFunctionName = name
+	e.calls = append(e.calls, ByteCode{T: TypeFunctionCall, Str: name})
 }
 
+// AddFunctionCall ends the innermost call, since an argument can call
+// functions of its own
 func (e *Expression) AddFunctionCall() {
 	code, top := e.Code, e.Top
 	e.Top++
-	code[top].T = TypeFunctionCall
-	code[top].Str = e.CurrentFunctionName
+	code[top] = e.calls[len(e.calls)-1]
+	e.calls = e.calls[:len(e.calls)-1]
 }
 
 func (e *Expression) AddIdentifierSpecific(value string) {
@@ -341,6 +365,50 @@ func (e *Expression) AddIdentifierThisRange() {
 	code[top].T = TypeIdentifierThisRange
 }
 
+// AddIdentifierNamed adds a reference to a field by its name, as in [Price].
+// It points at no series until the expression is resolved.
+func (e *Expression) AddIdentifierNamed(name string) {
+	code, top := e.Code, e.Top
+	e.Top++
+	code[top].T = TypeIdentifierSpecific
+	code[top].Str = strings.TrimSpace(name)
+	code[top].Int = -1
+}
+
+func (e *Expression) AddIdentifierNamedRange(name string) {
+	code, top := e.Code, e.Top
+	e.Top++
+	code[top].T = TypeIdentifierSpecificRange
+	code[top].Str = strings.TrimSpace(name)
+	code[top].Int = -1
+}
+
+// Resolve returns a copy of the expression where each field referred to by
+// name points at the series lookup finds for it
+func (e *Expression) Resolve(lookup func(name string) (int, error)) (*Expression, error) {
+	r := *e
+	r.Code = make([]ByteCode, len(e.Code))
+	copy(r.Code, e.Code)
+
+	for i, _ := range r.Code[0:r.Top] {
+		if !r.Code[i].isNamed() {
+			continue
+		}
+
+		n, err := lookup(r.Code[i].Str)
+		if err != nil {
+			return nil, err
+		}
+		r.Code[i].Int = n
+	}
+
+	return &r, nil
+}
+
+func (code *ByteCode) isNamed() bool {
+	return (code.T == TypeIdentifierSpecific || code.T == TypeIdentifierSpecificRange) && code.Str != ""
+}
+
 func (e *Expression) AddCategoryIdentifier() {
 	code, top := e.Code, e.Top
 	e.Top++
@@ -371,184 +439,6 @@ func (e *Expression) String() string {
 	return s
 }
 
-func (e *Expression) Evaluate() float64 {
-	val := []float64{1, 2, 3, 4, 5}
-	category := "Hello World"
-	length := len(val)
-	currentIndex := 2
-
-	stack, top := make([]float64, len(e.Code)), 0
-	booleanStack := make([]bool, len(e.Code))
-	arrayStack, arrayStackTop := make([][]float64, len(e.Code)), 0
-
-	var string1, string2 string
-	for i := 0; i < e.Top; i++ {
-		code := e.Code[i]
-		fmt.Printf("%s: i=%v, top=%v, stack=%v, booleanStack=%v, arrayStack=%v\n", code.String(), i, top, stack[0:top], booleanStack[0:top], arrayStack[0:arrayStackTop])
-
-		// These operators add to the stack
-		switch code.T {
-		case TypeNumber:
-			stack[top] = code.Float
-			top++
-			continue
-		case TypeString:
-			if string1 == "" {
-				string1 = code.Str
-			} else {
-				string2 = code.Str
-			}
-			continue
-		case TypeIdentifierCategory:
-			if string1 == "" {
-				string1 = category
-			} else {
-				string2 = category
-			}
-			continue
-		case TypeIdentifierSpecific:
-			// This will access a specific series
-			idx, _, err := code.EvaluateIndex(currentIndex, length)
-			if err == nil {
-				stack[top] = val[idx]
-			} else {
-				fmt.Printf("err=%s\n", err)
-			}
-			top++
-			continue
-		case TypeIdentifierGeneral:
-			// This will access the *current* series (similar to the *current* time)
-			idx, _, err := code.EvaluateIndex(currentIndex, length)
-			if err == nil {
-				stack[top] = val[idx]
-			} else {
-				fmt.Printf("err=%s\n", err)
-			}
-			top++
-			continue
-		case TypeIdentifierThis:
-			// This will access the *current* series (similar to the *current* time)
-			idx, _, err := code.EvaluateIndex(currentIndex, length)
-			if err == nil {
-				stack[top] = 2048 + val[idx] // TODO: Change to the current computed array
-			} else {
-				fmt.Printf("err=%s\n", err)
-			}
-			top++
-			continue
-		case TypeIdentifierSpecificRange:
-			// This will access a specific series
-			idx1, idx2, err := code.EvaluateIndex(currentIndex, length)
-			if err == nil {
-				// fmt.Printf(" idx1=%v, idx2=%v ", idx1, idx2)
-				arrayStack[arrayStackTop] = val[idx1:(1 + idx2)]
-			} else {
-				fmt.Printf("err=%s\n", err)
-			}
-			arrayStackTop++
-			continue
-		case TypeIdentifierGeneralRange:
-			// This will access the *current* series (similar to the *current* time)
-			idx1, idx2, err := code.EvaluateIndex(currentIndex, length)
-			if err == nil {
-				// fmt.Printf(" idx1=%v, idx2=%v ", idx1, idx2)
-				arrayStack[arrayStackTop] = val[idx1:(1 + idx2)]
-			} else {
-				fmt.Printf("err=%s\n", err)
-			}
-			arrayStackTop++
-			continue
-		case TypeIdentifierThisRange:
-			// This will access the *computed* series
-			idx1, idx2, err := code.EvaluateIndex(currentIndex, length)
-			if err == nil {
-				arrayStack[arrayStackTop] = val[idx1:(1 + idx2)] // TODO: Change this to the current computed array
-			} else {
-				fmt.Printf("err=%s\n", err)
-			}
-			arrayStackTop++
-			continue
-		case TypeNegation:
-			stack[top-1] = -1 * stack[top-1]
-			continue
-		case TypeTrue:
-			booleanStack[top] = true
-			top++
-			continue
-		case TypeFalse:
-			booleanStack[top] = false
-			top++
-			continue
-		case TypeNot:
-			booleanStack[top-1] = !booleanStack[top-1]
-			continue
-		case TypeStringEqual:
-			booleanStack[top] = strings.ToLower(string1) == strings.ToLower(string2)
-			string1, string2 = "", ""
-			top++
-			continue
-		case TypeStringNotEqual:
-			booleanStack[top] = strings.ToLower(string1) != strings.ToLower(string2)
-			string1, string2 = "", ""
-			top++
-			continue
-		case TypeTimeEqual:
-			booleanStack[top] = currentIndex == 0
-			top++
-			continue
-		}
-
-		// These operators remove from the stack
-		switch code.T {
-		case TypeAdd:
-			stack[top-2] = stack[top-2] + stack[top-1]
-		case TypeSubtract:
-			stack[top-2] = stack[top-2] - stack[top-1]
-		case TypeMultiply:
-			stack[top-2] = stack[top-2] * stack[top-1]
-		case TypeDivide:
-			stack[top-2] = stack[top-2] / stack[top-1]
-		case TypeModulus:
-			stack[top-2] = math.Mod(stack[top-2], stack[top-1])
-		case TypeExponentiation:
-			stack[top-2] = math.Pow(stack[top-2], stack[top-1])
-		case TypeLogicalEqual:
-			booleanStack[top-2] = booleanStack[top-2] == booleanStack[top-1]
-		case TypeLogicalNotEqual:
-			booleanStack[top-2] = booleanStack[top-2] != booleanStack[top-1]
-		case TypeAnd:
-			booleanStack[top-2] = booleanStack[top-2] && booleanStack[top-1]
-		case TypeOr:
-			booleanStack[top-2] = booleanStack[top-2] || booleanStack[top-1]
-		case TypeEqual:
-			booleanStack[top-2] = stack[top-2] == stack[top-1]
-		case TypeNotEqual:
-			booleanStack[top-2] = stack[top-2] != stack[top-1]
-		case TypeGreaterThan:
-			booleanStack[top-2] = stack[top-2] > stack[top-1]
-		case TypeGreaterThanEqual:
-			booleanStack[top-2] = stack[top-2] >= stack[top-1]
-		case TypeLessThan:
-			booleanStack[top-2] = stack[top-2] < stack[top-1]
-		case TypeLessThanEqual:
-			booleanStack[top-2] = stack[top-2] <= stack[top-1]
-		case TypeThen:
-			if booleanStack[0] {
-				// Continue on to the next operation
-			} else {
-				// Branch to the else
-				i = e.FindElseAfter(i)
-			}
-		case TypeElse:
-			return stack[0]
-		case TypeFunctionCall:
-			return 1024.0
-		}
-		top--
-	}
-	return stack[0]
-}
-
 func (e Expression) FindElseAfter(i int) int {
 	for j := i; j < e.Top; j++ {
 		if e.Code[j].T == TypeElse {
@@ -669,12 +559,9 @@ func (e *ByteCode) EvaluateIndex(currentIndex, length int) (int, int, error) {
 }
 
 func ParseHandler(w http.ResponseWriter, r *http.Request, expression string) {
-	var calc *Calculator
-	calc = &Calculator{Buffer: expression}
-	calc.Init()
-	calc.Expression.Init(expression)
-	if err := calc.Parse(); err != nil {
-		fmt.Fprintf(w, `{"status": "err"}`)
+	if _, err := Parse(expression); err != nil {
+		b, _ := json.Marshal(err)
+		fmt.Fprintf(w, `{"status": "err", "error": %s}`, b)
 	} else {
 		fmt.Fprintf(w, `{"status": "ok"}`)
 	}
@@ -43,6 +43,9 @@ const (
 	rulespecificIdentifierRange
 	rulegeneralIdentifierRange
 	rulethisIdentifierRange
+	rulenamedIdentifier
+	rulenamedIdentifierRange
+	rulefieldReference
 	rulecategoryIdentifier
 	rulestringValue
 	ruletimeRange
@@ -125,6 +128,9 @@ const (
 	ruleAction41
 	ruleAction42
 	ruleAction43
+	ruleAction44
+	ruleAction45
+	ruleAction46
 
 	rulePre_
 	rule_In_
@@ -162,6 +168,9 @@ var rul3s = [...]string{
 	"specificIdentifierRange",
 	"generalIdentifierRange",
 	"thisIdentifierRange",
+	"namedIdentifier",
+	"namedIdentifierRange",
+	"fieldReference",
 	"categoryIdentifier",
 	"stringValue",
 	"timeRange",
@@ -244,6 +253,9 @@ var rul3s = [...]string{
 	"Action41",
 	"Action42",
 	"Action43",
+	"Action44",
+	"Action45",
+	"Action46",
 
 	"Pre_",
 	"_In_",
@@ -564,7 +576,7 @@ type Calculator struct {
 
 	Buffer string
 	buffer []rune
-	rules  [112]func() bool
+	rules  [118]func() bool
 	Parse  func(rule ...int) error
 	Reset  func()
 	tokenTree
@@ -693,42 +705,48 @@ func (p *Calculator) Execute() {
 		case ruleAction24:
 			p.AddFunctionCall()
 		case ruleAction25:
-			p.AddFunctionArgument()
+			p.AddFunctionArgument(TypeTimeRange)
 		case ruleAction26:
-			p.AddFunctionName(buffer[begin:end])
+			p.AddFunctionArgument(TypeNumber)
 		case ruleAction27:
-			p.AddIdentifierSpecific(buffer[begin:end])
+			p.AddFunctionName(buffer[begin:end])
 		case ruleAction28:
-			p.AddIdentifierGeneral()
+			p.AddIdentifierSpecific(buffer[begin:end])
 		case ruleAction29:
-			p.AddIdentifierThis()
+			p.AddIdentifierGeneral()
 		case ruleAction30:
-			p.AddIdentifierSpecificRange(buffer[begin:end])
+			p.AddIdentifierThis()
 		case ruleAction31:
-			p.AddIdentifierGeneralRange()
+			p.AddIdentifierSpecificRange(buffer[begin:end])
 		case ruleAction32:
-			p.AddIdentifierThisRange()
+			p.AddIdentifierGeneralRange()
 		case ruleAction33:
-			p.AddCategoryIdentifier()
+			p.AddIdentifierThisRange()
 		case ruleAction34:
-			p.AddStringValue(buffer[begin:end])
+			p.AddIdentifierNamed(buffer[begin:end])
 		case ruleAction35:
-			p.AddIndexOperator(TypeTimeRange)
+			p.AddIdentifierNamedRange(buffer[begin:end])
 		case ruleAction36:
-			p.AddIndexOperator(TypeAdd)
+			p.AddCategoryIdentifier()
 		case ruleAction37:
-			p.AddIndexOperator(TypeSubtract)
+			p.AddStringValue(buffer[begin:end])
 		case ruleAction38:
-			p.AddIndexOperator(TypeBegin)
+			p.AddIndexOperator(TypeTimeRange)
 		case ruleAction39:
-			p.AddIndexOperator(TypeEnd)
+			p.AddIndexOperator(TypeAdd)
 		case ruleAction40:
-			p.AddIndexOperator(TypeCurrentTime)
+			p.AddIndexOperator(TypeSubtract)
 		case ruleAction41:
-			p.AddIndexValue(buffer[begin:end])
+			p.AddIndexOperator(TypeBegin)
 		case ruleAction42:
-			p.AddOperator(TypeTrue)
+			p.AddIndexOperator(TypeEnd)
 		case ruleAction43:
+			p.AddIndexOperator(TypeCurrentTime)
+		case ruleAction44:
+			p.AddIndexValue(buffer[begin:end])
+		case ruleAction45:
+			p.AddOperator(TypeTrue)
+		case ruleAction46:
 			p.AddOperator(TypeFalse)
 
 		}
@@ -1566,7 +1584,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position74, tokenIndex74, depth74
 			return false
 		},
-		/* 17 identifier <- <(specificIdentifier / generalIdentifier / thisIdentifier / functionCall)> */
+		/* 17 identifier <- <(specificIdentifier / generalIdentifier / thisIdentifier / namedIdentifier / functionCall)> */
 		func() bool {
 			position86, tokenIndex86, depth86 := position, tokenIndex, depth
 			{
@@ -1591,6 +1609,12 @@ func (p *Calculator) Init() {
 					}
 					goto l88
 				l91:
+					position, tokenIndex, depth = position88, tokenIndex88, depth88
+					if !_rules[rulenamedIdentifier]() {
+						goto l251
+					}
+					goto l88
+				l251:
 					position, tokenIndex, depth = position88, tokenIndex88, depth88
 					if !_rules[rulefunctionCall]() {
 						goto l86
@@ -1664,18 +1688,31 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position94, tokenIndex94, depth94
 			return false
 		},
-		/* 20 functionArgument <- <(wholeSeries Action25)> */
+		/* 20 functionArgument <- <((wholeSeries Action25) / (e1 Action26))> */
 		func() bool {
 			position98, tokenIndex98, depth98 := position, tokenIndex, depth
 			{
 				position99 := position
 				depth++
-				if !_rules[rulewholeSeries]() {
-					goto l98
-				}
-				if !_rules[ruleAction25]() {
-					goto l98
+				{
+					position286, tokenIndex286, depth286 := position, tokenIndex, depth
+					if !_rules[rulewholeSeries]() {
+						goto l287
+					}
+					if !_rules[ruleAction25]() {
+						goto l287
+					}
+					goto l286
+				l287:
+					position, tokenIndex, depth = position286, tokenIndex286, depth286
+					if !_rules[rulee1]() {
+						goto l98
+					}
+					if !_rules[ruleAction26]() {
+						goto l98
+					}
 				}
+			l286:
 				depth--
 				add(rulefunctionArgument, position99)
 			}
@@ -1684,7 +1721,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position98, tokenIndex98, depth98
 			return false
 		},
-		/* 21 functionName <- <(<(([a-z] / [A-Z])+ ([a-z] / [A-Z] / [0-9])*)> Action26)> */
+		/* 21 functionName <- <(<(([a-z] / [A-Z])+ ([a-z] / [A-Z] / [0-9])*)> Action27)> */
 		func() bool {
 			position100, tokenIndex100, depth100 := position, tokenIndex, depth
 			{
@@ -1762,7 +1799,7 @@ func (p *Calculator) Init() {
 					depth--
 					add(rulePegText, position102)
 				}
-				if !_rules[ruleAction26]() {
+				if !_rules[ruleAction27]() {
 					goto l100
 				}
 				depth--
@@ -1773,7 +1810,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position100, tokenIndex100, depth100
 			return false
 		},
-		/* 22 wholeSeries <- <(specificIdentifierRange / generalIdentifierRange / thisIdentifierRange)> */
+		/* 22 wholeSeries <- <(specificIdentifierRange / generalIdentifierRange / thisIdentifierRange / namedIdentifierRange)> */
 		func() bool {
 			position114, tokenIndex114, depth114 := position, tokenIndex, depth
 			{
@@ -1794,6 +1831,12 @@ func (p *Calculator) Init() {
 				l118:
 					position, tokenIndex, depth = position116, tokenIndex116, depth116
 					if !_rules[rulethisIdentifierRange]() {
+						goto l252
+					}
+					goto l116
+				l252:
+					position, tokenIndex, depth = position116, tokenIndex116, depth116
+					if !_rules[rulenamedIdentifierRange]() {
 						goto l114
 					}
 				}
@@ -1806,7 +1849,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position114, tokenIndex114, depth114
 			return false
 		},
-		/* 23 specificIdentifier <- <('v' 'a' 'l' <[0-9]+> Action27 timeIndex? sp)> */
+		/* 23 specificIdentifier <- <('v' 'a' 'l' <[0-9]+> Action28 timeIndex? sp)> */
 		func() bool {
 			position119, tokenIndex119, depth119 := position, tokenIndex, depth
 			{
@@ -1845,7 +1888,7 @@ func (p *Calculator) Init() {
 					depth--
 					add(rulePegText, position121)
 				}
-				if !_rules[ruleAction27]() {
+				if !_rules[ruleAction28]() {
 					goto l119
 				}
 				{
@@ -1869,7 +1912,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position119, tokenIndex119, depth119
 			return false
 		},
-		/* 24 generalIdentifier <- <('v' 'a' 'l' Action28 timeIndex? sp)> */
+		/* 24 generalIdentifier <- <('v' 'a' 'l' Action29 timeIndex? sp)> */
 		func() bool {
 			position126, tokenIndex126, depth126 := position, tokenIndex, depth
 			{
@@ -1887,7 +1930,7 @@ func (p *Calculator) Init() {
 					goto l126
 				}
 				position++
-				if !_rules[ruleAction28]() {
+				if !_rules[ruleAction29]() {
 					goto l126
 				}
 				{
@@ -1911,7 +1954,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position126, tokenIndex126, depth126
 			return false
 		},
-		/* 25 thisIdentifier <- <('t' 'h' 'i' 's' Action29 timeIndex sp)> */
+		/* 25 thisIdentifier <- <('t' 'h' 'i' 's' Action30 timeIndex sp)> */
 		func() bool {
 			position130, tokenIndex130, depth130 := position, tokenIndex, depth
 			{
@@ -1933,7 +1976,7 @@ func (p *Calculator) Init() {
 					goto l130
 				}
 				position++
-				if !_rules[ruleAction29]() {
+				if !_rules[ruleAction30]() {
 					goto l130
 				}
 				if !_rules[ruletimeIndex]() {
@@ -1950,7 +1993,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position130, tokenIndex130, depth130
 			return false
 		},
-		/* 26 specificIdentifierRange <- <('v' 'a' 'l' <[0-9]+> Action30 timeRange sp)> */
+		/* 26 specificIdentifierRange <- <('v' 'a' 'l' <[0-9]+> Action31 timeRange sp)> */
 		func() bool {
 			position132, tokenIndex132, depth132 := position, tokenIndex, depth
 			{
@@ -1989,7 +2032,7 @@ func (p *Calculator) Init() {
 					depth--
 					add(rulePegText, position134)
 				}
-				if !_rules[ruleAction30]() {
+				if !_rules[ruleAction31]() {
 					goto l132
 				}
 				if !_rules[ruletimeRange]() {
@@ -2006,7 +2049,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position132, tokenIndex132, depth132
 			return false
 		},
-		/* 27 generalIdentifierRange <- <('v' 'a' 'l' Action31 timeRange sp)> */
+		/* 27 generalIdentifierRange <- <('v' 'a' 'l' Action32 timeRange sp)> */
 		func() bool {
 			position137, tokenIndex137, depth137 := position, tokenIndex, depth
 			{
@@ -2024,7 +2067,7 @@ func (p *Calculator) Init() {
 					goto l137
 				}
 				position++
-				if !_rules[ruleAction31]() {
+				if !_rules[ruleAction32]() {
 					goto l137
 				}
 				if !_rules[ruletimeRange]() {
@@ -2041,7 +2084,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position137, tokenIndex137, depth137
 			return false
 		},
-		/* 28 thisIdentifierRange <- <('t' 'h' 'i' 's' Action32 timeRange sp)> */
+		/* 28 thisIdentifierRange <- <('t' 'h' 'i' 's' Action33 timeRange sp)> */
 		func() bool {
 			position139, tokenIndex139, depth139 := position, tokenIndex, depth
 			{
@@ -2063,7 +2106,7 @@ func (p *Calculator) Init() {
 					goto l139
 				}
 				position++
-				if !_rules[ruleAction32]() {
+				if !_rules[ruleAction33]() {
 					goto l139
 				}
 				if !_rules[ruletimeRange]() {
@@ -2080,7 +2123,290 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position139, tokenIndex139, depth139
 			return false
 		},
-		/* 29 categoryIdentifier <- <('c' 'a' 't' 'e' 'g' 'o' 'r' 'y' Action33 sp)> */
+		/* 29 namedIdentifier <- <(fieldReference Action34 timeIndex? sp)> */
+		func() bool {
+			position253, tokenIndex253, depth253 := position, tokenIndex, depth
+			{
+				position254 := position
+				depth++
+				if !_rules[rulefieldReference]() {
+					goto l253
+				}
+				if !_rules[ruleAction34]() {
+					goto l253
+				}
+				{
+					position255, tokenIndex255, depth255 := position, tokenIndex, depth
+					if !_rules[ruletimeIndex]() {
+						goto l255
+					}
+					goto l256
+				l255:
+					position, tokenIndex, depth = position255, tokenIndex255, depth255
+				}
+			l256:
+				if !_rules[rulesp]() {
+					goto l253
+				}
+				depth--
+				add(rulenamedIdentifier, position254)
+			}
+			return true
+		l253:
+			position, tokenIndex, depth = position253, tokenIndex253, depth253
+			return false
+		},
+		/* 30 namedIdentifierRange <- <(fieldReference Action35 timeRange sp)> */
+		func() bool {
+			position257, tokenIndex257, depth257 := position, tokenIndex, depth
+			{
+				position258 := position
+				depth++
+				if !_rules[rulefieldReference]() {
+					goto l257
+				}
+				if !_rules[ruleAction35]() {
+					goto l257
+				}
+				if !_rules[ruletimeRange]() {
+					goto l257
+				}
+				if !_rules[rulesp]() {
+					goto l257
+				}
+				depth--
+				add(rulenamedIdentifierRange, position258)
+			}
+			return true
+		l257:
+			position, tokenIndex, depth = position257, tokenIndex257, depth257
+			return false
+		},
+		/* 31 fieldReference <- <(('[' <(!(']' / '\n' / '\r') .)+> ']') / ('f' 'i' 'e' 'l' 'd' sp '(' sp '"' <(!('"' / '\n' / '\r') .)+> '"' sp ')'))> */
+		func() bool {
+			position259, tokenIndex259, depth259 := position, tokenIndex, depth
+			{
+				position260 := position
+				depth++
+				{
+					position261, tokenIndex261, depth261 := position, tokenIndex, depth
+					if buffer[position] != rune('[') {
+						goto l262
+					}
+					position++
+					{
+						position263 := position
+						depth++
+						{
+							position264, tokenIndex264, depth264 := position, tokenIndex, depth
+							{
+								position265, tokenIndex265, depth265 := position, tokenIndex, depth
+								if buffer[position] != rune(']') {
+									goto l266
+								}
+								position++
+								goto l265
+							l266:
+								position, tokenIndex, depth = position265, tokenIndex265, depth265
+								if buffer[position] != rune('\n') {
+									goto l267
+								}
+								position++
+								goto l265
+							l267:
+								position, tokenIndex, depth = position265, tokenIndex265, depth265
+								if buffer[position] != rune('\r') {
+									goto l264
+								}
+								position++
+							}
+						l265:
+							goto l262
+						l264:
+							position, tokenIndex, depth = position264, tokenIndex264, depth264
+						}
+						if !matchDot() {
+							goto l262
+						}
+					l268:
+						{
+							position269, tokenIndex269, depth269 := position, tokenIndex, depth
+							{
+								position270, tokenIndex270, depth270 := position, tokenIndex, depth
+								{
+									position271, tokenIndex271, depth271 := position, tokenIndex, depth
+									if buffer[position] != rune(']') {
+										goto l272
+									}
+									position++
+									goto l271
+								l272:
+									position, tokenIndex, depth = position271, tokenIndex271, depth271
+									if buffer[position] != rune('\n') {
+										goto l273
+									}
+									position++
+									goto l271
+								l273:
+									position, tokenIndex, depth = position271, tokenIndex271, depth271
+									if buffer[position] != rune('\r') {
+										goto l270
+									}
+									position++
+								}
+							l271:
+								goto l269
+							l270:
+								position, tokenIndex, depth = position270, tokenIndex270, depth270
+							}
+							if !matchDot() {
+								goto l269
+							}
+							goto l268
+						l269:
+							position, tokenIndex, depth = position269, tokenIndex269, depth269
+						}
+						depth--
+						add(rulePegText, position263)
+					}
+					if buffer[position] != rune(']') {
+						goto l262
+					}
+					position++
+					goto l261
+				l262:
+					position, tokenIndex, depth = position261, tokenIndex261, depth261
+					if buffer[position] != rune('f') {
+						goto l259
+					}
+					position++
+					if buffer[position] != rune('i') {
+						goto l259
+					}
+					position++
+					if buffer[position] != rune('e') {
+						goto l259
+					}
+					position++
+					if buffer[position] != rune('l') {
+						goto l259
+					}
+					position++
+					if buffer[position] != rune('d') {
+						goto l259
+					}
+					position++
+					if !_rules[rulesp]() {
+						goto l259
+					}
+					if buffer[position] != rune('(') {
+						goto l259
+					}
+					position++
+					if !_rules[rulesp]() {
+						goto l259
+					}
+					if buffer[position] != rune('"') {
+						goto l259
+					}
+					position++
+					{
+						position274 := position
+						depth++
+						{
+							position275, tokenIndex275, depth275 := position, tokenIndex, depth
+							{
+								position276, tokenIndex276, depth276 := position, tokenIndex, depth
+								if buffer[position] != rune('"') {
+									goto l277
+								}
+								position++
+								goto l276
+							l277:
+								position, tokenIndex, depth = position276, tokenIndex276, depth276
+								if buffer[position] != rune('\n') {
+									goto l278
+								}
+								position++
+								goto l276
+							l278:
+								position, tokenIndex, depth = position276, tokenIndex276, depth276
+								if buffer[position] != rune('\r') {
+									goto l275
+								}
+								position++
+							}
+						l276:
+							goto l259
+						l275:
+							position, tokenIndex, depth = position275, tokenIndex275, depth275
+						}
+						if !matchDot() {
+							goto l259
+						}
+					l279:
+						{
+							position280, tokenIndex280, depth280 := position, tokenIndex, depth
+							{
+								position281, tokenIndex281, depth281 := position, tokenIndex, depth
+								{
+									position282, tokenIndex282, depth282 := position, tokenIndex, depth
+									if buffer[position] != rune('"') {
+										goto l283
+									}
+									position++
+									goto l282
+								l283:
+									position, tokenIndex, depth = position282, tokenIndex282, depth282
+									if buffer[position] != rune('\n') {
+										goto l284
+									}
+									position++
+									goto l282
+								l284:
+									position, tokenIndex, depth = position282, tokenIndex282, depth282
+									if buffer[position] != rune('\r') {
+										goto l281
+									}
+									position++
+								}
+							l282:
+								goto l280
+							l281:
+								position, tokenIndex, depth = position281, tokenIndex281, depth281
+							}
+							if !matchDot() {
+								goto l280
+							}
+							goto l279
+						l280:
+							position, tokenIndex, depth = position280, tokenIndex280, depth280
+						}
+						depth--
+						add(rulePegText, position274)
+					}
+					if buffer[position] != rune('"') {
+						goto l259
+					}
+					position++
+					if !_rules[rulesp]() {
+						goto l259
+					}
+					if buffer[position] != rune(')') {
+						goto l259
+					}
+					position++
+				}
+			l261:
+				depth--
+				add(rulefieldReference, position260)
+			}
+			return true
+		l259:
+			position, tokenIndex, depth = position259, tokenIndex259, depth259
+			return false
+		},
+		/* 32 categoryIdentifier <- <('c' 'a' 't' 'e' 'g' 'o' 'r' 'y' Action36 sp)> */
 		func() bool {
 			position141, tokenIndex141, depth141 := position, tokenIndex, depth
 			{
@@ -2118,7 +2444,7 @@ func (p *Calculator) Init() {
 					goto l141
 				}
 				position++
-				if !_rules[ruleAction33]() {
+				if !_rules[ruleAction36]() {
 					goto l141
 				}
 				if !_rules[rulesp]() {
@@ -2132,7 +2458,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position141, tokenIndex141, depth141
 			return false
 		},
-		/* 30 stringValue <- <(quote <(!('"' / '\\' / '\n' / '\r') .)*> quote Action34 sp)> */
+		/* 33 stringValue <- <(quote <(!('"' / '\\' / '\n' / '\r') .)*> quote Action37 sp)> */
 		func() bool {
 			position143, tokenIndex143, depth143 := position, tokenIndex, depth
 			{
@@ -2195,7 +2521,7 @@ func (p *Calculator) Init() {
 				if !_rules[rulequote]() {
 					goto l143
 				}
-				if !_rules[ruleAction34]() {
+				if !_rules[ruleAction37]() {
 					goto l143
 				}
 				if !_rules[rulesp]() {
@@ -2209,7 +2535,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position143, tokenIndex143, depth143
 			return false
 		},
-		/* 31 timeRange <- <(openIndex indexComputation colon indexComputation closeIndex Action35)> */
+		/* 34 timeRange <- <(openIndex indexComputation colon indexComputation closeIndex Action38)> */
 		func() bool {
 			position153, tokenIndex153, depth153 := position, tokenIndex, depth
 			{
@@ -2230,7 +2556,7 @@ func (p *Calculator) Init() {
 				if !_rules[rulecloseIndex]() {
 					goto l153
 				}
-				if !_rules[ruleAction35]() {
+				if !_rules[ruleAction38]() {
 					goto l153
 				}
 				depth--
@@ -2241,7 +2567,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position153, tokenIndex153, depth153
 			return false
 		},
-		/* 32 timeIndex <- <(openIndex indexComputation closeIndex)> */
+		/* 35 timeIndex <- <(openIndex indexComputation closeIndex)> */
 		func() bool {
 			position155, tokenIndex155, depth155 := position, tokenIndex, depth
 			{
@@ -2264,7 +2590,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position155, tokenIndex155, depth155
 			return false
 		},
-		/* 33 indexComputation <- <(indexExpr ((add indexExpr Action36) / (minus indexExpr Action37))*)> */
+		/* 36 indexComputation <- <(indexExpr ((add indexExpr Action39) / (minus indexExpr Action40))*)> */
 		func() bool {
 			position157, tokenIndex157, depth157 := position, tokenIndex, depth
 			{
@@ -2284,7 +2610,7 @@ func (p *Calculator) Init() {
 						if !_rules[ruleindexExpr]() {
 							goto l162
 						}
-						if !_rules[ruleAction36]() {
+						if !_rules[ruleAction39]() {
 							goto l162
 						}
 						goto l161
@@ -2296,7 +2622,7 @@ func (p *Calculator) Init() {
 						if !_rules[ruleindexExpr]() {
 							goto l160
 						}
-						if !_rules[ruleAction37]() {
+						if !_rules[ruleAction40]() {
 							goto l160
 						}
 					}
@@ -2313,7 +2639,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position157, tokenIndex157, depth157
 			return false
 		},
-		/* 34 indexExpr <- <((indexBegin Action38) / (indexEnd Action39) / (indexT Action40) / (<[0-9]+> Action41))> */
+		/* 37 indexExpr <- <((indexBegin Action41) / (indexEnd Action42) / (indexT Action43) / (<[0-9]+> &{ p.reach(position) } Action44))> */
 		func() bool {
 			position163, tokenIndex163, depth163 := position, tokenIndex, depth
 			{
@@ -2324,7 +2650,7 @@ func (p *Calculator) Init() {
 					if !_rules[ruleindexBegin]() {
 						goto l166
 					}
-					if !_rules[ruleAction38]() {
+					if !_rules[ruleAction41]() {
 						goto l166
 					}
 					goto l165
@@ -2333,7 +2659,7 @@ func (p *Calculator) Init() {
 					if !_rules[ruleindexEnd]() {
 						goto l167
 					}
-					if !_rules[ruleAction39]() {
+					if !_rules[ruleAction42]() {
 						goto l167
 					}
 					goto l165
@@ -2342,7 +2668,7 @@ func (p *Calculator) Init() {
 					if !_rules[ruleindexT]() {
 						goto l168
 					}
-					if !_rules[ruleAction40]() {
+					if !_rules[ruleAction43]() {
 						goto l168
 					}
 					goto l165
@@ -2369,7 +2695,10 @@ func (p *Calculator) Init() {
 						depth--
 						add(rulePegText, position169)
 					}
-					if !_rules[ruleAction41]() {
+					if !(p.reach(position)) {
+						goto l163
+					}
+					if !_rules[ruleAction44]() {
 						goto l163
 					}
 				}
@@ -2382,7 +2711,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position163, tokenIndex163, depth163
 			return false
 		},
-		/* 35 indexBegin <- <('b' 'e' 'g' 'i' 'n' sp)> */
+		/* 38 indexBegin <- <('b' 'e' 'g' 'i' 'n' sp)> */
 		func() bool {
 			position172, tokenIndex172, depth172 := position, tokenIndex, depth
 			{
@@ -2419,7 +2748,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position172, tokenIndex172, depth172
 			return false
 		},
-		/* 36 indexEnd <- <('e' 'n' 'd' sp)> */
+		/* 39 indexEnd <- <('e' 'n' 'd' sp)> */
 		func() bool {
 			position174, tokenIndex174, depth174 := position, tokenIndex, depth
 			{
@@ -2448,7 +2777,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position174, tokenIndex174, depth174
 			return false
 		},
-		/* 37 indexT <- <('t' sp)> */
+		/* 40 indexT <- <('t' sp)> */
 		func() bool {
 			position176, tokenIndex176, depth176 := position, tokenIndex, depth
 			{
@@ -2469,7 +2798,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position176, tokenIndex176, depth176
 			return false
 		},
-		/* 38 openIndex <- <('[' sp)> */
+		/* 41 openIndex <- <('[' sp)> */
 		func() bool {
 			position178, tokenIndex178, depth178 := position, tokenIndex, depth
 			{
@@ -2490,7 +2819,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position178, tokenIndex178, depth178
 			return false
 		},
-		/* 39 closeIndex <- <(']' sp)> */
+		/* 42 closeIndex <- <(']' sp)> */
 		func() bool {
 			position180, tokenIndex180, depth180 := position, tokenIndex, depth
 			{
@@ -2511,7 +2840,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position180, tokenIndex180, depth180
 			return false
 		},
-		/* 40 if <- <('i' 'f' sp)> */
+		/* 43 if <- <('i' 'f' sp)> */
 		func() bool {
 			position182, tokenIndex182, depth182 := position, tokenIndex, depth
 			{
@@ -2536,7 +2865,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position182, tokenIndex182, depth182
 			return false
 		},
-		/* 41 then <- <('t' 'h' 'e' 'n' sp)> */
+		/* 44 then <- <('t' 'h' 'e' 'n' sp)> */
 		func() bool {
 			position184, tokenIndex184, depth184 := position, tokenIndex, depth
 			{
@@ -2569,7 +2898,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position184, tokenIndex184, depth184
 			return false
 		},
-		/* 42 else <- <('e' 'l' 's' 'e' sp)> */
+		/* 45 else <- <('e' 'l' 's' 'e' sp)> */
 		func() bool {
 			position186, tokenIndex186, depth186 := position, tokenIndex, depth
 			{
@@ -2602,7 +2931,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position186, tokenIndex186, depth186
 			return false
 		},
-		/* 43 true <- <('t' 'r' 'u' 'e' sp Action42)> */
+		/* 46 true <- <('t' 'r' 'u' 'e' sp Action45)> */
 		func() bool {
 			position188, tokenIndex188, depth188 := position, tokenIndex, depth
 			{
@@ -2627,7 +2956,7 @@ func (p *Calculator) Init() {
 				if !_rules[rulesp]() {
 					goto l188
 				}
-				if !_rules[ruleAction42]() {
+				if !_rules[ruleAction45]() {
 					goto l188
 				}
 				depth--
@@ -2638,7 +2967,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position188, tokenIndex188, depth188
 			return false
 		},
-		/* 44 false <- <('f' 'a' 'l' 's' 'e' sp Action43)> */
+		/* 47 false <- <('f' 'a' 'l' 's' 'e' sp Action46)> */
 		func() bool {
 			position190, tokenIndex190, depth190 := position, tokenIndex, depth
 			{
@@ -2667,7 +2996,7 @@ func (p *Calculator) Init() {
 				if !_rules[rulesp]() {
 					goto l190
 				}
-				if !_rules[ruleAction43]() {
+				if !_rules[ruleAction46]() {
 					goto l190
 				}
 				depth--
@@ -2678,7 +3007,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position190, tokenIndex190, depth190
 			return false
 		},
-		/* 45 equal <- <((('=' '=') / '=') sp)> */
+		/* 48 equal <- <((('=' '=') / '=') sp)> */
 		func() bool {
 			position192, tokenIndex192, depth192 := position, tokenIndex, depth
 			{
@@ -2714,7 +3043,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position192, tokenIndex192, depth192
 			return false
 		},
-		/* 46 notEqual <- <((('!' '=') / ('<' '>')) sp)> */
+		/* 49 notEqual <- <((('!' '=') / ('<' '>')) sp)> */
 		func() bool {
 			position196, tokenIndex196, depth196 := position, tokenIndex, depth
 			{
@@ -2754,7 +3083,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position196, tokenIndex196, depth196
 			return false
 		},
-		/* 47 greaterThan <- <('>' sp)> */
+		/* 50 greaterThan <- <('>' sp)> */
 		func() bool {
 			position200, tokenIndex200, depth200 := position, tokenIndex, depth
 			{
@@ -2775,7 +3104,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position200, tokenIndex200, depth200
 			return false
 		},
-		/* 48 greaterThanEqual <- <('>' '=' sp)> */
+		/* 51 greaterThanEqual <- <('>' '=' sp)> */
 		func() bool {
 			position202, tokenIndex202, depth202 := position, tokenIndex, depth
 			{
@@ -2800,7 +3129,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position202, tokenIndex202, depth202
 			return false
 		},
-		/* 49 lessThan <- <('<' sp)> */
+		/* 52 lessThan <- <('<' sp)> */
 		func() bool {
 			position204, tokenIndex204, depth204 := position, tokenIndex, depth
 			{
@@ -2821,7 +3150,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position204, tokenIndex204, depth204
 			return false
 		},
-		/* 50 lessThanEqual <- <('<' '=' sp)> */
+		/* 53 lessThanEqual <- <('<' '=' sp)> */
 		func() bool {
 			position206, tokenIndex206, depth206 := position, tokenIndex, depth
 			{
@@ -2846,7 +3175,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position206, tokenIndex206, depth206
 			return false
 		},
-		/* 51 not <- <((('n' 'o' 't') / '!') sp)> */
+		/* 54 not <- <((('n' 'o' 't') / '!') sp)> */
 		func() bool {
 			position208, tokenIndex208, depth208 := position, tokenIndex, depth
 			{
@@ -2886,7 +3215,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position208, tokenIndex208, depth208
 			return false
 		},
-		/* 52 and <- <((('a' 'n' 'd') / ('&' '&')) sp)> */
+		/* 55 and <- <((('a' 'n' 'd') / ('&' '&')) sp)> */
 		func() bool {
 			position212, tokenIndex212, depth212 := position, tokenIndex, depth
 			{
@@ -2930,7 +3259,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position212, tokenIndex212, depth212
 			return false
 		},
-		/* 53 or <- <((('o' 'r') / ('|' '|')) sp)> */
+		/* 56 or <- <((('o' 'r') / ('|' '|')) sp)> */
 		func() bool {
 			position216, tokenIndex216, depth216 := position, tokenIndex, depth
 			{
@@ -2970,7 +3299,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position216, tokenIndex216, depth216
 			return false
 		},
-		/* 54 add <- <('+' sp)> */
+		/* 57 add <- <('+' sp)> */
 		func() bool {
 			position220, tokenIndex220, depth220 := position, tokenIndex, depth
 			{
@@ -2991,7 +3320,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position220, tokenIndex220, depth220
 			return false
 		},
-		/* 55 minus <- <('-' sp)> */
+		/* 58 minus <- <('-' sp)> */
 		func() bool {
 			position222, tokenIndex222, depth222 := position, tokenIndex, depth
 			{
@@ -3012,7 +3341,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position222, tokenIndex222, depth222
 			return false
 		},
-		/* 56 multiply <- <('*' sp)> */
+		/* 59 multiply <- <('*' sp)> */
 		func() bool {
 			position224, tokenIndex224, depth224 := position, tokenIndex, depth
 			{
@@ -3033,7 +3362,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position224, tokenIndex224, depth224
 			return false
 		},
-		/* 57 divide <- <('/' sp)> */
+		/* 60 divide <- <('/' sp)> */
 		func() bool {
 			position226, tokenIndex226, depth226 := position, tokenIndex, depth
 			{
@@ -3054,7 +3383,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position226, tokenIndex226, depth226
 			return false
 		},
-		/* 58 modulus <- <('%' sp)> */
+		/* 61 modulus <- <('%' sp)> */
 		func() bool {
 			position228, tokenIndex228, depth228 := position, tokenIndex, depth
 			{
@@ -3075,7 +3404,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position228, tokenIndex228, depth228
 			return false
 		},
-		/* 59 exponentiation <- <('^' sp)> */
+		/* 62 exponentiation <- <('^' sp)> */
 		func() bool {
 			position230, tokenIndex230, depth230 := position, tokenIndex, depth
 			{
@@ -3096,7 +3425,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position230, tokenIndex230, depth230
 			return false
 		},
-		/* 60 open <- <('(' sp)> */
+		/* 63 open <- <('(' sp)> */
 		func() bool {
 			position232, tokenIndex232, depth232 := position, tokenIndex, depth
 			{
@@ -3117,7 +3446,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position232, tokenIndex232, depth232
 			return false
 		},
-		/* 61 close <- <(')' sp)> */
+		/* 64 close <- <(')' sp)> */
 		func() bool {
 			position234, tokenIndex234, depth234 := position, tokenIndex, depth
 			{
@@ -3138,7 +3467,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position234, tokenIndex234, depth234
 			return false
 		},
-		/* 62 comma <- <(',' sp)> */
+		/* 65 comma <- <(',' sp)> */
 		func() bool {
 			position236, tokenIndex236, depth236 := position, tokenIndex, depth
 			{
@@ -3159,7 +3488,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position236, tokenIndex236, depth236
 			return false
 		},
-		/* 63 quote <- <('"' sp)> */
+		/* 66 quote <- <('"' sp)> */
 		func() bool {
 			position238, tokenIndex238, depth238 := position, tokenIndex, depth
 			{
@@ -3180,7 +3509,7 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position238, tokenIndex238, depth238
 			return false
 		},
-		/* 64 colon <- <(':' sp)> */
+		/* 67 colon <- <(':' sp)> */
 		func() bool {
 			position240, tokenIndex240, depth240 := position, tokenIndex, depth
 			{
@@ -3201,8 +3530,9 @@ func (p *Calculator) Init() {
 			position, tokenIndex, depth = position240, tokenIndex240, depth240
 			return false
 		},
-		/* 65 sp <- <(' ' / '\t')*> */
+		/* 68 sp <- <((' ' / '\t')* &{ p.reach(position) })> */
 		func() bool {
+			position288, tokenIndex288, depth288 := position, tokenIndex, depth
 			{
 				position243 := position
 				depth++
@@ -3228,166 +3558,172 @@ func (p *Calculator) Init() {
 				l245:
 					position, tokenIndex, depth = position245, tokenIndex245, depth245
 				}
+				if !(p.reach(position)) {
+					goto l288
+				}
 				depth--
 				add(rulesp, position243)
 			}
 			return true
+		l288:
+			position, tokenIndex, depth = position288, tokenIndex288, depth288
+			return false
 		},
-		/* 67 Action0 <- <{ p.AddOperator(TypeThen) }> */
+		/* 70 Action0 <- <{ p.AddOperator(TypeThen) }> */
 		func() bool {
 			{
 				add(ruleAction0, position)
 			}
 			return true
 		},
-		/* 68 Action1 <- <{ p.AddOperator(TypeElse) }> */
+		/* 71 Action1 <- <{ p.AddOperator(TypeElse) }> */
 		func() bool {
 			{
 				add(ruleAction1, position)
 			}
 			return true
 		},
-		/* 69 Action2 <- <{ p.AddOperator(TypeAnd) }> */
+		/* 72 Action2 <- <{ p.AddOperator(TypeAnd) }> */
 		func() bool {
 			{
 				add(ruleAction2, position)
 			}
 			return true
 		},
-		/* 70 Action3 <- <{ p.AddOperator(TypeOr) }> */
+		/* 73 Action3 <- <{ p.AddOperator(TypeOr) }> */
 		func() bool {
 			{
 				add(ruleAction3, position)
 			}
 			return true
 		},
-		/* 71 Action4 <- <{ p.AddOperator(TypeNot) }> */
+		/* 74 Action4 <- <{ p.AddOperator(TypeNot) }> */
 		func() bool {
 			{
 				add(ruleAction4, position)
 			}
 			return true
 		},
-		/* 72 Action5 <- <{ p.AddOperator(TypeTimeEqual) }> */
+		/* 75 Action5 <- <{ p.AddOperator(TypeTimeEqual) }> */
 		func() bool {
 			{
 				add(ruleAction5, position)
 			}
 			return true
 		},
-		/* 73 Action6 <- <{ p.AddOperator(TypeEqual) }> */
+		/* 76 Action6 <- <{ p.AddOperator(TypeEqual) }> */
 		func() bool {
 			{
 				add(ruleAction6, position)
 			}
 			return true
 		},
-		/* 74 Action7 <- <{ p.AddOperator(TypeNotEqual) }> */
+		/* 77 Action7 <- <{ p.AddOperator(TypeNotEqual) }> */
 		func() bool {
 			{
 				add(ruleAction7, position)
 			}
 			return true
 		},
-		/* 75 Action8 <- <{ p.AddOperator(TypeGreaterThan) }> */
+		/* 78 Action8 <- <{ p.AddOperator(TypeGreaterThan) }> */
 		func() bool {
 			{
 				add(ruleAction8, position)
 			}
 			return true
 		},
-		/* 76 Action9 <- <{ p.AddOperator(TypeGreaterThanEqual) }> */
+		/* 79 Action9 <- <{ p.AddOperator(TypeGreaterThanEqual) }> */
 		func() bool {
 			{
 				add(ruleAction9, position)
 			}
 			return true
 		},
-		/* 77 Action10 <- <{ p.AddOperator(TypeLessThan) }> */
+		/* 80 Action10 <- <{ p.AddOperator(TypeLessThan) }> */
 		func() bool {
 			{
 				add(ruleAction10, position)
 			}
 			return true
 		},
-		/* 78 Action11 <- <{ p.AddOperator(TypeLessThanEqual) }> */
+		/* 81 Action11 <- <{ p.AddOperator(TypeLessThanEqual) }> */
 		func() bool {
 			{
 				add(ruleAction11, position)
 			}
 			return true
 		},
-		/* 79 Action12 <- <{ p.AddOperator(TypeLogicalEqual) }> */
+		/* 82 Action12 <- <{ p.AddOperator(TypeLogicalEqual) }> */
 		func() bool {
 			{
 				add(ruleAction12, position)
 			}
 			return true
 		},
-		/* 80 Action13 <- <{ p.AddOperator(TypeLogicalNotEqual) }> */
+		/* 83 Action13 <- <{ p.AddOperator(TypeLogicalNotEqual) }> */
 		func() bool {
 			{
 				add(ruleAction13, position)
 			}
 			return true
 		},
-		/* 81 Action14 <- <{ p.AddOperator(TypeStringEqual)}> */
+		/* 84 Action14 <- <{ p.AddOperator(TypeStringEqual)}> */
 		func() bool {
 			{
 				add(ruleAction14, position)
 			}
 			return true
 		},
-		/* 82 Action15 <- <{ p.AddOperator(TypeStringNotEqual) }> */
+		/* 85 Action15 <- <{ p.AddOperator(TypeStringNotEqual) }> */
 		func() bool {
 			{
 				add(ruleAction15, position)
 			}
 			return true
 		},
-		/* 83 Action16 <- <{ p.AddOperator(TypeAdd) }> */
+		/* 86 Action16 <- <{ p.AddOperator(TypeAdd) }> */
 		func() bool {
 			{
 				add(ruleAction16, position)
 			}
 			return true
 		},
-		/* 84 Action17 <- <{ p.AddOperator(TypeSubtract) }> */
+		/* 87 Action17 <- <{ p.AddOperator(TypeSubtract) }> */
 		func() bool {
 			{
 				add(ruleAction17, position)
 			}
 			return true
 		},
-		/* 85 Action18 <- <{ p.AddOperator(TypeMultiply) }> */
+		/* 88 Action18 <- <{ p.AddOperator(TypeMultiply) }> */
 		func() bool {
 			{
 				add(ruleAction18, position)
 			}
 			return true
 		},
-		/* 86 Action19 <- <{ p.AddOperator(TypeDivide) }> */
+		/* 89 Action19 <- <{ p.AddOperator(TypeDivide) }> */
 		func() bool {
 			{
 				add(ruleAction19, position)
 			}
 			return true
 		},
-		/* 87 Action20 <- <{ p.AddOperator(TypeModulus) }> */
+		/* 90 Action20 <- <{ p.AddOperator(TypeModulus) }> */
 		func() bool {
 			{
 				add(ruleAction20, position)
 			}
 			return true
 		},
-		/* 88 Action21 <- <{ p.AddOperator(TypeExponentiation) }> */
+		/* 91 Action21 <- <{ p.AddOperator(TypeExponentiation) }> */
 		func() bool {
 			{
 				add(ruleAction21, position)
 			}
 			return true
 		},
-		/* 89 Action22 <- <{ p.AddOperator(TypeNegation) }> */
+		/* 92 Action22 <- <{ p.AddOperator(TypeNegation) }> */
 		func() bool {
 			{
 				add(ruleAction22, position)
@@ -3395,153 +3731,174 @@ func (p *Calculator) Init() {
 			return true
 		},
 		nil,
-		/* 91 Action23 <- <{ p.AddValue(buffer[begin:end]) }> */
+		/* 94 Action23 <- <{ p.AddValue(buffer[begin:end]) }> */
 		func() bool {
 			{
 				add(ruleAction23, position)
 			}
 			return true
 		},
-		/* 92 Action24 <- <{ p.AddFunctionCall() }> */
+		/* 95 Action24 <- <{ p.AddFunctionCall() }> */
 		func() bool {
 			{
 				add(ruleAction24, position)
 			}
 			return true
 		},
-		/* 93 Action25 <- <{ p.AddFunctionArgument() }> */
+		/* 96 Action25 <- <{ p.AddFunctionArgument(TypeTimeRange) }> */
 		func() bool {
 			{
 				add(ruleAction25, position)
 			}
 			return true
 		},
-		/* 94 Action26 <- <{ p.AddFunctionName(buffer[begin:end]) }> */
+		/* 97 Action26 <- <{ p.AddFunctionArgument(TypeNumber) }> */
 		func() bool {
 			{
 				add(ruleAction26, position)
 			}
 			return true
 		},
-		/* 95 Action27 <- <{ p.AddIdentifierSpecific(buffer[begin:end]) }> */
+		/* 98 Action27 <- <{ p.AddFunctionName(buffer[begin:end]) }> */
 		func() bool {
 			{
 				add(ruleAction27, position)
 			}
 			return true
 		},
-		/* 96 Action28 <- <{ p.AddIdentifierGeneral() }> */
+		/* 99 Action28 <- <{ p.AddIdentifierSpecific(buffer[begin:end]) }> */
 		func() bool {
 			{
 				add(ruleAction28, position)
 			}
 			return true
 		},
-		/* 97 Action29 <- <{ p.AddIdentifierThis() }> */
+		/* 100 Action29 <- <{ p.AddIdentifierGeneral() }> */
 		func() bool {
 			{
 				add(ruleAction29, position)
 			}
 			return true
 		},
-		/* 98 Action30 <- <{ p.AddIdentifierSpecificRange(buffer[begin:end]) }> */
+		/* 101 Action30 <- <{ p.AddIdentifierThis() }> */
 		func() bool {
 			{
 				add(ruleAction30, position)
 			}
 			return true
 		},
-		/* 99 Action31 <- <{ p.AddIdentifierGeneralRange() }> */
+		/* 102 Action31 <- <{ p.AddIdentifierSpecificRange(buffer[begin:end]) }> */
 		func() bool {
 			{
 				add(ruleAction31, position)
 			}
 			return true
 		},
-		/* 100 Action32 <- <{ p.AddIdentifierThisRange() }> */
+		/* 103 Action32 <- <{ p.AddIdentifierGeneralRange() }> */
 		func() bool {
 			{
 				add(ruleAction32, position)
 			}
 			return true
 		},
-		/* 101 Action33 <- <{ p.AddCategoryIdentifier() }> */
+		/* 104 Action33 <- <{ p.AddIdentifierThisRange() }> */
 		func() bool {
 			{
 				add(ruleAction33, position)
 			}
 			return true
 		},
-		/* 102 Action34 <- <{ p.AddStringValue(buffer[begin:end]) }> */
+		/* 105 Action34 <- <{ p.AddIdentifierNamed(buffer[begin:end]) }> */
 		func() bool {
 			{
 				add(ruleAction34, position)
 			}
 			return true
 		},
-		/* 103 Action35 <- <{ p.AddIndexOperator(TypeTimeRange) }> */
+		/* 106 Action35 <- <{ p.AddIdentifierNamedRange(buffer[begin:end]) }> */
 		func() bool {
 			{
 				add(ruleAction35, position)
 			}
 			return true
 		},
-		/* 104 Action36 <- <{ p.AddIndexOperator(TypeAdd) }> */
+		/* 107 Action36 <- <{ p.AddCategoryIdentifier() }> */
 		func() bool {
 			{
 				add(ruleAction36, position)
 			}
 			return true
 		},
-		/* 105 Action37 <- <{ p.AddIndexOperator(TypeSubtract) }> */
+		/* 108 Action37 <- <{ p.AddStringValue(buffer[begin:end]) }> */
 		func() bool {
 			{
 				add(ruleAction37, position)
 			}
 			return true
 		},
-		/* 106 Action38 <- <{ p.AddIndexOperator(TypeBegin) }> */
+		/* 109 Action38 <- <{ p.AddIndexOperator(TypeTimeRange) }> */
 		func() bool {
 			{
 				add(ruleAction38, position)
 			}
 			return true
 		},
-		/* 107 Action39 <- <{ p.AddIndexOperator(TypeEnd) }> */
+		/* 110 Action39 <- <{ p.AddIndexOperator(TypeAdd) }> */
 		func() bool {
 			{
 				add(ruleAction39, position)
 			}
 			return true
 		},
-		/* 108 Action40 <- <{ p.AddIndexOperator(TypeCurrentTime) }> */
+		/* 111 Action40 <- <{ p.AddIndexOperator(TypeSubtract) }> */
 		func() bool {
 			{
 				add(ruleAction40, position)
 			}
 			return true
 		},
-		/* 109 Action41 <- <{ p.AddIndexValue(buffer[begin:end]) }> */
+		/* 112 Action41 <- <{ p.AddIndexOperator(TypeBegin) }> */
 		func() bool {
 			{
 				add(ruleAction41, position)
 			}
 			return true
 		},
-		/* 110 Action42 <- <{ p.AddOperator(TypeTrue) }> */
+		/* 113 Action42 <- <{ p.AddIndexOperator(TypeEnd) }> */
 		func() bool {
 			{
 				add(ruleAction42, position)
 			}
 			return true
 		},
-		/* 111 Action43 <- <{ p.AddOperator(TypeFalse) }> */
+		/* 114 Action43 <- <{ p.AddIndexOperator(TypeCurrentTime) }> */
 		func() bool {
 			{
 				add(ruleAction43, position)
 			}
 			return true
 		},
+		/* 115 Action44 <- <{ p.AddIndexValue(buffer[begin:end]) }> */
+		func() bool {
+			{
+				add(ruleAction44, position)
+			}
+			return true
+		},
+		/* 116 Action45 <- <{ p.AddOperator(TypeTrue) }> */
+		func() bool {
+			{
+				add(ruleAction45, position)
+			}
+			return true
+		},
+		/* 117 Action46 <- <{ p.AddOperator(TypeFalse) }> */
+		func() bool {
+			{
+				add(ruleAction46, position)
+			}
+			return true
+		},
 	}
 	p.rules = _rules
 }
@@ -1,8 +1,6 @@
 package parse
 
-import "log"
-
-var calc *Calculator
+import "fmt"
 
 func ExampleParse() {
 	//expression := "val[t-1] + 5 % 2 + 3.5 + val0 + val[begin] + val * val[end] + val[end-1] + val1[t-1] * 1024"
@@ -17,18 +15,18 @@ func ExampleParse() {
 	// expression := "1 + sum(val[t-1:t])"
 	// expression := "val[t] + sum(this[t-1:t])"
 	// expression := "if t == begin then 0 else 1"
-	expression := "val"
-	calc = &Calculator{Buffer: expression}
-	calc.Init()
-	calc.Expression.Init(expression)
-	if err := calc.Parse(); err != nil {
-		log.Fatal(err)
+	for _, expression := range []string{"val", "val1[t-1] * * 2", "sum(val[t-1:t]"} {
+		if _, err := Parse(expression); err != nil {
+			fmt.Println(err)
+		} else {
+			fmt.Println("ok")
+		}
 	}
-	// calc.Execute()
-	// fmt.Printf("%s =\n%s\n", expression, calc.String())
 
 	// Output:
-	//
+	// ok
+	// Unexpected "*" at line 1, column 13, expected a number, "val", "this", a field, a function, "(", "-"
+	// Unexpected end of formula at line 1, column 15, expected ")", ","
 }
 
 //
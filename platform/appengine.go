@@ -0,0 +1,237 @@
+package platform
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"time"
+
+	"google.golang.org/appengine"
+	"google.golang.org/appengine/datastore"
+	aelog "google.golang.org/appengine/log"
+	"google.golang.org/appengine/memcache"
+	"google.golang.org/appengine/taskqueue"
+	"google.golang.org/appengine/urlfetch"
+)
+
+var appEngineServices = AppEngine()
+
+// AppEngine returns the services backed by the App Engine APIs. They only work
+// with contexts of App Engine requests.
+func AppEngine() *Services {
+	return &Services{
+		Log:   appEngineLog{},
+		Cache: appEngineCache{},
+		Store: appEngineStore{},
+		Queue: appEngineQueue{},
+		Fetch: appEngineFetch{},
+
+		DevServer: appengine.IsDevAppServer(),
+	}
+}
+
+type appEngineLog struct{}
+
+func (appEngineLog) Debugf(ctx context.Context, format string, args ...interface{}) {
+	aelog.Debugf(ctx, format, args...)
+}
+
+func (appEngineLog) Infof(ctx context.Context, format string, args ...interface{}) {
+	aelog.Infof(ctx, format, args...)
+}
+
+func (appEngineLog) Warningf(ctx context.Context, format string, args ...interface{}) {
+	aelog.Warningf(ctx, format, args...)
+}
+
+func (appEngineLog) Errorf(ctx context.Context, format string, args ...interface{}) {
+	aelog.Errorf(ctx, format, args...)
+}
+
+func (appEngineLog) Criticalf(ctx context.Context, format string, args ...interface{}) {
+	aelog.Criticalf(ctx, format, args...)
+}
+
+type appEngineCache struct{}
+
+func (appEngineCache) Get(ctx context.Context, key string) ([]byte, error) {
+	item, err := memcache.Get(ctx, key)
+	if err == memcache.ErrCacheMiss {
+		return nil, ErrCacheMiss
+	} else if err != nil {
+		return nil, err
+	}
+
+	return item.Value, nil
+}
+
+func (appEngineCache) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	items, err := memcache.GetMulti(ctx, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string][]byte, len(items))
+	for k, v := range items {
+		values[k] = v.Value
+	}
+
+	return values, nil
+}
+
+func (appEngineCache) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	return memcache.Set(ctx, &memcache.Item{
+		Key:        key,
+		Value:      value,
+		Expiration: expiration,
+	})
+}
+
+func (appEngineCache) Delete(ctx context.Context, key string) error {
+	err := memcache.Delete(ctx, key)
+	if err == memcache.ErrCacheMiss {
+		return ErrCacheMiss
+	}
+
+	return err
+}
+
+func (appEngineCache) Increment(ctx context.Context, key string, delta int64, initial uint64) (uint64, error) {
+	return memcache.Increment(ctx, key, delta, initial)
+}
+
+type appEngineStore struct{}
+
+func (appEngineStore) Insert(ctx context.Context, kind string, parent string, v interface{}) (string, error) {
+	var parentKey *datastore.Key
+
+	if parent != "" {
+		k, err := datastore.DecodeKey(parent)
+		if err != nil {
+			return "", ErrInvalidKey
+		}
+		parentKey = k
+	}
+
+	key, err := datastore.Put(ctx, datastore.NewIncompleteKey(ctx, kind, parentKey), v)
+	if err != nil {
+		return "", err
+	}
+
+	return key.Encode(), nil
+}
+
+func (appEngineStore) Update(ctx context.Context, keyString string, v interface{}) error {
+	key, err := datastore.DecodeKey(keyString)
+	if err != nil {
+		return err
+	}
+
+	_, err = datastore.Put(ctx, key, v)
+
+	return err
+}
+
+func (appEngineStore) NamedKey(ctx context.Context, kind string, parent string, name string) (string, error) {
+	var parentKey *datastore.Key
+
+	if parent != "" {
+		k, err := datastore.DecodeKey(parent)
+		if err != nil {
+			return "", ErrInvalidKey
+		}
+		parentKey = k
+	}
+
+	return datastore.NewKey(ctx, kind, name, 0, parentKey).Encode(), nil
+}
+
+func (appEngineStore) Put(ctx context.Context, keyString string, v interface{}) error {
+	key, err := datastore.DecodeKey(keyString)
+	if err != nil {
+		return err
+	}
+
+	_, err = datastore.Put(ctx, key, v)
+
+	return err
+}
+
+func (appEngineStore) Get(ctx context.Context, keyString string, v interface{}) error {
+	key, err := datastore.DecodeKey(keyString)
+	if err != nil {
+		return err
+	}
+
+	err = datastore.Get(ctx, key, v)
+	if err == datastore.ErrNoSuchEntity {
+		return ErrNoSuchDocument
+	}
+
+	return err
+}
+
+func (appEngineStore) Delete(ctx context.Context, keyString string) error {
+	key, err := datastore.DecodeKey(keyString)
+	if err != nil {
+		return err
+	}
+
+	return datastore.Delete(ctx, key)
+}
+
+func (appEngineStore) FindOne(ctx context.Context, kind string, field string, value string, v interface{}) (string, error) {
+	key, err := datastore.NewQuery(kind).Filter(field+" =", value).Run(ctx).Next(v)
+
+	if err == datastore.Done {
+		return "", nil
+	} else if key == nil {
+		return "", err
+	}
+
+	// Some errors, such as a field mismatch, still load the document
+	return key.Encode(), err
+}
+
+func (appEngineStore) FindAll(ctx context.Context, kind string, field string, value string, limit int, dst interface{}) error {
+	query := datastore.NewQuery(kind)
+
+	if field != "" {
+		query = query.Filter(field+" =", value)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	_, err := query.GetAll(ctx, dst)
+
+	if err == datastore.Done {
+		return nil
+	}
+
+	return err
+}
+
+func (appEngineStore) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return datastore.RunInTransaction(ctx, fn, nil)
+}
+
+type appEngineQueue struct{}
+
+func (appEngineQueue) Add(ctx context.Context, path string, params url.Values, retryLimit int) error {
+	t := taskqueue.NewPOSTTask(path, params)
+	if t.RetryOptions == nil {
+		t.RetryOptions = &taskqueue.RetryOptions{}
+	}
+	t.RetryOptions.RetryLimit = int32(retryLimit)
+
+	_, err := taskqueue.Add(ctx, t, "")
+
+	return err
+}
+
+type appEngineFetch struct{}
+
+func (appEngineFetch) Client(ctx context.Context) *http.Client {
+	return urlfetch.Client(ctx)
+}
@@ -0,0 +1,523 @@
+package platform
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	stdlog "log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoSuchDocument is returned by the stores for unknown keys
+var ErrNoSuchDocument = errors.New("platform: no such document")
+
+// ErrInvalidKey is returned by Insert for a parent that can't be decoded
+var ErrInvalidKey = errors.New("platform: invalid key")
+
+// TaskFn handles a task added to the in-process queue
+type TaskFn func(ctx context.Context, w http.ResponseWriter, r *http.Request)
+
+// Local returns services that keep everything in memory and log to stderr.
+// Tasks are run in the background by the handlers in tasks, keyed by path.
+func Local(tasks map[string]TaskFn) *Services {
+	s := &Services{
+		Log:   NewStdLogger(stdlog.New(os.Stderr, "", stdlog.LstdFlags), false),
+		Cache: NewMemoryCache(),
+		Store: NewMemoryStore(),
+		Fetch: defaultFetch{},
+	}
+	s.Queue = &LocalQueue{services: s, tasks: tasks}
+
+	return s
+}
+
+// StdLogger writes to a standard library logger. Debug messages are dropped
+// unless verbose is set.
+type StdLogger struct {
+	logger  *stdlog.Logger
+	verbose bool
+}
+
+func NewStdLogger(logger *stdlog.Logger, verbose bool) *StdLogger {
+	return &StdLogger{logger, verbose}
+}
+
+func (l *StdLogger) Debugf(ctx context.Context, format string, args ...interface{}) {
+	if l.verbose {
+		l.logger.Printf("DEBUG "+format, args...)
+	}
+}
+
+func (l *StdLogger) Infof(ctx context.Context, format string, args ...interface{}) {
+	l.logger.Printf("INFO "+format, args...)
+}
+
+func (l *StdLogger) Warningf(ctx context.Context, format string, args ...interface{}) {
+	l.logger.Printf("WARNING "+format, args...)
+}
+
+func (l *StdLogger) Errorf(ctx context.Context, format string, args ...interface{}) {
+	l.logger.Printf("ERROR "+format, args...)
+}
+
+func (l *StdLogger) Criticalf(ctx context.Context, format string, args ...interface{}) {
+	l.logger.Printf("CRITICAL "+format, args...)
+}
+
+type cacheEntry struct {
+	value   []byte
+	expires time.Time
+}
+
+// MemoryCache is a Cache held in this process
+type MemoryCache struct {
+	mutex   sync.Mutex
+	entries map[string]cacheEntry
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: make(map[string]cacheEntry)}
+}
+
+func (c *MemoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.get(key)
+}
+
+func (c *MemoryCache) get(key string) ([]byte, error) {
+	entry, ok := c.entries[key]
+
+	if ok && !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		delete(c.entries, key)
+		ok = false
+	}
+
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+
+	value := make([]byte, len(entry.value))
+	copy(value, entry.value)
+
+	return value, nil
+}
+
+func (c *MemoryCache) GetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	values := make(map[string][]byte)
+	for _, k := range keys {
+		if v, err := c.get(k); err == nil {
+			values[k] = v
+		}
+	}
+
+	return values, nil
+}
+
+func (c *MemoryCache) Set(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	entry := cacheEntry{value: make([]byte, len(value))}
+	copy(entry.value, value)
+
+	if expiration > 0 {
+		entry.expires = time.Now().Add(expiration)
+	}
+
+	c.entries[key] = entry
+
+	return nil
+}
+
+func (c *MemoryCache) Delete(ctx context.Context, key string) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.entries[key]; !ok {
+		return ErrCacheMiss
+	}
+	delete(c.entries, key)
+
+	return nil
+}
+
+func (c *MemoryCache) Increment(ctx context.Context, key string, delta int64, initial uint64) (uint64, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	n := initial
+	entry, err := c.get(key)
+	if err == nil {
+		if n, err = strconv.ParseUint(string(entry), 10, 64); err != nil {
+			return 0, errors.New("platform: cannot increment a value that isn't a number")
+		}
+	}
+
+	n = uint64(int64(n) + delta)
+
+	value := cacheEntry{value: []byte(strconv.FormatUint(n, 10))}
+	if existing, ok := c.entries[key]; ok {
+		value.expires = existing.expires
+	}
+	c.entries[key] = value
+
+	return n, nil
+}
+
+type document struct {
+	kind string
+	data []byte
+}
+
+// MemoryStore is a Store held in this process. Documents are gob encoded so
+// that callers never share them.
+type MemoryStore struct {
+	mutex  sync.Mutex
+	nextId int64
+	keys   []string
+	docs   map[string]document
+	// tx is held by the transaction that is running
+	tx sync.Mutex
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{docs: make(map[string]document)}
+}
+
+func encodeDocument(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	err := gob.NewEncoder(&buf).Encode(v)
+
+	return buf.Bytes(), err
+}
+
+func decodeDocument(b []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
+
+// validKey reports whether key has the form of the keys the store hands out,
+// kind/id or kind/name optionally preceded by the parent key
+func validKey(key string) bool {
+	parts := strings.Split(key, "/")
+
+	if len(parts)%2 != 0 {
+		return false
+	}
+
+	for _, v := range parts {
+		if v == "" {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *MemoryStore) Insert(ctx context.Context, kind string, parent string, v interface{}) (string, error) {
+	if parent != "" && !validKey(parent) {
+		return "", ErrInvalidKey
+	}
+
+	b, err := encodeDocument(v)
+	if err != nil {
+		return "", err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.nextId++
+	key := kind + "/" + strconv.FormatInt(s.nextId, 10)
+	if parent != "" {
+		key = parent + "/" + key
+	}
+
+	s.keys = append(s.keys, key)
+	s.docs[key] = document{kind, b}
+
+	return key, nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, key string, v interface{}) error {
+	b, err := encodeDocument(v)
+	if err != nil {
+		return err
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	doc, ok := s.docs[key]
+	if !ok {
+		return ErrNoSuchDocument
+	}
+
+	doc.data = b
+	s.docs[key] = doc
+
+	return nil
+}
+
+func (s *MemoryStore) NamedKey(ctx context.Context, kind string, parent string, name string) (string, error) {
+	if parent != "" && !validKey(parent) {
+		return "", ErrInvalidKey
+	}
+
+	key := kind + "/" + name
+	if parent != "" {
+		key = parent + "/" + key
+	}
+
+	if !validKey(key) {
+		return "", ErrInvalidKey
+	}
+
+	return key, nil
+}
+
+func (s *MemoryStore) Put(ctx context.Context, key string, v interface{}) error {
+	if !validKey(key) {
+		return ErrInvalidKey
+	}
+
+	b, err := encodeDocument(v)
+	if err != nil {
+		return err
+	}
+
+	parts := strings.Split(key, "/")
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.docs[key]; !ok {
+		s.keys = append(s.keys, key)
+	}
+	s.docs[key] = document{parts[len(parts)-2], b}
+
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string, v interface{}) error {
+	s.mutex.Lock()
+	doc, ok := s.docs[key]
+	s.mutex.Unlock()
+
+	if !ok {
+		return ErrNoSuchDocument
+	}
+
+	return decodeDocument(doc.data, v)
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, ok := s.docs[key]; !ok {
+		return ErrNoSuchDocument
+	}
+	delete(s.docs, key)
+
+	for i, k := range s.keys {
+		if k == key {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+// documents returns the keys and data of a kind in the order they were inserted
+func (s *MemoryStore) documents(kind string) ([]string, [][]byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	keys := make([]string, 0)
+	data := make([][]byte, 0)
+
+	for _, k := range s.keys {
+		if doc := s.docs[k]; doc.kind == kind {
+			keys = append(keys, k)
+			data = append(data, doc.data)
+		}
+	}
+
+	return keys, data
+}
+
+// fieldMatches compares the field of a decoded document with the value the
+// way it would be written in a query
+func fieldMatches(v reflect.Value, field string, value string) bool {
+	if field == "" {
+		return true
+	}
+
+	f := reflect.Indirect(v).FieldByName(field)
+
+	return f.IsValid() && fmt.Sprint(f.Interface()) == value
+}
+
+func (s *MemoryStore) FindOne(ctx context.Context, kind string, field string, value string, v interface{}) (string, error) {
+	keys, data := s.documents(kind)
+	t := reflect.TypeOf(v).Elem()
+
+	for i, b := range data {
+		candidate := reflect.New(t)
+
+		if err := decodeDocument(b, candidate.Interface()); err != nil {
+			return keys[i], err
+		}
+
+		if fieldMatches(candidate, field, value) {
+			reflect.ValueOf(v).Elem().Set(candidate.Elem())
+			return keys[i], nil
+		}
+	}
+
+	return "", nil
+}
+
+func (s *MemoryStore) FindAll(ctx context.Context, kind string, field string, value string, limit int, dst interface{}) error {
+	_, data := s.documents(kind)
+	slice := reflect.ValueOf(dst).Elem()
+	t := slice.Type().Elem()
+	found := 0
+
+	for _, b := range data {
+		if limit > 0 && found >= limit {
+			break
+		}
+
+		candidate := reflect.New(t)
+
+		if err := decodeDocument(b, candidate.Interface()); err != nil {
+			return err
+		}
+
+		if fieldMatches(candidate, field, value) {
+			slice.Set(reflect.Append(slice, candidate.Elem()))
+			found++
+		}
+	}
+
+	return nil
+}
+
+// RunInTransaction runs one transaction at a time. Unlike a datastore
+// transaction, the writes fn made before failing are kept.
+func (s *MemoryStore) RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	s.tx.Lock()
+	defer s.tx.Unlock()
+
+	return fn(ctx)
+}
+
+// LocalQueue runs each task in its own goroutine with the services of the
+// queue. Failed tasks are not retried.
+type LocalQueue struct {
+	services *Services
+	tasks    map[string]TaskFn
+	wg       sync.WaitGroup
+}
+
+func (q *LocalQueue) Add(ctx context.Context, path string, params url.Values, retryLimit int) error {
+	fn, ok := q.tasks[path]
+	if !ok {
+		return errors.New("No task handler for " + path)
+	}
+
+	r, err := http.NewRequest("POST", path, strings.NewReader(params.Encode()))
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+
+		taskCtx := WithServices(context.Background(), q.services)
+		w := httptest.NewRecorder()
+
+		fn(taskCtx, w, r.WithContext(taskCtx))
+
+		if w.Code >= 400 {
+			q.services.Log.Errorf(taskCtx, "Task %s failed with status %d: %s", path, w.Code, w.Body.String())
+		}
+	}()
+
+	return nil
+}
+
+// Wait blocks until every task added so far has finished
+func (q *LocalQueue) Wait() {
+	q.wg.Wait()
+}
+
+// DirFetcher answers requests from files in a directory instead of the
+// network. The file for a URL is at <dir>/<host>/<path>, ignoring the query.
+type DirFetcher struct {
+	dir string
+}
+
+func NewDirFetcher(dir string) *DirFetcher {
+	return &DirFetcher{dir}
+}
+
+func (f *DirFetcher) Client(ctx context.Context) *http.Client {
+	return &http.Client{Transport: f}
+}
+
+func (f *DirFetcher) RoundTrip(r *http.Request) (*http.Response, error) {
+	name := filepath.Join(f.dir, r.URL.Host, filepath.FromSlash(path.Clean("/"+r.URL.Path)))
+
+	b, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return fileResponse(r, http.StatusNotFound, []byte("No file "+name)), nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return fileResponse(r, http.StatusOK, b), nil
+}
+
+func fileResponse(r *http.Request, status int, b []byte) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(status) + " " + http.StatusText(status),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        make(http.Header),
+		Body:          ioutil.NopCloser(bytes.NewReader(b)),
+		ContentLength: int64(len(b)),
+		Request:       r,
+	}
+}
+
+type defaultFetch struct{}
+
+func (defaultFetch) Client(ctx context.Context) *http.Client {
+	return http.DefaultClient
+}
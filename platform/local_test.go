@@ -0,0 +1,149 @@
+package platform
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache()
+
+	value := []byte("value")
+	c.Set(ctx, "key", value, 0)
+	value[0] = 'X'
+
+	if got, err := c.Get(ctx, "key"); err != nil || string(got) != "value" {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+
+	c.Set(ctx, "expired", []byte("old"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, err := c.Get(ctx, "expired"); err != ErrCacheMiss {
+		t.Errorf("Get of an expired key err = %v", err)
+	}
+
+	values, _ := c.GetMulti(ctx, []string{"key", "expired", "missing"})
+	if len(values) != 1 || string(values["key"]) != "value" {
+		t.Errorf("GetMulti = %q", values)
+	}
+
+	if err := c.Delete(ctx, "key"); err != nil {
+		t.Errorf("Delete err = %v", err)
+	}
+	if err := c.Delete(ctx, "key"); err != ErrCacheMiss {
+		t.Errorf("second Delete err = %v", err)
+	}
+
+	if n, err := c.Increment(ctx, "count", 1, 0); n != 1 || err != nil {
+		t.Errorf("first Increment = %d, %v", n, err)
+	}
+	if n, err := c.Increment(ctx, "count", 2, 0); n != 3 || err != nil {
+		t.Errorf("second Increment = %d, %v", n, err)
+	}
+	if got, _ := c.Get(ctx, "count"); string(got) != "3" {
+		t.Errorf("Get after Increment = %q", got)
+	}
+}
+
+type testDocument struct {
+	Name  string
+	Count int
+}
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	a, _ := s.Insert(ctx, "doc", "", &testDocument{"a", 1})
+	b, _ := s.Insert(ctx, "doc", "", &testDocument{"b", 2})
+	s.Insert(ctx, "other", "", &testDocument{"a", 3})
+
+	child, err := s.Insert(ctx, "doc", a, &testDocument{"c", 1})
+	if err != nil || child != a+"/doc/4" {
+		t.Errorf("Insert under %s = %s, %v", a, child, err)
+	}
+	if _, err := s.Insert(ctx, "doc", "not a key", &testDocument{}); err != ErrInvalidKey {
+		t.Errorf("Insert under an invalid parent err = %v", err)
+	}
+
+	var d testDocument
+	if err := s.Get(ctx, b, &d); err != nil || d.Name != "b" {
+		t.Errorf("Get = %+v, %v", d, err)
+	}
+
+	s.Update(ctx, b, &testDocument{"b", 5})
+	if key, err := s.FindOne(ctx, "doc", "Count", "5", &d); key != b || err != nil || d.Name != "b" {
+		t.Errorf("FindOne = %s %+v, %v", key, d, err)
+	}
+	if key, err := s.FindOne(ctx, "doc", "Name", "missing", &d); key != "" || err != nil {
+		t.Errorf("FindOne of a missing document = %s, %v", key, err)
+	}
+
+	var all []testDocument
+	s.FindAll(ctx, "doc", "Count", "1", 0, &all)
+	if len(all) != 2 || all[0].Name != "a" || all[1].Name != "c" {
+		t.Errorf("FindAll = %+v", all)
+	}
+
+	all = nil
+	s.FindAll(ctx, "doc", "", "", 2, &all)
+	if len(all) != 2 {
+		t.Errorf("FindAll with a limit of 2 = %+v", all)
+	}
+
+	s.Delete(ctx, a)
+	if err := s.Get(ctx, a, &d); err != ErrNoSuchDocument {
+		t.Errorf("Get of a deleted document err = %v", err)
+	}
+	if err := s.Update(ctx, a, &d); err != ErrNoSuchDocument {
+		t.Errorf("Update of a deleted document err = %v", err)
+	}
+
+	named, err := s.NamedKey(ctx, "doc", b, "state")
+	if err != nil || named != b+"/doc/state" {
+		t.Errorf("NamedKey under %s = %s, %v", b, named, err)
+	}
+	if err := s.Get(ctx, named, &d); err != ErrNoSuchDocument {
+		t.Errorf("Get of a named document before Put err = %v", err)
+	}
+	s.Put(ctx, named, &testDocument{"d", 1})
+	s.Put(ctx, named, &testDocument{"d", 2})
+	all = nil
+	s.FindAll(ctx, "doc", "Name", "d", 0, &all)
+	if len(all) != 1 || all[0].Count != 2 {
+		t.Errorf("Put twice = %+v", all)
+	}
+}
+
+func TestLocalQueue(t *testing.T) {
+	done := make(chan string, 1)
+
+	services := Local(map[string]TaskFn{
+		"/task": func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			// Tasks run with the services of the queue
+			CacheSet(ctx, "ran", []byte(r.FormValue("id")), 0)
+			done <- r.FormValue("id")
+		},
+	})
+	ctx := WithServices(context.Background(), services)
+
+	if err := AddTask(ctx, "/task", url.Values{"id": {"42"}}, 0); err != nil {
+		t.Fatalf("AddTask err = %v", err)
+	}
+	services.Queue.(*LocalQueue).Wait()
+
+	if id := <-done; id != "42" {
+		t.Errorf("task got id %s", id)
+	}
+	if b, _ := CacheGet(ctx, "ran"); string(b) != "42" {
+		t.Errorf("task didn't use the services of the queue")
+	}
+
+	if err := AddTask(ctx, "/missing", nil, 0); err == nil {
+		t.Errorf("AddTask for a path without a handler succeeded")
+	}
+}
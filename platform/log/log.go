@@ -0,0 +1,29 @@
+// Package log writes to the logger of the platform services in a context. It
+// has the same functions as the App Engine log package it replaces.
+package log
+
+import (
+	"context"
+
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+)
+
+func Debugf(ctx context.Context, format string, args ...interface{}) {
+	platform.From(ctx).Log.Debugf(ctx, format, args...)
+}
+
+func Infof(ctx context.Context, format string, args ...interface{}) {
+	platform.From(ctx).Log.Infof(ctx, format, args...)
+}
+
+func Warningf(ctx context.Context, format string, args ...interface{}) {
+	platform.From(ctx).Log.Warningf(ctx, format, args...)
+}
+
+func Errorf(ctx context.Context, format string, args ...interface{}) {
+	platform.From(ctx).Log.Errorf(ctx, format, args...)
+}
+
+func Criticalf(ctx context.Context, format string, args ...interface{}) {
+	platform.From(ctx).Log.Criticalf(ctx, format, args...)
+}
@@ -0,0 +1,185 @@
+// Package platform puts the services the engine needs behind interfaces so
+// that trees can be executed on App Engine or in a plain Go process.
+package platform
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ErrCacheMiss is returned by a Cache when there is no value for a key
+var ErrCacheMiss = errors.New("platform: cache miss")
+
+type Logger interface {
+	Debugf(ctx context.Context, format string, args ...interface{})
+	Infof(ctx context.Context, format string, args ...interface{})
+	Warningf(ctx context.Context, format string, args ...interface{})
+	Errorf(ctx context.Context, format string, args ...interface{})
+	Criticalf(ctx context.Context, format string, args ...interface{})
+}
+
+// Cache is a key-value cache. Values may be dropped at any time.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	GetMulti(ctx context.Context, keys []string) (map[string][]byte, error)
+	Set(ctx context.Context, key string, value []byte, expiration time.Duration) error
+	Delete(ctx context.Context, key string) error
+	// Increment atomically adds delta to the decimal number stored under key,
+	// starting from initial when there is none, and returns the new value
+	Increment(ctx context.Context, key string, delta int64, initial uint64) (uint64, error)
+}
+
+// Store keeps documents of a kind under opaque string keys. v is a pointer
+// to a struct and dst is a pointer to a slice of structs.
+type Store interface {
+	// Insert adds a document under parent, or at the top level when parent is
+	// empty. It returns ErrInvalidKey when parent isn't a key of the store.
+	Insert(ctx context.Context, kind string, parent string, v interface{}) (string, error)
+	Update(ctx context.Context, key string, v interface{}) error
+	// NamedKey returns the key of the document of the kind with the name under
+	// parent, whether it exists or not. It returns ErrInvalidKey when parent
+	// isn't a key of the store.
+	NamedKey(ctx context.Context, kind string, parent string, name string) (string, error)
+	// Put stores a document under a key from NamedKey, adding it or replacing
+	// the one already there
+	Put(ctx context.Context, key string, v interface{}) error
+	// Get returns ErrNoSuchDocument when there is no document with the key
+	Get(ctx context.Context, key string, v interface{}) error
+	Delete(ctx context.Context, key string) error
+	// FindOne loads the first document of the kind whose field has the value
+	// and returns its key, or an empty key when there is none
+	FindOne(ctx context.Context, kind string, field string, value string, v interface{}) (string, error)
+	// FindAll loads up to limit documents of the kind whose field has the
+	// value. An empty field matches every document and a limit of 0 has no limit.
+	FindAll(ctx context.Context, kind string, field string, value string, limit int, dst interface{}) error
+	// RunInTransaction runs fn so that the gets, updates and deletes it makes
+	// with the context it is passed happen together or not at all
+	RunInTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Queue runs a POST to one of the app's handlers in the background
+type Queue interface {
+	Add(ctx context.Context, path string, params url.Values, retryLimit int) error
+}
+
+// Fetcher provides the client for outgoing HTTP requests
+type Fetcher interface {
+	Client(ctx context.Context) *http.Client
+}
+
+type Services struct {
+	Log   Logger
+	Cache Cache
+	Store Store
+	Queue Queue
+	Fetch Fetcher
+
+	// DevServer is set when running on the App Engine development server,
+	// where external databases can't be reached
+	DevServer bool
+}
+
+type servicesKey struct{}
+
+// WithServices makes everything run with ctx use s
+func WithServices(ctx context.Context, s *Services) context.Context {
+	return context.WithValue(ctx, servicesKey{}, s)
+}
+
+// From returns the services of ctx, which are the App Engine ones unless
+// WithServices was used
+func From(ctx context.Context) *Services {
+	if s, ok := ctx.Value(servicesKey{}).(*Services); ok {
+		return s
+	}
+
+	return appEngineServices
+}
+
+func CacheGet(ctx context.Context, key string) ([]byte, error) {
+	return From(ctx).Cache.Get(ctx, key)
+}
+
+func CacheGetMulti(ctx context.Context, keys []string) (map[string][]byte, error) {
+	return From(ctx).Cache.GetMulti(ctx, keys)
+}
+
+func CacheSet(ctx context.Context, key string, value []byte, expiration time.Duration) error {
+	return From(ctx).Cache.Set(ctx, key, value, expiration)
+}
+
+func CacheDelete(ctx context.Context, key string) error {
+	return From(ctx).Cache.Delete(ctx, key)
+}
+
+func CacheIncrement(ctx context.Context, key string, delta int64, initial uint64) (uint64, error) {
+	return From(ctx).Cache.Increment(ctx, key, delta, initial)
+}
+
+// CacheGetGob decodes a value stored with CacheSetGob into v
+func CacheGetGob(ctx context.Context, key string, v interface{}) error {
+	b, err := CacheGet(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	return gob.NewDecoder(bytes.NewReader(b)).Decode(v)
+}
+
+// CacheSetGob stores v gob encoded
+func CacheSetGob(ctx context.Context, key string, v interface{}, expiration time.Duration) error {
+	var buf bytes.Buffer
+
+	if err := gob.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+
+	return CacheSet(ctx, key, buf.Bytes(), expiration)
+}
+
+func AddTask(ctx context.Context, path string, params url.Values, retryLimit int) error {
+	return From(ctx).Queue.Add(ctx, path, params, retryLimit)
+}
+
+type throttleKey struct{}
+
+// WithThrottle makes the requests of the clients Client returns for ctx call
+// wait before they go out
+func WithThrottle(ctx context.Context, wait func(context.Context) error) context.Context {
+	return context.WithValue(ctx, throttleKey{}, wait)
+}
+
+func Client(ctx context.Context) *http.Client {
+	c := From(ctx).Fetch.Client(ctx)
+
+	if wait, ok := ctx.Value(throttleKey{}).(func(context.Context) error); ok {
+		throttled := *c
+		throttled.Transport = &throttledTransport{ctx, wait, c.Transport}
+		return &throttled
+	}
+
+	return c
+}
+
+type throttledTransport struct {
+	ctx  context.Context
+	wait func(context.Context) error
+	next http.RoundTripper
+}
+
+func (t *throttledTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	if err := t.wait(t.ctx); err != nil {
+		return nil, err
+	}
+
+	if t.next == nil {
+		return http.DefaultTransport.RoundTrip(r)
+	}
+
+	return t.next.RoundTrip(r)
+}
@@ -5,17 +5,19 @@ import (
 	"context"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"net/http"
 
 	"reflect"
 	"sort"
 	"strings"
 	"time"
 
-	"google.golang.org/appengine/log"
-	"google.golang.org/appengine/urlfetch"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+	"github.com/AlphaHat/gcp-alpha-hat/platform/log"
 
 	"github.com/AlphaHat/gcp-alpha-hat/cache"
 )
@@ -156,14 +158,25 @@ func assembleURLwithoutDates(identifier string) string {
 	return url
 }
 
+// ErrNotFound is returned when Quandl has no dataset for the requested code
+var ErrNotFound = errors.New("quandl: dataset not found")
+
 func readBytesFromUrl(ctx context.Context, url string) ([]byte, error) {
-	resp, err := urlfetch.Client(ctx).Get(url)
+	resp, err := platform.Client(ctx).Get(url)
 	if err != nil {
 		log.Errorf(ctx, "err=%s\n", err)
 		return nil, err
 	}
 
 	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("quandl returned %s", resp.Status)
+	}
+
 	body, err := ioutil.ReadAll(resp.Body)
 
 	return body, err
@@ -402,7 +415,7 @@ func Search(ctx context.Context, query string) ([]byte, error) {
 }
 
 func loadJson(ctx context.Context, url string) []byte {
-	resp, err := urlfetch.Client(ctx).Get(url)
+	resp, err := platform.Client(ctx).Get(url)
 	if err != nil {
 		log.Infof(ctx, "err=%s\n", err)
 		return nil
@@ -421,7 +434,7 @@ func loadJson(ctx context.Context, url string) []byte {
 }
 
 func loadPipeDelimited(ctx context.Context, url string) [][]string {
-	resp, err := urlfetch.Client(ctx).Get(url)
+	resp, err := platform.Client(ctx).Get(url)
 	if err != nil {
 		log.Infof(ctx, "err=%s\n", err)
 		return nil
@@ -438,7 +451,7 @@ func loadPipeDelimited(ctx context.Context, url string) [][]string {
 }
 
 func loadTabDelimited(ctx context.Context, url string) [][]string {
-	resp, err := urlfetch.Client(ctx).Get(url)
+	resp, err := platform.Client(ctx).Get(url)
 	if err != nil {
 		log.Infof(ctx, "err=%s\n", err)
 		return nil
@@ -496,7 +509,7 @@ func (r linefeedConverter) Read(b []byte) (int, error) {
 
 func loadCSVMac(ctx context.Context, url string) ([][]string, error) {
 	log.Infof(ctx, "Loading csv %s", url)
-	resp, err := urlfetch.Client(ctx).Get(url)
+	resp, err := platform.Client(ctx).Get(url)
 	if err != nil {
 		log.Infof(ctx, "err=%s\n", err)
 		return nil, err
@@ -518,7 +531,7 @@ func loadCSV(ctx context.Context, url string) ([][]string, error) {
 	log.Infof(ctx, "Loading csv %s", url)
 
 	//file, err := os.Open(fileName)
-	resp, err := urlfetch.Client(ctx).Get(url)
+	resp, err := platform.Client(ctx).Get(url)
 	if err != nil {
 		log.Infof(ctx, "err=%s\n", err)
 		return nil, err
@@ -0,0 +1,67 @@
+package run
+
+import (
+	"context"
+	"time"
+)
+
+type asOfKey struct{}
+
+// WithAsOf makes a run see the data as it was at the end of the given day.
+// Relative time ranges end on the business day before it and data dated
+// after it is dropped.
+func WithAsOf(ctx context.Context, asOf time.Time) context.Context {
+	asOf = asOf.UTC()
+
+	return context.WithValue(ctx, asOfKey{}, time.Date(asOf.Year(), asOf.Month(), asOf.Day(), 0, 0, 0, 0, time.UTC))
+}
+
+// AsOf is the date a run is evaluated on, which is now unless WithAsOf was used
+func AsOf(ctx context.Context) time.Time {
+	if asOf, ok := pointInTime(ctx); ok {
+		return asOf
+	}
+
+	return time.Now().UTC()
+}
+
+func pointInTime(ctx context.Context) (time.Time, bool) {
+	asOf, ok := ctx.Value(asOfKey{}).(time.Time)
+
+	return asOf, ok
+}
+
+func parseAsOf(s string) (time.Time, error) {
+	return time.Parse("2006-01-02", s)
+}
+
+// truncateAfterAsOf drops the points dated after the as-of date of a point in time run
+func truncateAfterAsOf(ctx context.Context, d []DataPoint) []DataPoint {
+	asOf, ok := pointInTime(ctx)
+	if !ok {
+		return d
+	}
+
+	truncated := make([]DataPoint, 0, len(d))
+	for _, v := range d {
+		if !v.Time.After(asOf) {
+			truncated = append(truncated, v)
+		}
+	}
+
+	return truncated
+}
+
+func (m MultiEntityData) truncateAfterAsOf(ctx context.Context) MultiEntityData {
+	if _, ok := pointInTime(ctx); !ok {
+		return m
+	}
+
+	for i, v := range m.EntityData {
+		for j, _ := range v.Data {
+			m.EntityData[i].Data[j].Data = truncateAfterAsOf(ctx, v.Data[j].Data)
+		}
+	}
+
+	return m
+}
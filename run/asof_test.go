@@ -0,0 +1,93 @@
+package run
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTruncateAfterAsOf(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC)
+	}
+	points := []DataPoint{{day(1), 1}, {day(2), 2}, {day(3), 3}}
+
+	if got := truncateAfterAsOf(context.Background(), points); !reflect.DeepEqual(got, points) {
+		t.Errorf("without an as-of date got %v", got)
+	}
+
+	// The as-of day itself is kept, whatever the time of day it was given at
+	ctx := WithAsOf(context.Background(), day(2).Add(15*time.Hour))
+	if got, want := truncateAfterAsOf(ctx, points), points[:2]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if len(points) != 3 {
+		t.Error("the points were changed")
+	}
+
+	m := MultiEntityData{EntityData: []SingleEntityData{
+		{Data: []Series{{Data: []DataPoint{{day(1), 1}, {day(3), 3}}}, {Data: []DataPoint{{day(4), 4}}}}},
+	}}
+	m = m.truncateAfterAsOf(ctx)
+	if got := len(m.EntityData[0].Data[0].Data) + len(m.EntityData[0].Data[1].Data); got != 1 {
+		t.Errorf("%d points are left, want 1", got)
+	}
+}
+
+func TestDateHelpersUseAsOf(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC)
+	}
+	asOf := day(20)
+
+	empty := SingleEntityData{Data: []Series{{}}}
+	if start, end := getStartEndDatesForEntity(empty, asOf); !start.Equal(asOf) || !end.IsZero() {
+		t.Errorf("an entity without data got %v to %v", start, end)
+	}
+	if got := earliestDate(empty, asOf); !got.Equal(asOf) {
+		t.Errorf("earliestDate of an entity without data = %v", got)
+	}
+
+	s := SingleEntityData{Data: []Series{
+		{Data: []DataPoint{{day(3), 1}, {day(5), 1}}},
+		{Data: []DataPoint{{day(2), 1}, {day(4), 1}}},
+	}}
+	if start, end := getStartEndDatesForEntity(s, asOf); !start.Equal(day(2)) || !end.Equal(day(5)) {
+		t.Errorf("got %v to %v, want %v to %v", start, end, day(2), day(5))
+	}
+	if got := earliestDate(s, asOf); !got.Equal(day(2)) {
+		t.Errorf("earliestDate = %v, want %v", got, day(2))
+	}
+
+	// The period that is still open ends on the as-of date
+	w := Series{IsWeight: true, Data: []DataPoint{{day(1), 1}, {day(2), 0}, {day(3), 0}, {day(4), 1}}}
+	starts, ends := getWeightStartEndDates(w, asOf)
+	if want := []time.Time{day(1), day(4)}; !reflect.DeepEqual(starts, want) {
+		t.Errorf("starts = %v, want %v", starts, want)
+	}
+	if want := []time.Time{day(1), asOf}; !reflect.DeepEqual(ends, want) {
+		t.Errorf("ends = %v, want %v", ends, want)
+	}
+}
+
+func TestComputeTSAsOf(t *testing.T) {
+	var got time.Time
+	step := ComputeTSAsOf(func(asOf time.Time) func(SingleEntityData) SingleEntityData {
+		got = asOf
+		return func(s SingleEntityData) SingleEntityData {
+			return s
+		}
+	})
+
+	asOf := time.Date(2020, 3, 31, 0, 0, 0, 0, time.UTC)
+	step(WithAsOf(context.Background(), asOf), []MultiEntityData{{}})
+	if !got.Equal(asOf) {
+		t.Errorf("got %v, want %v", got, asOf)
+	}
+
+	step(context.Background(), []MultiEntityData{{}})
+	if time.Since(got) > time.Minute {
+		t.Errorf("without an as-of date got %v, want now", got)
+	}
+}
@@ -13,10 +13,10 @@ import (
 	"cloud.google.com/go/civil"
 
 	"google.golang.org/api/iterator"
-	"google.golang.org/appengine"
-	"google.golang.org/appengine/log"
 
 	"github.com/AlphaHat/gcp-alpha-hat/cache"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+	"github.com/AlphaHat/gcp-alpha-hat/platform/log"
 )
 
 var bogusData = `
@@ -105,20 +105,20 @@ func bigQuery(ctx context.Context, proj string, queryString string) (*bigquery.R
 func GenericBigQuery(ctx context.Context, query string, metaMap map[string]SeriesMeta, stringJoiner string, isWeight bool) MultiEntityData {
 	iter, err := bigQuery(ctx, "altdatahub", query)
 
-	var m MultiEntityData
+	b := NewRowBuilder(metaMap, stringJoiner, isWeight)
 
 	if !logError(ctx, err) {
-		return m
+		return buildBulk(ctx, b)
 	}
 
 	for {
 		var row []bigquery.Value
 		err := iter.Next(&row)
 		if err == iterator.Done {
-			return m
+			return buildBulk(ctx, b)
 		}
 		if !logError(ctx, err) {
-			return m
+			return buildBulk(ctx, b)
 		}
 
 		date := row[0].(civil.Date)
@@ -127,40 +127,8 @@ func GenericBigQuery(ctx context.Context, query string, metaMap map[string]Serie
 		subfield := row[3].(string)
 		data := row[4].(float64)
 
-		if err != nil {
-			log.Errorf(ctx, "query error = %s", err)
-		} else {
-			// t, _ := time.Parse("2006-01-02", date)
-			t := time.Date(date.Year, date.Month, date.Day, 0, 0, 0, 0, time.UTC)
-
-			seriesMeta := metaMap[field]
-
-			if subfield != "" {
-				seriesMeta.VendorCode = seriesMeta.VendorCode + stringJoiner + subfield
-				seriesMeta.Label = seriesMeta.VendorCode
-			}
-
-			m = m.Insert(
-				EntityMeta{
-					Name:     entity,
-					UniqueId: entity,
-					IsCustom: true,
-				},
-				seriesMeta,
-				DataPoint{
-					Time: t,
-					Data: data,
-				},
-				CategoryLabel{
-					Id:    0,
-					Label: "",
-				},
-				isWeight)
-		}
-
+		b.Add(time.Date(date.Year, date.Month, date.Day, 0, 0, 0, 0, time.UTC), entity, field, subfield, data)
 	}
-
-	// return m
 }
 
 func GenericSQLQuery(ctx context.Context, query string, metaMap map[string]SeriesMeta, stringJoiner string, isWeight bool) MultiEntityData {
@@ -172,16 +140,15 @@ func GenericSQLQuery(ctx context.Context, query string, metaMap map[string]Serie
 		log.Errorf(ctx, "Unable to connect to database")
 	}
 
-	var m MultiEntityData
+	b := NewRowBuilder(metaMap, stringJoiner, isWeight)
 
 	rows, err := sqlDb.Query(query)
 
 	if err != nil {
 		log.Errorf(ctx, "query error = %s", err)
-		return m
+		return buildBulk(ctx, b)
 	}
 
-	m.Title = ""
 	var date, entity, field, subfield string
 	var data float64
 
@@ -194,32 +161,24 @@ func GenericSQLQuery(ctx context.Context, query string, metaMap map[string]Serie
 		} else {
 			t, _ := time.Parse("2006-01-02", date)
 
-			seriesMeta := metaMap[field]
-
-			if subfield != "" {
-				seriesMeta.VendorCode = seriesMeta.VendorCode + stringJoiner + subfield
-				seriesMeta.Label = seriesMeta.VendorCode
-			}
-
-			m = m.Insert(
-				EntityMeta{
-					Name:     entity,
-					UniqueId: entity,
-					IsCustom: true,
-				},
-				seriesMeta,
-				DataPoint{
-					Time: t,
-					Data: data,
-				},
-				CategoryLabel{
-					Id:    0,
-					Label: "",
-				},
-				isWeight)
+			b.Add(t, entity, field, subfield, data)
 		}
 	}
 
+	return buildBulk(ctx, b)
+}
+
+// buildBulk builds the result of a bulk query, warning about the duplicate rows
+func buildBulk(ctx context.Context, b *MultiEntityBuilder) MultiEntityData {
+	m := b.Build()
+
+	if b.NumDuplicates > 0 {
+		d := b.Duplicates[0]
+		message := fmt.Sprintf("Dropped %d duplicate rows, the first for %s %s %s on %s", b.NumDuplicates, d.Entity, d.Field, d.Subfield, d.Date.Format("2006-01-02"))
+		log.Warningf(ctx, "%s", message)
+		warnRun(ctx, message)
+	}
+
 	return m
 }
 
@@ -574,7 +533,7 @@ ORDER BY dma_total desc`
 
 	log.Infof(ctx, "running query = %s", query)
 
-	if appengine.IsDevAppServer() {
+	if platform.From(ctx).DevServer {
 		time.Sleep(time.Second * 1)
 		var m MultiEntityData
 		log.Infof(ctx, "CANNOT DO A SQL CONNECT ON THE DEV SERVER")
@@ -0,0 +1,113 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+	"github.com/AlphaHat/gcp-alpha-hat/platform/log"
+)
+
+// RunTimeout is the longest a whole run can take
+var RunTimeout = time.Minute * 9
+
+// StepTimeout is the longest a single step can take
+var StepTimeout = time.Minute * 5
+
+// cancelPollInterval is how often a running worker checks for a cancel request
+var cancelPollInterval = time.Second * 2
+
+func cancelKey(id string) string {
+	return "cancel:" + id
+}
+
+func requestCancel(ctx context.Context, id string) error {
+	return platform.CacheSet(ctx, cancelKey(id), []byte(time.Now().Format(time.RFC3339)), RunTimeout*2)
+}
+
+// clearCancel forgets a cancel request so that the run can be queued again
+func clearCancel(ctx context.Context, id string) {
+	if err := platform.CacheDelete(ctx, cancelKey(id)); err != nil && err != platform.ErrCacheMiss {
+		log.Warningf(ctx, "Unable to clear the cancel request of %s: %s", id, err)
+	}
+}
+
+// cancelRequested is whether the run has been cancelled. The cache is only a
+// fast path, since the request can be evicted before the worker sees it.
+func cancelRequested(ctx context.Context, id string) bool {
+	if _, err := platform.CacheGet(ctx, cancelKey(id)); err == nil {
+		return true
+	}
+
+	rec, _, found := getRunRecord(ctx, id)
+
+	return found && rec.State == RunCancelled
+}
+
+// watchForCancel stops the run once somebody asks for it to be cancelled. The
+// lookups use the request context since runCtx is the one cancelled.
+func watchForCancel(ctx context.Context, runCtx context.Context, id string, cancel context.CancelFunc) {
+	ticker := time.NewTicker(cancelPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-runCtx.Done():
+			return
+		case <-ticker.C:
+			if cancelRequested(ctx, id) {
+				log.Infof(ctx, "Cancelling run %s", id)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// stopReason explains why a context is done in terms the user understands
+func stopReason(ctx context.Context) string {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return "Timed out"
+	case context.Canceled:
+		return "Cancelled"
+	}
+
+	return ""
+}
+
+// stoppedData is returned by steps that gave up part way through
+func stoppedData(ctx context.Context) MultiEntityData {
+	return MultiEntityData{Error: stopReason(ctx)}
+}
+
+// stepTimeoutError is used when a step runs past StepTimeout but the run itself is still live
+func stepTimeoutError(e ExecutionNode) string {
+	return fmt.Sprintf("%s timed out after %v", e.Describe(), StepTimeout)
+}
+
+func CancelHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get(":query")
+
+	rec, _, found := getRunRecord(ctx, id)
+
+	if !found {
+		http.Error(w, "No run found for "+id, http.StatusNotFound)
+		return
+	}
+
+	if rec.State == RunQueued || rec.State == RunRunning {
+		if err := requestCancel(ctx, id); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		setRunState(ctx, id, RunCancelled, "Cancelled by user", "")
+	}
+
+	status, _ := runStatus(ctx, id)
+
+	returnJson(ctx, w, status)
+}
@@ -0,0 +1,66 @@
+package run
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AlphaHat/gcp-alpha-hat/db"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+)
+
+func TestReRunAfterCancel(t *testing.T) {
+	services := platform.Local(map[string]platform.TaskFn{"/apiv1/worker": WorkerHandler})
+	ctx := platform.WithServices(context.Background(), services)
+
+	id := db.DatabaseInsert(ctx, db.RunTree, &TreeDummy{Tree: []byte(`{}`)}, "")
+
+	// The user cancels the first run and then runs the tree again
+	setRunState(ctx, id, RunRunning, "", "")
+	if err := requestCancel(ctx, id); err != nil {
+		t.Fatalf("requestCancel err = %s", err)
+	}
+	setRunState(ctx, id, RunCancelled, "Cancelled by user", "")
+	appendRunEvent(ctx, id, RunEvent{Type: EventWarning, Message: "From the first run"})
+
+	ReRunHandler(ctx, httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil), id)
+	services.Queue.(*platform.LocalQueue).Wait()
+
+	if cancelRequested(ctx, id) {
+		t.Errorf("the cancel request outlived the re-run")
+	}
+	for _, v := range getRunEvents(ctx, id, 0) {
+		if v.Message == "From the first run" {
+			t.Errorf("the events of the first run outlived the re-run")
+		}
+	}
+
+	rec, _, found := getRunRecord(ctx, id)
+	if !found {
+		t.Fatalf("no run record for %s", id)
+	}
+	if rec.State == RunRunning || rec.State == RunQueued || rec.State == RunCancelled {
+		t.Errorf("re-run ended in state %s", rec.State)
+	}
+}
+
+func TestCancelSurvivesCacheEviction(t *testing.T) {
+	ctx := platform.WithServices(context.Background(), platform.Local(nil))
+
+	id := db.DatabaseInsert(ctx, db.RunTree, &TreeDummy{Tree: []byte(`{}`)}, "")
+	createRunRecord(ctx, id)
+	setRunState(ctx, id, RunRunning, "", "")
+
+	if cancelRequested(ctx, id) {
+		t.Errorf("a running run is cancelled")
+	}
+
+	requestCancel(ctx, id)
+	setRunState(ctx, id, RunCancelled, "Cancelled by user", "")
+	clearCancel(ctx, id)
+
+	if !cancelRequested(ctx, id) {
+		t.Errorf("the cancel was lost with the cache")
+	}
+}
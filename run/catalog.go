@@ -0,0 +1,69 @@
+package run
+
+import (
+	"context"
+	"net/http"
+)
+
+// StepInfo is the public description of a registered computation step
+type StepInfo struct {
+	Type          string           `json:"type"`
+	Name          string           `json:"name"`
+	DefaultString string           `json:"default_string"`
+	Description   string           `json:"description"`
+	Examples      []string         `json:"examples"`
+	Placeholders  []string         `json:"placeholders"`
+	Arguments     []ArgumentSchema `json:"arguments"`
+	MinChildren   int              `json:"min_children"`
+	MaxChildren   int              `json:"max_children"`
+}
+
+// Catalog lists every registered step in the order it was registered
+func (r *StepRegistry) Catalog() []StepInfo {
+	steps := r.All()
+	catalog := make([]StepInfo, len(steps))
+
+	for i, v := range steps {
+		catalog[i] = StepInfo{
+			Type:          string(v.Type),
+			Name:          v.Name,
+			DefaultString: v.DefaultString,
+			Description:   v.Description,
+			Examples:      v.Examples,
+			Placeholders:  v.Placeholders(),
+			Arguments:     v.Arguments,
+			MinChildren:   v.MinChildren,
+			MaxChildren:   v.MaxChildren,
+		}
+
+		if catalog[i].Examples == nil {
+			catalog[i].Examples = make([]string, 0)
+		}
+		if catalog[i].Placeholders == nil {
+			catalog[i].Placeholders = make([]string, 0)
+		}
+	}
+
+	return catalog
+}
+
+// StepsHandler returns the step catalog, optionally limited to one major type
+func StepsHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	majorType := r.FormValue("type")
+	catalog := Steps.Catalog()
+
+	if majorType != "" {
+		filtered := make([]StepInfo, 0)
+
+		for _, v := range catalog {
+			if v.Type == majorType {
+				filtered = append(filtered, v)
+			}
+		}
+
+		catalog = filtered
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	returnJson(ctx, w, catalog)
+}
@@ -0,0 +1,395 @@
+package run
+
+import (
+	"sort"
+	"time"
+)
+
+// columnarData holds the same data as MultiEntityData with the times and
+// values of each series in columns of their own, and hash indexes from entity
+// UniqueId and series label to their position so that appending a point
+// doesn't scan every entity and series
+type columnarData struct {
+	Title               string
+	Error               string
+	GraphicalPreference string
+
+	entities    []columnarEntity
+	entityIndex map[string]int
+}
+
+type columnarEntity struct {
+	Meta     EntityMeta
+	Category CategorySeries
+
+	series      []columnarSeries
+	seriesIndex map[string]int
+	// weight is the position of the first weight series, or -1
+	weight int
+}
+
+type columnarSeries struct {
+	Meta     SeriesMeta
+	IsWeight bool
+	// Times and Values are nil when the series had no data slice at all
+	Times  []time.Time
+	Values []float64
+}
+
+func newColumnarData() *columnarData {
+	return &columnarData{
+		entities:    make([]columnarEntity, 0),
+		entityIndex: make(map[string]int),
+	}
+}
+
+func newColumnarEntity(meta EntityMeta, category CategorySeries) columnarEntity {
+	return columnarEntity{
+		Meta:        meta,
+		Category:    category,
+		series:      make([]columnarSeries, 0),
+		seriesIndex: make(map[string]int),
+		weight:      -1,
+	}
+}
+
+// toColumnar converts m keeping everything needed to convert it back,
+// including entities or series that share a UniqueId or label. Lookups find
+// the first of those, as indexOfEntity and Insert do.
+func toColumnar(m MultiEntityData) *columnarData {
+	c := newColumnarData()
+	c.Title = m.Title
+	c.Error = m.Error
+	c.GraphicalPreference = m.GraphicalPreference
+
+	if m.EntityData == nil {
+		c.entities = nil
+	}
+
+	for _, v := range m.EntityData {
+		e := newColumnarEntity(v.Meta, v.Category)
+
+		if v.Data == nil {
+			e.series = nil
+		}
+
+		for _, v2 := range v.Data {
+			s := columnarSeries{Meta: v2.Meta, IsWeight: v2.IsWeight}
+
+			if v2.Data != nil {
+				s.Times = make([]time.Time, len(v2.Data))
+				s.Values = make([]float64, len(v2.Data))
+
+				for i, p := range v2.Data {
+					s.Times[i] = p.Time
+					s.Values[i] = p.Data
+				}
+			}
+
+			e.addSeries(s)
+		}
+
+		c.addEntity(e)
+	}
+
+	return c
+}
+
+func (c *columnarData) addEntity(e columnarEntity) int {
+	if c.entities == nil {
+		c.entities = make([]columnarEntity, 0)
+	}
+
+	c.entities = append(c.entities, e)
+
+	if _, found := c.entityIndex[e.Meta.UniqueId]; !found {
+		c.entityIndex[e.Meta.UniqueId] = len(c.entities) - 1
+	}
+
+	return len(c.entities) - 1
+}
+
+func (e *columnarEntity) addSeries(s columnarSeries) int {
+	if e.series == nil {
+		e.series = make([]columnarSeries, 0)
+	}
+
+	e.series = append(e.series, s)
+	i := len(e.series) - 1
+
+	if _, found := e.seriesIndex[s.Meta.Label]; !found {
+		e.seriesIndex[s.Meta.Label] = i
+	}
+
+	if s.IsWeight && e.weight < 0 {
+		e.weight = i
+	}
+
+	return i
+}
+
+// findEntity returns the position of the first entity with the UniqueId, or -1
+func (c *columnarData) findEntity(uniqueId string) int {
+	if i, found := c.entityIndex[uniqueId]; found {
+		return i
+	}
+
+	return -1
+}
+
+// findSeries returns the position of the first series with the label, or -1
+func (e *columnarEntity) findSeries(label string) int {
+	if i, found := e.seriesIndex[label]; found {
+		return i
+	}
+
+	return -1
+}
+
+// Insert appends a point the same way as MultiEntityData.Insert
+func (c *columnarData) Insert(eMeta EntityMeta, sMeta SeriesMeta, d DataPoint, label CategoryLabel, isWeight bool) {
+	if isWeight {
+		sMeta.Label = "Weights"
+		sMeta.Units = "Weight"
+	}
+
+	i := c.findEntity(eMeta.UniqueId)
+
+	if i < 0 {
+		e := newColumnarEntity(eMeta, CategorySeries{})
+		e.Category = e.Category.Insert(d.Time, label)
+		e.addSeries(columnarSeries{Meta: sMeta, IsWeight: isWeight, Times: []time.Time{d.Time}, Values: []float64{d.Data}})
+		c.addEntity(e)
+
+		return
+	}
+
+	e := &c.entities[i]
+
+	if !isWeight {
+		e.Category = e.Category.Insert(d.Time, label)
+	}
+
+	j := e.findSeries(sMeta.Label)
+	if isWeight && e.weight >= 0 && (j < 0 || e.weight < j) {
+		j = e.weight
+	}
+
+	if j < 0 {
+		e.addSeries(columnarSeries{Meta: sMeta, IsWeight: isWeight, Times: []time.Time{d.Time}, Values: []float64{d.Data}})
+		return
+	}
+
+	e.series[j].Times = append(e.series[j].Times, d.Time)
+	e.series[j].Values = append(e.series[j].Values, d.Data)
+}
+
+// MultiEntityData converts back to the representation the steps use
+func (c *columnarData) MultiEntityData() MultiEntityData {
+	m := MultiEntityData{
+		Title:               c.Title,
+		Error:               c.Error,
+		GraphicalPreference: c.GraphicalPreference,
+	}
+
+	if c.entities != nil {
+		m.EntityData = make([]SingleEntityData, len(c.entities))
+	}
+
+	for i, v := range c.entities {
+		m.EntityData[i] = SingleEntityData{Meta: v.Meta, Category: v.Category}
+
+		if v.series != nil {
+			m.EntityData[i].Data = make([]Series, len(v.series))
+		}
+
+		for j, v2 := range v.series {
+			s := Series{Meta: v2.Meta, IsWeight: v2.IsWeight}
+
+			if v2.Times != nil {
+				s.Data = make([]DataPoint, len(v2.Times))
+				for k, t := range v2.Times {
+					s.Data[k] = DataPoint{Time: t, Data: v2.Values[k]}
+				}
+			}
+
+			m.EntityData[i].Data[j] = s
+		}
+	}
+
+	return m
+}
+
+// maxReportedDuplicates bounds the duplicate rows a MultiEntityBuilder keeps
+const maxReportedDuplicates = 100
+
+// rowKey identifies the series that a row added with Add goes to
+type rowKey struct {
+	entity   string
+	field    string
+	subfield string
+}
+
+type seriesPos struct {
+	entity int
+	series int
+}
+
+// DuplicateRow is a row for an entity, field and date that was already seen.
+// The value of the first row is kept.
+type DuplicateRow struct {
+	Entity   string
+	Field    string
+	Subfield string
+	Date     time.Time
+	Kept     float64
+	Dropped  float64
+}
+
+// MultiEntityBuilder collects points one at a time for loads that are too
+// large to go through MultiEntityData.Insert
+type MultiEntityBuilder struct {
+	c *columnarData
+
+	metaMap      map[string]SeriesMeta
+	stringJoiner string
+	isWeight     bool
+
+	rows     map[rowKey]seriesPos
+	rowOrder []rowKey
+
+	// Duplicates lists the first duplicate rows found by Build and
+	// NumDuplicates counts all of them
+	Duplicates    []DuplicateRow
+	NumDuplicates int
+}
+
+func NewMultiEntityBuilder() *MultiEntityBuilder {
+	return NewRowBuilder(nil, "", false)
+}
+
+// NewRowBuilder returns a builder for the (date, entity, field, subfield,
+// value) rows of a bulk query. Fields are labelled with metaMap, joining
+// subfields to the vendor code with stringJoiner, and weights go to a single
+// series per entity.
+func NewRowBuilder(metaMap map[string]SeriesMeta, stringJoiner string, isWeight bool) *MultiEntityBuilder {
+	return &MultiEntityBuilder{
+		c:            newColumnarData(),
+		metaMap:      metaMap,
+		stringJoiner: stringJoiner,
+		isWeight:     isWeight,
+		rows:         make(map[rowKey]seriesPos),
+		rowOrder:     make([]rowKey, 0),
+		Duplicates:   make([]DuplicateRow, 0),
+	}
+}
+
+func (b *MultiEntityBuilder) Insert(eMeta EntityMeta, sMeta SeriesMeta, d DataPoint, c CategoryLabel, isWeight bool) {
+	b.c.Insert(eMeta, sMeta, d, c, isWeight)
+}
+
+func (b *MultiEntityBuilder) rowMeta(field string, subfield string) SeriesMeta {
+	meta := b.metaMap[field]
+
+	if subfield != "" {
+		meta.VendorCode = meta.VendorCode + b.stringJoiner + subfield
+		meta.Label = meta.VendorCode
+	}
+
+	if b.isWeight {
+		meta.Label = "Weights"
+		meta.Units = "Weight"
+	}
+
+	return meta
+}
+
+// Add appends a row. Rows may come in any order; Build sorts each series.
+func (b *MultiEntityBuilder) Add(date time.Time, entity string, field string, subfield string, data float64) {
+	key := rowKey{entity, field, subfield}
+	if b.isWeight {
+		key = rowKey{entity: entity}
+	}
+
+	pos, found := b.rows[key]
+	if !found {
+		pos.entity = b.c.findEntity(entity)
+		if pos.entity < 0 {
+			pos.entity = b.c.addEntity(newColumnarEntity(EntityMeta{Name: entity, UniqueId: entity, IsCustom: true}, CategorySeries{}))
+		}
+
+		pos.series = b.c.entities[pos.entity].addSeries(columnarSeries{
+			Meta:     b.rowMeta(field, subfield),
+			IsWeight: b.isWeight,
+			Times:    make([]time.Time, 0),
+			Values:   make([]float64, 0),
+		})
+
+		b.rows[key] = pos
+		b.rowOrder = append(b.rowOrder, key)
+	}
+
+	s := &b.c.entities[pos.entity].series[pos.series]
+	s.Times = append(s.Times, date)
+	s.Values = append(s.Values, data)
+}
+
+// sortRows sorts a series by date, keeping the first of the rows that share
+// a date and reporting the others
+func (b *MultiEntityBuilder) sortRows(key rowKey, s *columnarSeries) {
+	order := make([]int, len(s.Times))
+	for i := range order {
+		order[i] = i
+	}
+
+	sort.SliceStable(order, func(i, j int) bool {
+		return s.Times[order[i]].Before(s.Times[order[j]])
+	})
+
+	times := make([]time.Time, 0, len(order))
+	values := make([]float64, 0, len(order))
+
+	for _, v := range order {
+		if n := len(times); n > 0 && times[n-1].Equal(s.Times[v]) {
+			b.NumDuplicates++
+			if len(b.Duplicates) < maxReportedDuplicates {
+				b.Duplicates = append(b.Duplicates, DuplicateRow{key.entity, key.field, key.subfield, s.Times[v], values[n-1], s.Values[v]})
+			}
+			continue
+		}
+
+		times = append(times, s.Times[v])
+		values = append(values, s.Values[v])
+	}
+
+	s.Times, s.Values = times, values
+}
+
+// Build returns what was inserted so far. Nothing inserted gives the zero
+// MultiEntityData, as a loop of MultiEntityData.Insert would. The series of
+// rows are sorted, and their entities get a category from the first row, so
+// a builder that rows were added to is meant to be built once.
+func (b *MultiEntityBuilder) Build() MultiEntityData {
+	if len(b.c.entities) == 0 {
+		return MultiEntityData{}
+	}
+
+	first := make(map[int]time.Time)
+
+	for _, key := range b.rowOrder {
+		pos := b.rows[key]
+		s := &b.c.entities[pos.entity].series[pos.series]
+		b.sortRows(key, s)
+
+		if t, found := first[pos.entity]; len(s.Times) > 0 && (!found || s.Times[0].Before(t)) {
+			first[pos.entity] = s.Times[0]
+		}
+	}
+
+	for i, t := range first {
+		e := &b.c.entities[i]
+		e.Category = e.Category.Insert(t, CategoryLabel{})
+	}
+
+	return b.c.MultiEntityData()
+}
@@ -0,0 +1,147 @@
+package run
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+)
+
+func TestColumnarRoundTrip(t *testing.T) {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	m := MultiEntityData{
+		Title: "Round trip",
+		EntityData: []SingleEntityData{
+			{
+				Meta: EntityMeta{Name: "Apple", UniqueId: "AAPL"},
+				Data: []Series{
+					{Meta: SeriesMeta{Label: "Price"}, Data: []DataPoint{{day, 1}, {day.AddDate(0, 0, 1), 2}}},
+					{Meta: SeriesMeta{Label: "Price"}, Data: []DataPoint{}},
+					{Meta: SeriesMeta{Label: "Weights"}, IsWeight: true},
+				},
+				Category: CategorySeries{Data: []CategoryPoint{{day, 1}}, Labels: []CategoryLabel{{1, "Tech"}}},
+			},
+			{Meta: EntityMeta{Name: "Apple again", UniqueId: "AAPL"}},
+		},
+	}
+
+	if got := toColumnar(m).MultiEntityData(); !reflect.DeepEqual(got, m) {
+		t.Errorf("round trip changed the data:\n got %+v\nwant %+v", got, m)
+	}
+
+	if got := toColumnar(MultiEntityData{}).MultiEntityData(); !reflect.DeepEqual(got, MultiEntityData{}) {
+		t.Errorf("round trip of the zero value = %+v", got)
+	}
+}
+
+func TestBuilderMatchesInsert(t *testing.T) {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	var m MultiEntityData
+	b := NewMultiEntityBuilder()
+
+	for i := 0; i < 20; i++ {
+		e := EntityMeta{Name: string(rune('A' + i%3)), UniqueId: string(rune('A' + i%3))}
+		s := SeriesMeta{Label: []string{"Price", "Volume"}[i%2]}
+		d := DataPoint{day.AddDate(0, 0, i), float64(i)}
+		c := CategoryLabel{Label: []string{"", "Tech"}[i%4/3]}
+		isWeight := i%5 == 4
+
+		m = m.Insert(e, s, d, c, isWeight)
+		b.Insert(e, s, d, c, isWeight)
+	}
+
+	if got := b.Build(); !reflect.DeepEqual(got, m) {
+		t.Errorf("builder differs from Insert:\n got %+v\nwant %+v", got, m)
+	}
+
+	if got := NewMultiEntityBuilder().Build(); !reflect.DeepEqual(got, MultiEntityData{}) {
+		t.Errorf("empty builder = %+v", got)
+	}
+}
+
+func TestRowBuilderSortsAndReportsDuplicates(t *testing.T) {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	b := NewRowBuilder(map[string]SeriesMeta{"visits": {VendorCode: "visits", Label: "Visits"}}, " - ", false)
+
+	b.Add(day.AddDate(0, 0, 2), "Store A", "visits", "", 3)
+	b.Add(day, "Store A", "visits", "", 1)
+	b.Add(day.AddDate(0, 0, 1), "Store A", "visits", "weekend", 20)
+	b.Add(day.AddDate(0, 0, 1), "Store A", "visits", "", 2)
+	b.Add(day, "Store B", "visits", "", 5)
+	b.Add(day, "Store A", "visits", "", 9)
+
+	m := b.Build()
+
+	if len(m.EntityData) != 2 || m.EntityData[0].Meta.UniqueId != "Store A" {
+		t.Fatalf("entities = %+v", m.EntityData)
+	}
+
+	a := m.EntityData[0]
+	if len(a.Data) != 2 || a.Data[0].Meta.Label != "Visits" || a.Data[1].Meta.Label != "visits - weekend" {
+		t.Fatalf("series of Store A = %+v", a.Data)
+	}
+
+	want := []float64{1, 2, 3}
+	for i, v := range a.Data[0].Data {
+		if i > 0 && !v.Time.After(a.Data[0].Data[i-1].Time) {
+			t.Errorf("point %d is out of order", i)
+		}
+		if v.Data != want[i] {
+			t.Errorf("point %d = %v, want %v", i, v.Data, want[i])
+		}
+	}
+
+	if len(a.Category.Data) != 1 || !a.Category.Data[0].Time.Equal(day) {
+		t.Errorf("category of Store A = %+v", a.Category)
+	}
+
+	if b.NumDuplicates != 1 || b.Duplicates[0].Kept != 1 || b.Duplicates[0].Dropped != 9 {
+		t.Errorf("duplicates = %d %+v", b.NumDuplicates, b.Duplicates)
+	}
+}
+
+func TestRowBuilderKeysByField(t *testing.T) {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+
+	// Two fields with the same label are still separate series
+	b := NewRowBuilder(map[string]SeriesMeta{"in": {Label: "Visits"}, "out": {Label: "Visits"}}, "", false)
+	b.Add(day, "Store A", "in", "", 1)
+	b.Add(day, "Store A", "out", "", 2)
+
+	if m := b.Build(); len(m.EntityData) != 1 || len(m.EntityData[0].Data) != 2 || b.NumDuplicates != 0 {
+		t.Errorf("fields = %+v, %d duplicates", m.EntityData, b.NumDuplicates)
+	}
+
+	// Weights go to a single series per entity
+	b = NewRowBuilder(nil, "", true)
+	b.Add(day, "Store A", "share", "", 0.5)
+	b.Add(day.AddDate(0, 0, 1), "Store A", "count", "", 0.25)
+
+	m := b.Build()
+
+	if len(m.EntityData) != 1 || len(m.EntityData[0].Data) != 1 || !m.EntityData[0].Data[0].IsWeight || len(m.EntityData[0].Data[0].Data) != 2 {
+		t.Errorf("weights = %+v", m.EntityData)
+	}
+}
+
+func TestBuildBulkWarnsAboutDuplicates(t *testing.T) {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	ctx := platform.WithServices(context.Background(), platform.Local(nil))
+	ctx, _ = withRunProgress(ctx, "bulk", ExecutionNode{})
+
+	b := NewRowBuilder(nil, "", false)
+	b.Add(day, "Store A", "visits", "", 1)
+	b.Add(day, "Store A", "visits", "", 2)
+	buildBulk(ctx, b)
+
+	events := getRunEvents(ctx, "bulk", 0)
+	if len(events) != 1 || events[0].Type != EventWarning || !strings.Contains(events[0].Message, "Dropped 1 duplicate rows") {
+		t.Errorf("events = %+v", events)
+	}
+}
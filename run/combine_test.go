@@ -0,0 +1,168 @@
+package run
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/component"
+)
+
+// combineInput has an entity with a price series for each id
+func combineInput(title string, ids ...string) MultiEntityData {
+	day := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	m := MultiEntityData{Title: title, EntityData: make([]SingleEntityData, 0)}
+
+	for _, v := range ids {
+		m.EntityData = append(m.EntityData, SingleEntityData{
+			Meta: EntityMeta{Name: v, UniqueId: v},
+			Data: []Series{{Meta: SeriesMeta{Label: title + " " + v}, Data: []DataPoint{{day, 1}}}},
+		})
+	}
+
+	return m
+}
+
+func entityIds(m MultiEntityData) []string {
+	ids := make([]string, 0)
+	for _, v := range m.EntityData {
+		ids = append(ids, v.Meta.UniqueId)
+	}
+
+	return ids
+}
+
+func TestCombineErrors(t *testing.T) {
+	tests := []struct {
+		a, b, want string
+	}{
+		{"", "", ""},
+		{"a failed", "", "a failed"},
+		{"", "b failed", "b failed"},
+		{"a failed", "b failed", "a failed, b failed"},
+	}
+
+	for _, v := range tests {
+		if got := combineErrors(v.a, v.b); got != v.want {
+			t.Errorf("combineErrors(%q, %q) = %q, want %q", v.a, v.b, got, v.want)
+		}
+	}
+}
+
+func TestJoinTitles(t *testing.T) {
+	tests := []struct {
+		titles []string
+		want   string
+	}{
+		{nil, ""},
+		{[]string{"A"}, "A"},
+		{[]string{"A", "B"}, "A and B"},
+		{[]string{"A", "B", "C"}, "A, B and C"},
+		{[]string{"A", "B", "C", "D", "E"}, "A, B, C, D and E"},
+	}
+
+	for _, v := range tests {
+		mArr := make([]MultiEntityData, len(v.titles))
+		for i, title := range v.titles {
+			mArr[i].Title = title
+		}
+
+		if got := joinTitles(mArr); got != v.want {
+			t.Errorf("joinTitles(%q) = %q, want %q", v.titles, got, v.want)
+		}
+	}
+}
+
+func TestCombineLeft(t *testing.T) {
+	concat := func(ctx context.Context, a MultiEntityData, b MultiEntityData) MultiEntityData {
+		return MultiEntityData{Title: "(" + a.Title + " " + b.Title + ")"}
+	}
+
+	tests := []struct {
+		titles []string
+		want   string
+	}{
+		{[]string{"a"}, "a"},
+		{[]string{"a", "b"}, "(a b)"},
+		{[]string{"a", "b", "c"}, "((a b) c)"},
+		{[]string{"a", "b", "c", "d", "e"}, "((((a b) c) d) e)"},
+	}
+
+	for _, v := range tests {
+		mArr := make([]MultiEntityData, len(v.titles))
+		for i, title := range v.titles {
+			mArr[i].Title = title
+		}
+
+		if got := combineLeft(context.Background(), mArr, concat).Title; got != v.want {
+			t.Errorf("combineLeft(%q) = %q, want %q", v.titles, got, v.want)
+		}
+	}
+}
+
+func TestCombineSteps(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name   string
+		step   StepFnType
+		inputs []MultiEntityData
+		ids    []string
+		title  string
+	}{
+		{"union of 2", UnionData, []MultiEntityData{combineInput("A", "x", "y"), combineInput("B", "y", "z")}, []string{"x", "y", "z"}, "A, B"},
+		{"union of 3", UnionData, []MultiEntityData{combineInput("A", "x"), combineInput("B", "y"), combineInput("C", "x", "z")}, []string{"x", "y", "z"}, "A, B, C"},
+		{"intersection of 2", IntersectData, []MultiEntityData{combineInput("A", "x", "y"), combineInput("B", "y", "z")}, []string{"y"}, "Intersection of A and B"},
+		{"intersection of 3", IntersectData, []MultiEntityData{combineInput("A", "w", "x", "y"), combineInput("B", "x", "y", "z"), combineInput("C", "y", "x")}, []string{"y", "x"}, "Intersection of A, B and C"},
+		{"exclusion of 2", ExcludeData, []MultiEntityData{combineInput("A", "x", "y"), combineInput("B", "y")}, []string{"x"}, "A excluding B"},
+		{"exclusion of 3", ExcludeData, []MultiEntityData{combineInput("A", "w", "x", "y"), combineInput("B", "x"), combineInput("C", "y")}, []string{"w"}, "A excluding B and C"},
+	}
+
+	for _, v := range tests {
+		got := v.step(ctx, v.inputs)
+
+		if ids := entityIds(got); !reflect.DeepEqual(ids, v.ids) {
+			t.Errorf("%s: entities = %v, want %v", v.name, ids, v.ids)
+		}
+		if got.Title != v.title {
+			t.Errorf("%s: title = %q, want %q", v.name, got.Title, v.title)
+		}
+	}
+}
+
+func TestCombineStepsJoinErrors(t *testing.T) {
+	ctx := context.Background()
+
+	for _, step := range []StepFnType{UnionData, IntersectData, ExcludeData} {
+		inputs := []MultiEntityData{combineInput("A", "x"), combineInput("B", "x"), combineInput("C", "x"), combineInput("D", "x")}
+		inputs[0].Error = "A failed"
+		inputs[2].Error = "C failed"
+		inputs[3].Error = "D failed"
+
+		if got := step(ctx, inputs); got.Error != "A failed, C failed, D failed" {
+			t.Errorf("error = %q", got.Error)
+		}
+	}
+}
+
+func TestCombineChildren(t *testing.T) {
+	for _, name := range []string{"Union", "Difference", "Intersection", "Exclusion"} {
+		step, ok := Steps.Lookup(component.CombineData, name)
+		if !ok {
+			t.Errorf("no step %s", name)
+			continue
+		}
+
+		for n, want := range map[int]bool{0: false, 1: false, 2: true, 3: true, 10: true} {
+			if got := step.AcceptsChildren(n); got != want {
+				t.Errorf("%s accepts %d children = %v, want %v", name, n, got, want)
+			}
+		}
+
+		if got := describeChildren(step); !strings.HasPrefix(got, "at least 2") {
+			t.Errorf("%s takes %s", name, got)
+		}
+	}
+}
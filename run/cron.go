@@ -0,0 +1,172 @@
+package run
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed five field cron expression: minute, hour, day of
+// the month, month and day of the week
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// When both days are restricted a time matches either of them, as in cron
+	domStar, dowStar bool
+}
+
+type cronField struct {
+	min, max int
+	names    map[string]int
+}
+
+var cronFields = []cronField{
+	{0, 59, nil},
+	{0, 23, nil},
+	{1, 31, nil},
+	{1, 12, map[string]int{"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6, "jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12}},
+	{0, 7, map[string]int{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}},
+}
+
+var cronShortcuts = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+func parseCron(expr string) (cronSchedule, error) {
+	var c cronSchedule
+
+	if shortcut, ok := cronShortcuts[strings.ToLower(strings.TrimSpace(expr))]; ok {
+		expr = shortcut
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return c, errors.New("A schedule needs five fields: minute, hour, day of month, month and day of week")
+	}
+
+	bits := make([]uint64, 5)
+	for i, v := range fields {
+		b, err := parseCronField(v, cronFields[i])
+		if err != nil {
+			return c, errors.New("Invalid schedule field " + v + ": " + err.Error())
+		}
+		bits[i] = b
+	}
+
+	// Sunday can be written as 0 or 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+
+	c.minute, c.hour, c.dom, c.month, c.dow = bits[0], bits[1], bits[2], bits[3], bits[4]
+	c.domStar = fields[2] == "*"
+	c.dowStar = fields[4] == "*"
+
+	return c, nil
+}
+
+// parseCronField turns a comma separated list of values, ranges and steps
+// such as "1-5" or "*/15" into a bit per allowed value
+func parseCronField(s string, f cronField) (uint64, error) {
+	var bits uint64
+
+	for _, part := range strings.Split(s, ",") {
+		step := 1
+
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, errors.New("bad step")
+			}
+			step = n
+			part = part[:i]
+		}
+
+		low, high := f.min, f.max
+
+		if part != "*" {
+			bounds := strings.SplitN(part, "-", 2)
+
+			var err error
+			if low, err = cronValue(bounds[0], f); err != nil {
+				return 0, err
+			}
+
+			high = low
+			if len(bounds) == 2 {
+				if high, err = cronValue(bounds[1], f); err != nil {
+					return 0, err
+				}
+			} else if step > 1 {
+				high = f.max
+			}
+
+			if high < low {
+				return 0, errors.New("range ends before it starts")
+			}
+		}
+
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+
+	return bits, nil
+}
+
+func cronValue(s string, f cronField) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, errors.New("unknown value " + s)
+	}
+
+	if v < f.min || v > f.max {
+		return 0, errors.New("value " + s + " out of range")
+	}
+
+	return v, nil
+}
+
+func (c cronSchedule) dayMatches(t time.Time) bool {
+	domMatch := c.dom&(1<<uint(t.Day())) != 0
+	dowMatch := c.dow&(1<<uint(t.Weekday())) != 0
+
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
+
+// Next returns the first matching minute after t in t's location, or the zero
+// time when nothing matches within five years
+func (c cronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+		case !c.dayMatches(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	return time.Time{}
+}
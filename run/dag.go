@@ -0,0 +1,203 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// dagState holds the nodes of a tree that can be referenced by id and the
+// results of the ones that have been executed
+type dagState struct {
+	nodes    map[string]ExecutionNode
+	cycleErr error
+	mutex    sync.Mutex
+	results  map[string]*sharedResult
+}
+
+type sharedResult struct {
+	once sync.Once
+	med  MultiEntityData
+}
+
+type dagKey struct{}
+
+func withDAG(ctx context.Context, root ExecutionNode) (context.Context, *dagState) {
+	d := &dagState{
+		nodes:   root.NodeIds(),
+		results: make(map[string]*sharedResult),
+	}
+
+	if cycle := root.findCycle(d.nodes); cycle != nil {
+		d.cycleErr = errors.New("Cycle between nodes " + strings.Join(cycle, " → "))
+	}
+
+	return context.WithValue(ctx, dagKey{}, d), d
+}
+
+func dagFromContext(ctx context.Context) (*dagState, bool) {
+	d, ok := ctx.Value(dagKey{}).(*dagState)
+
+	return d, ok
+}
+
+// NodeIds maps the id of every node in the tree that has one to the node.
+// When an id is used twice the first node found keeps it.
+func (e ExecutionNode) NodeIds() map[string]ExecutionNode {
+	nodes := make(map[string]ExecutionNode)
+
+	e.walk(func(n ExecutionNode) {
+		if _, ok := nodes[n.Id]; n.Id != "" && !ok {
+			nodes[n.Id] = n
+		}
+	})
+
+	return nodes
+}
+
+// walk visits the node and its descendants without following references
+func (e ExecutionNode) walk(fn func(ExecutionNode)) {
+	fn(e)
+
+	for _, v := range e.Children {
+		v.walk(fn)
+	}
+}
+
+// dependencies lists the ids a node with an id needs before it can be
+// executed. These are the nodes it references and the nodes with ids
+// nested inside it.
+func (e ExecutionNode) dependencies() []string {
+	deps := make([]string, 0)
+
+	for _, child := range e.Children {
+		child.walk(func(n ExecutionNode) {
+			if n.Ref != "" {
+				deps = append(deps, n.Ref)
+			}
+			if n.Id != "" {
+				deps = append(deps, n.Id)
+			}
+		})
+	}
+
+	return deps
+}
+
+// findCycle returns the ids on a cycle of references or nil if there are none
+func (e ExecutionNode) findCycle(nodes map[string]ExecutionNode) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int)
+	stack := make([]string, 0)
+
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		switch state[id] {
+		case visiting:
+			for i, v := range stack {
+				if v == id {
+					return append(append([]string{}, stack[i:]...), id)
+				}
+			}
+		case visited:
+			return nil
+		}
+
+		node, ok := nodes[id]
+		if !ok {
+			return nil
+		}
+
+		state[id] = visiting
+		stack = append(stack, id)
+
+		for _, dep := range node.dependencies() {
+			if cycle := visit(dep); cycle != nil {
+				return cycle
+			}
+		}
+
+		stack = stack[:len(stack)-1]
+		state[id] = visited
+
+		return nil
+	}
+
+	ids := make([]string, 0, len(nodes))
+	for k, _ := range nodes {
+		ids = append(ids, k)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		if cycle := visit(id); cycle != nil {
+			return cycle
+		}
+	}
+
+	return nil
+}
+
+// shared executes the node with the given id once, under its sharedPath, and
+// hands each caller its own copy, since steps change the data they are given
+func (d *dagState) shared(ctx context.Context, id string, nodeId string, node ExecutionNode) MultiEntityData {
+	d.mutex.Lock()
+	r, ok := d.results[nodeId]
+	if !ok {
+		r = &sharedResult{}
+		d.results[nodeId] = r
+	}
+	d.mutex.Unlock()
+
+	r.once.Do(func() {
+		r.med = node.execute(withNodePath(ctx, sharedPath(nodeId)), id, "")
+	})
+
+	return r.med.Copy()
+}
+
+// Copy makes a deep copy of the data. Unlike Duplicate it keeps values that
+// can't be represented in JSON such as NaN.
+func (m MultiEntityData) Copy() MultiEntityData {
+	newData := m
+
+	if m.EntityData == nil {
+		return newData
+	}
+
+	newData.EntityData = make([]SingleEntityData, len(m.EntityData))
+
+	for i, v := range m.EntityData {
+		newData.EntityData[i] = v
+
+		if v.Data != nil {
+			newData.EntityData[i].Data = make([]Series, len(v.Data))
+			for j, s := range v.Data {
+				newData.EntityData[i].Data[j] = s
+				if s.Data != nil {
+					newData.EntityData[i].Data[j].Data = make([]DataPoint, len(s.Data))
+					copy(newData.EntityData[i].Data[j].Data, s.Data)
+				}
+			}
+		}
+
+		if v.Category.Data != nil {
+			newData.EntityData[i].Category.Data = make([]CategoryPoint, len(v.Category.Data))
+			copy(newData.EntityData[i].Category.Data, v.Category.Data)
+		}
+
+		if v.Category.Labels != nil {
+			newData.EntityData[i].Category.Labels = make([]CategoryLabel, len(v.Category.Labels))
+			copy(newData.EntityData[i].Category.Labels, v.Category.Labels)
+		}
+	}
+
+	return newData
+}
@@ -0,0 +1,79 @@
+package run
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AlphaHat/gcp-alpha-hat/component"
+)
+
+func TestSharedNodeRunsOnce(t *testing.T) {
+	ctx := testContext(t)
+
+	shared := testNode("shared", 10, testNode("leaf", 0))
+	shared.Id = "x"
+	e := testNode("root", 0, ExecutionNode{Ref: "x"}, shared, ExecutionNode{Ref: "x"})
+
+	ctx, trace := withRunTrace(ctx)
+	ctx, _ = withDAG(ctx, e)
+	data := e.executeChildren(ctx, "run")
+
+	for i, v := range data {
+		if len(v.EntityData) != 1 || v.EntityData[0].Meta.Name != "shared" {
+			t.Fatalf("child %d = %+v", i, v)
+		}
+	}
+
+	// Every site gets its own copy
+	data[0].EntityData[0].Meta.Name = "changed"
+	if data[2].EntityData[0].Meta.Name != "shared" {
+		t.Errorf("a change to one use of the shared result showed up in another")
+	}
+
+	paths := make([]string, 0)
+	for _, v := range trace.Nodes {
+		paths = append(paths, v.Path)
+	}
+	if strings.Join(paths, " ") != "#x.children[0] #x" {
+		t.Errorf("traced paths = %v, want the shared node once under #x", paths)
+	}
+}
+
+func TestRefTitleIsTheReferencedNode(t *testing.T) {
+	node := func(title string, children ...ExecutionNode) ExecutionNode {
+		return ExecutionNode{Arguments: []component.QueryComponent{{QueryComponentOriginalString: title}}, Children: children}
+	}
+
+	shared := node("Price", node("S&P 500"))
+	shared.Id = "x"
+	e := node("Union", shared, node("Scale", ExecutionNode{Ref: "x"}))
+
+	if got := e.Children[1].TitleWith(e.NodeIds()); got != "S&P 500 → Price → Scale" {
+		t.Errorf("title = %q", got)
+	}
+	if got := e.Children[1].GetTitle(); got != "x → Scale" {
+		t.Errorf("title without the rest of the tree = %q", got)
+	}
+}
+
+func TestCycleIsReported(t *testing.T) {
+	ctx := testContext(t)
+
+	a := testNode("a", 0, ExecutionNode{Ref: "b"})
+	a.Id = "a"
+	b := testNode("b", 0, ExecutionNode{Ref: "a"})
+	b.Id = "b"
+	e := testNode("root", 0, a, b)
+
+	if cycle := e.findCycle(e.NodeIds()); strings.Join(cycle, " ") != "a b a" {
+		t.Errorf("cycle = %v", cycle)
+	}
+
+	med := e.Execute(ctx, "run", "")
+	if !strings.Contains(med.Error, "Cycle between nodes a → b → a") {
+		t.Errorf("error = %q", med.Error)
+	}
+	if med.Title == "" {
+		t.Errorf("no title for a tree with a cycle")
+	}
+}
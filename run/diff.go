@@ -0,0 +1,415 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/db"
+)
+
+// DefaultDiffTolerance is the relative change below which values count as equal
+const DefaultDiffTolerance = 1e-6
+
+// maxDiffChanges bounds the value changes reported for a pair of runs
+const maxDiffChanges = 10000
+
+// ValueChange is a value that differs between the runs. Entity is the name to
+// show and EntityKey tells apart entities sharing a name.
+type ValueChange struct {
+	Entity    string
+	EntityKey string
+	Field     string
+	Date      time.Time
+	Old       float64
+	New       float64
+}
+
+type FieldChange struct {
+	Entity string
+	Field  string
+}
+
+// RunDiff describes how the result of a run differs from an earlier one
+type RunDiff struct {
+	OldRunId        string
+	NewRunId        string
+	Tolerance       float64
+	AddedEntities   []string
+	RemovedEntities []string
+	AddedFields     []FieldChange
+	RemovedFields   []FieldChange
+	NewDates        []time.Time
+	RemovedDates    []time.Time
+	Changes         []ValueChange
+	// Truncated is set when there were more changes than are listed
+	Truncated bool
+}
+
+func entityKey(e EntityMeta) string {
+	if e.UniqueId != "" {
+		return e.UniqueId
+	}
+
+	return e.Name
+}
+
+// seriesByLabel indexes the series of an entity that aren't weights by label,
+// keeping the first of series sharing a label
+func seriesByLabel(e SingleEntityData) (map[string]Series, []string) {
+	series := make(map[string]Series)
+	labels := make([]string, 0)
+
+	for _, v := range e.Data {
+		if _, ok := series[v.Meta.Label]; v.IsWeight || ok {
+			continue
+		}
+		series[v.Meta.Label] = v
+		labels = append(labels, v.Meta.Label)
+	}
+
+	return series, labels
+}
+
+// valueChanged compares two values relative to the old one, or absolutely
+// when the old value is zero
+func valueChanged(old float64, new float64, tolerance float64) bool {
+	if math.IsNaN(old) || math.IsNaN(new) {
+		return math.IsNaN(old) != math.IsNaN(new)
+	}
+
+	scale := math.Abs(old)
+	if scale == 0 {
+		scale = 1
+	}
+
+	return math.Abs(new-old) > tolerance*scale
+}
+
+// DiffRuns compares the result of a run with that of an earlier one
+func DiffRuns(old MultiEntityData, new MultiEntityData, tolerance float64) RunDiff {
+	d := RunDiff{
+		Tolerance:       tolerance,
+		AddedEntities:   make([]string, 0),
+		RemovedEntities: make([]string, 0),
+		AddedFields:     make([]FieldChange, 0),
+		RemovedFields:   make([]FieldChange, 0),
+		Changes:         make([]ValueChange, 0),
+	}
+
+	oldEntities := make(map[string]SingleEntityData)
+	for _, v := range old.EntityData {
+		oldEntities[entityKey(v.Meta)] = v
+	}
+
+	newEntities := make(map[string]bool)
+
+	for _, v := range new.EntityData {
+		newEntities[entityKey(v.Meta)] = true
+
+		oldEntity, ok := oldEntities[entityKey(v.Meta)]
+		if !ok {
+			d.AddedEntities = append(d.AddedEntities, v.Meta.Name)
+			continue
+		}
+
+		d.diffEntity(oldEntity, v)
+	}
+
+	for _, v := range old.EntityData {
+		if !newEntities[entityKey(v.Meta)] {
+			d.RemovedEntities = append(d.RemovedEntities, v.Meta.Name)
+		}
+	}
+
+	d.NewDates = subtractDates(new.UniqueDates(), old.UniqueDates())
+	d.RemovedDates = subtractDates(old.UniqueDates(), new.UniqueDates())
+
+	return d
+}
+
+func (d *RunDiff) diffEntity(old SingleEntityData, new SingleEntityData) {
+	oldSeries, oldLabels := seriesByLabel(old)
+	newSeries, newLabels := seriesByLabel(new)
+
+	for _, label := range newLabels {
+		s, ok := oldSeries[label]
+		if !ok {
+			d.AddedFields = append(d.AddedFields, FieldChange{new.Meta.Name, label})
+			continue
+		}
+
+		d.diffSeries(new.Meta, label, s, newSeries[label])
+	}
+
+	for _, label := range oldLabels {
+		if _, ok := newSeries[label]; !ok {
+			d.RemovedFields = append(d.RemovedFields, FieldChange{old.Meta.Name, label})
+		}
+	}
+}
+
+// diffSeries compares the values on the dates both series have
+func (d *RunDiff) diffSeries(entity EntityMeta, field string, old Series, new Series) {
+	oldValues := make(map[time.Time]float64, len(old.Data))
+	for _, v := range old.Data {
+		oldValues[v.Time.UTC()] = v.Data
+	}
+
+	for _, v := range new.Data {
+		oldValue, ok := oldValues[v.Time.UTC()]
+		if !ok || !valueChanged(oldValue, v.Data, d.Tolerance) {
+			continue
+		}
+
+		if len(d.Changes) >= maxDiffChanges {
+			d.Truncated = true
+			return
+		}
+
+		d.Changes = append(d.Changes, ValueChange{entity.Name, entityKey(entity), field, v.Time, oldValue, v.Data})
+	}
+}
+
+// subtractDates returns the dates of a that are not in b
+func subtractDates(a []time.Time, b []time.Time) []time.Time {
+	inB := make(map[time.Time]bool, len(b))
+	for _, v := range b {
+		inB[v.UTC()] = true
+	}
+
+	dates := make([]time.Time, 0)
+	for _, v := range a {
+		if !inB[v.UTC()] {
+			dates = append(dates, v)
+		}
+	}
+
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+
+	return dates
+}
+
+// Sheet lays the differences out as a table with a section per kind of change
+func (d RunDiff) Sheet() Sheet {
+	s := newSheet()
+
+	bold := func(values ...string) []SheetCell {
+		row := make([]SheetCell, len(values))
+		for i, v := range values {
+			row[i] = SheetCell{Value: v, Type: CellString, Style: CellBold}
+		}
+		return row
+	}
+
+	text := func(values ...string) []SheetCell {
+		row := make([]SheetCell, len(values))
+		for i, v := range values {
+			row[i] = SheetCell{Value: v, Type: CellString, Style: CellIndent}
+		}
+		return row
+	}
+
+	section := func(title string, rows [][]SheetCell) {
+		if len(rows) == 0 {
+			return
+		}
+		s = append(s, bold(title))
+		s = append(s, rows...)
+	}
+
+	rows := make([][]SheetCell, 0)
+	for _, v := range d.AddedEntities {
+		rows = append(rows, text(v))
+	}
+	section("Added entities", rows)
+
+	rows = make([][]SheetCell, 0)
+	for _, v := range d.RemovedEntities {
+		rows = append(rows, text(v))
+	}
+	section("Removed entities", rows)
+
+	rows = make([][]SheetCell, 0)
+	for _, v := range d.AddedFields {
+		rows = append(rows, text(v.Entity, v.Field))
+	}
+	section("Added fields", rows)
+
+	rows = make([][]SheetCell, 0)
+	for _, v := range d.RemovedFields {
+		rows = append(rows, text(v.Entity, v.Field))
+	}
+	section("Removed fields", rows)
+
+	rows = make([][]SheetCell, 0)
+	for _, v := range d.NewDates {
+		rows = append(rows, []SheetCell{{Value: v.UTC().String()[0:10], Type: CellDate, Style: CellIndent}})
+	}
+	section("New dates", rows)
+
+	rows = make([][]SheetCell, 0)
+	for _, v := range d.RemovedDates {
+		rows = append(rows, []SheetCell{{Value: v.UTC().String()[0:10], Type: CellDate, Style: CellIndent}})
+	}
+	section("Removed dates", rows)
+
+	if len(d.Changes) > 0 {
+		s = append(s, bold("Entity", "Field", "Date", "Old", "New", "Change"))
+		for _, v := range d.Changes {
+			s = append(s, []SheetCell{
+				{Value: v.Entity, Type: CellString},
+				{Value: v.Field, Type: CellString},
+				{Value: v.Date.UTC().String()[0:10], Type: CellDate},
+				{Value: strconv.FormatFloat(v.Old, 'f', -1, 64), Type: CellFloat},
+				{Value: strconv.FormatFloat(v.New, 'f', -1, 64), Type: CellFloat},
+				{Value: strconv.FormatFloat(v.New-v.Old, 'f', -1, 64), Type: CellFloat},
+			})
+		}
+	}
+
+	if d.Truncated {
+		s = append(s, bold("Only the first "+strconv.Itoa(maxDiffChanges)+" changes are listed"))
+	}
+
+	return s
+}
+
+const (
+	oldSuffix = " (old)"
+	newSuffix = " (new)"
+)
+
+// overlayData puts the old and new series of the entities and fields that
+// changed side by side, with the entities renamed to tell them apart
+func (d RunDiff) overlayData(old MultiEntityData, new MultiEntityData) MultiEntityData {
+	changed := make(map[string]bool)
+	for _, v := range d.Changes {
+		changed[v.EntityKey+"\x00"+v.Field] = true
+	}
+
+	m := MultiEntityData{Title: new.Title, EntityData: make([]SingleEntityData, 0)}
+
+	add := func(source MultiEntityData, suffix string) {
+		for _, v := range source.EntityData {
+			e := SingleEntityData{Meta: v.Meta, Data: make([]Series, 0)}
+			e.Meta.Name = v.Meta.Name + suffix
+			e.Meta.UniqueId = v.Meta.UniqueId + suffix
+
+			for _, s := range v.Data {
+				if !s.IsWeight && changed[entityKey(v.Meta)+"\x00"+s.Meta.Label] {
+					e.Data = append(e.Data, s)
+				}
+			}
+
+			if len(e.Data) > 0 {
+				m.EntityData = append(m.EntityData, e)
+			}
+		}
+	}
+
+	add(old, oldSuffix)
+	add(new, newSuffix)
+
+	return m
+}
+
+// Highcharts overlays the old and new values of the series that changed,
+// with the old ones dashed
+func (d RunDiff) Highcharts(old MultiEntityData, new MultiEntityData, chartOptions ChartOptions) map[string]interface{} {
+	hc := ConvertMultiEntityDataToHighcharts(d.overlayData(old, new), chartOptions)
+
+	if series, ok := hc["series"].([]map[string]interface{}); ok {
+		for i, v := range series {
+			if name, _ := v["name"].(string); strings.Contains(name, oldSuffix) {
+				series[i]["dashStyle"] = "ShortDash"
+			}
+		}
+	}
+
+	hc["subtitle"] = map[string]interface{}{
+		"text": "Run " + d.OldRunId + " against " + d.NewRunId,
+	}
+
+	return hc
+}
+
+func loadRunData(ctx context.Context, id string) (MultiEntityData, error) {
+	var m DataDummy
+	var med MultiEntityData
+
+	key, err := db.GetFromField(ctx, db.RunData, "RunId", id, &m)
+	if err != nil {
+		return med, err
+	} else if key == "" || len(m.Data) == 0 {
+		return med, errors.New("No results for run " + id)
+	}
+
+	err = json.Unmarshal(m.Data, &med)
+
+	return med, err
+}
+
+// DiffHandler compares the results of the runs given by the old and new form
+// values, or of the two latest versions of a schedule. The format is json,
+// table, csv or chart.
+func DiffHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	oldId := r.FormValue("old")
+	newId := r.FormValue("new")
+
+	if scheduleId := r.FormValue("schedule"); scheduleId != "" && oldId == "" && newId == "" {
+		history, err := ScheduleHistory(ctx, scheduleId)
+		if err != nil || len(history) < 2 {
+			http.Error(w, "Schedule "+scheduleId+" doesn't have two versions to compare", http.StatusNotFound)
+			return
+		}
+		oldId, newId = history[1].RunId, history[0].RunId
+	}
+
+	tolerance := DefaultDiffTolerance
+	if s := r.FormValue("tolerance"); s != "" {
+		t, err := strconv.ParseFloat(s, 64)
+		if err != nil || t < 0 {
+			http.Error(w, "Invalid tolerance "+s, http.StatusBadRequest)
+			return
+		}
+		tolerance = t
+	}
+
+	old, err := loadRunData(ctx, oldId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	new, err := loadRunData(ctx, newId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	d := DiffRuns(old, new, tolerance)
+	d.OldRunId, d.NewRunId = oldId, newId
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+
+	switch r.FormValue("format") {
+	case "table":
+		returnJson(ctx, w, d.Sheet())
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		logError(ctx, WriteCSV(w, d.Sheet()))
+	case "chart":
+		returnJson(ctx, w, d.Highcharts(old, new, ParseChartOptions(ctx, r.FormValue("options"))))
+	default:
+		returnJson(ctx, w, d)
+	}
+}
@@ -0,0 +1,108 @@
+package run
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValueChanged(t *testing.T) {
+	tests := []struct {
+		old, new  float64
+		tolerance float64
+		changed   bool
+	}{
+		{100, 100, 0, false},
+		{100, 100.001, 1e-6, true},
+		{100, 100.001, 1e-4, false},
+		{-100, -101, 0.001, true},
+		{0, 1e-7, 1e-6, false},
+		{0, 1e-5, 1e-6, true},
+		{math.NaN(), math.NaN(), 0, false},
+		{math.NaN(), 1, 0, true},
+		{1, math.NaN(), 0, true},
+	}
+
+	for _, v := range tests {
+		if got := valueChanged(v.old, v.new, v.tolerance); got != v.changed {
+			t.Errorf("valueChanged(%v, %v, %v) = %v, want %v", v.old, v.new, v.tolerance, got, v.changed)
+		}
+	}
+}
+
+func TestSubtractDates(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC)
+	}
+	est := time.FixedZone("EST", -5*60*60)
+
+	a := []time.Time{day(3), day(1), day(2), day(4)}
+	b := []time.Time{day(2).In(est), day(5)}
+
+	if got, want := subtractDates(a, b), []time.Time{day(1), day(3), day(4)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got := subtractDates(nil, b); got == nil || len(got) != 0 {
+		t.Errorf("got %v, want an empty list", got)
+	}
+}
+
+func TestDiffRuns(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC)
+	}
+	series := func(label string, values ...float64) Series {
+		s := Series{Meta: SeriesMeta{Label: label}}
+		for i, v := range values {
+			s.Data = append(s.Data, DataPoint{day(i + 1), v})
+		}
+		return s
+	}
+
+	old := MultiEntityData{EntityData: []SingleEntityData{
+		{Meta: EntityMeta{Name: "Apple", UniqueId: "AAPL"}, Data: []Series{series("Price", 1, 2), series("Volume", 5)}},
+		{Meta: EntityMeta{Name: "Alphabet", UniqueId: "GOOG"}, Data: []Series{series("Price", 1, 2)}},
+		{Meta: EntityMeta{Name: "Alphabet", UniqueId: "GOOGL"}, Data: []Series{series("Price", 1, 2)}},
+		{Meta: EntityMeta{Name: "Gone"}, Data: []Series{series("Price", 1)}},
+	}}
+	new := MultiEntityData{EntityData: []SingleEntityData{
+		{Meta: EntityMeta{Name: "Apple", UniqueId: "AAPL"}, Data: []Series{series("Price", 1, 2, 3), series("Return", 1)}},
+		{Meta: EntityMeta{Name: "Alphabet", UniqueId: "GOOG"}, Data: []Series{series("Price", 1, 2)}},
+		{Meta: EntityMeta{Name: "Alphabet", UniqueId: "GOOGL"}, Data: []Series{series("Price", 1, 4)}},
+		{Meta: EntityMeta{Name: "New"}, Data: []Series{series("Price", 1)}},
+	}}
+
+	d := DiffRuns(old, new, DefaultDiffTolerance)
+
+	if want := []string{"New"}; !reflect.DeepEqual(d.AddedEntities, want) {
+		t.Errorf("added entities = %v, want %v", d.AddedEntities, want)
+	}
+	if want := []string{"Gone"}; !reflect.DeepEqual(d.RemovedEntities, want) {
+		t.Errorf("removed entities = %v, want %v", d.RemovedEntities, want)
+	}
+	if want := []FieldChange{{"Apple", "Return"}}; !reflect.DeepEqual(d.AddedFields, want) {
+		t.Errorf("added fields = %v, want %v", d.AddedFields, want)
+	}
+	if want := []FieldChange{{"Apple", "Volume"}}; !reflect.DeepEqual(d.RemovedFields, want) {
+		t.Errorf("removed fields = %v, want %v", d.RemovedFields, want)
+	}
+	if want := []time.Time{day(3)}; !reflect.DeepEqual(d.NewDates, want) {
+		t.Errorf("new dates = %v, want %v", d.NewDates, want)
+	}
+	if want := []ValueChange{{"Alphabet", "GOOGL", "Price", day(2), 2, 4}}; !reflect.DeepEqual(d.Changes, want) {
+		t.Errorf("changes = %v, want %v", d.Changes, want)
+	}
+
+	// Only the entity that changed is overlaid, even though another shares
+	// its name
+	overlay := d.overlayData(old, new)
+	ids := make([]string, 0)
+	for _, v := range overlay.EntityData {
+		ids = append(ids, v.Meta.UniqueId)
+	}
+	if want := []string{"GOOGL" + oldSuffix, "GOOGL" + newSuffix}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("overlay = %v, want %v", ids, want)
+	}
+}
@@ -0,0 +1,232 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+	"github.com/AlphaHat/gcp-alpha-hat/platform/log"
+	"github.com/AlphaHat/gcp-alpha-hat/track"
+)
+
+const (
+	EventProgress = "progress"
+	EventNode     = "node"
+	EventWarning  = "warning"
+	EventResult   = "result"
+)
+
+// maxRunEvents bounds the event log of a run. Events are never dropped from
+// the front since their position is used as the stream's event id.
+const maxRunEvents = 1000
+
+// eventPollInterval is how often the stream checks for new events
+var eventPollInterval = time.Second
+
+// eventStreamTimeout ends a stream before the request deadline. Browsers
+// reconnect on their own and resume from the last event id.
+var eventStreamTimeout = time.Second * 50
+
+// RunEvent is something that happened during a run that a client may want to be told about
+type RunEvent struct {
+	Type    string
+	Node    string `json:",omitempty"`
+	Message string
+	Time    time.Time
+}
+
+type RunResult struct {
+	Id    string
+	State string `json:",omitempty"`
+	Error string `json:",omitempty"`
+}
+
+func eventsKey(id string) string {
+	return "events:" + id
+}
+
+func eventKey(id string, n int) string {
+	return eventsKey(id) + ":" + strconv.Itoa(n)
+}
+
+// appendRunEvent adds to the run's event log. Each event has a key of its own
+// and eventsKey holds the count, so an append doesn't rewrite the whole log.
+// The count is incremented first to reserve the event's position, and readers
+// stop at a position whose event hasn't been written yet.
+func appendRunEvent(ctx context.Context, id string, ev RunEvent) {
+	count, err := platform.CacheIncrement(ctx, eventsKey(id), 1, 0)
+	if err != nil {
+		log.Errorf(ctx, "appendRunEvent err = %s", err)
+		return
+	}
+
+	n := int(count) - 1
+	if n >= maxRunEvents {
+		return
+	}
+
+	ev.Time = time.Now()
+
+	if err := platform.CacheSetGob(ctx, eventKey(id, n), ev, time.Hour*24); err != nil {
+		log.Errorf(ctx, "appendRunEvent err = %s", err)
+	}
+}
+
+func countRunEvents(ctx context.Context, id string) int {
+	b, err := platform.CacheGet(ctx, eventsKey(id))
+	if err != nil {
+		return 0
+	}
+
+	n, _ := strconv.Atoi(string(b))
+
+	if n > maxRunEvents {
+		return maxRunEvents
+	}
+
+	return n
+}
+
+// getRunEvents returns the events of the run from position from onwards. It
+// stops at an event that is missing from the cache so that positions stay
+// usable as event ids.
+func getRunEvents(ctx context.Context, id string, from int) []RunEvent {
+	events := make([]RunEvent, 0)
+	n := countRunEvents(ctx, id)
+
+	if from >= n {
+		return events
+	}
+
+	keys := make([]string, 0, n-from)
+	for i := from; i < n; i++ {
+		keys = append(keys, eventKey(id, i))
+	}
+
+	items, err := platform.CacheGetMulti(ctx, keys)
+	if err != nil {
+		log.Errorf(ctx, "getRunEvents err = %s", err)
+		return events
+	}
+
+	for _, v := range keys {
+		var ev RunEvent
+
+		b, found := items[v]
+		if !found || gob.NewDecoder(bytes.NewReader(b)).Decode(&ev) != nil {
+			break
+		}
+
+		events = append(events, ev)
+	}
+
+	return events
+}
+
+// clearRunEvents empties the event log so that a re-run starts a new one
+func clearRunEvents(ctx context.Context, id string) {
+	if err := platform.CacheDelete(ctx, eventsKey(id)); err != nil && err != platform.ErrCacheMiss {
+		log.Warningf(ctx, "Unable to clear the events of %s: %s", id, err)
+	}
+}
+
+// recordNodeEvent tells clients a node has finished and warns them if it failed
+func recordNodeEvent(ctx context.Context, id string, e ExecutionNode, med MultiEntityData, cacheHit bool) {
+	message := "Finished " + e.Describe()
+	if cacheHit {
+		message = "Reused cached result for " + e.Describe()
+	}
+
+	appendRunEvent(ctx, id, RunEvent{Type: EventNode, Node: nodePath(ctx), Message: message})
+
+	if med.Error != "" {
+		appendRunEvent(ctx, id, RunEvent{Type: EventWarning, Node: nodePath(ctx), Message: med.Error})
+	}
+}
+
+// warnRun records a warning for the run being executed in ctx
+func warnRun(ctx context.Context, message string) {
+	if p, ok := progressFromContext(ctx); ok {
+		appendRunEvent(ctx, p.id, RunEvent{Type: EventWarning, Node: nodePath(ctx), Message: message})
+	}
+}
+
+func isFinished(state string) bool {
+	return state == RunCompleted || state == RunCompletedWithErrors || state == RunFailed || state == RunCancelled || state == RunTimedOut
+}
+
+func writeEvent(w io.Writer, id string, eventType string, v interface{}) {
+	b, _ := json.Marshal(v)
+
+	if id != "" {
+		fmt.Fprintf(w, "id: %s\n", id)
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", eventType, b)
+}
+
+// EventsHandler streams the progress of a run as server-sent events. It ends
+// with a result event once the run is finished.
+func EventsHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get(":query")
+
+	flusher, ok := w.(http.Flusher)
+
+	if !ok {
+		http.Error(w, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	next := 0
+	if lastId, err := strconv.Atoi(r.Header.Get("Last-Event-ID")); err == nil {
+		next = lastId + 1
+	}
+
+	var lastProgress track.TrackData
+
+	ticker := time.NewTicker(eventPollInterval)
+	defer ticker.Stop()
+
+	timeout := time.After(eventStreamTimeout)
+
+	for {
+		for _, v := range getRunEvents(ctx, id, next) {
+			writeEvent(w, strconv.Itoa(next), v.Type, v)
+			next++
+		}
+
+		status, complete := runStatus(ctx, id)
+
+		if status.TrackData != lastProgress {
+			writeEvent(w, "", EventProgress, status.TrackData)
+			lastProgress = status.TrackData
+		}
+
+		if complete || isFinished(status.State) {
+			writeEvent(w, "", EventResult, RunResult{id, status.State, status.Error})
+			flusher.Flush()
+			return
+		}
+
+		flusher.Flush()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-timeout:
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package run
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+)
+
+func TestRunEventLog(t *testing.T) {
+	ctx := platform.WithServices(context.Background(), platform.Local(nil))
+
+	for _, v := range []string{"a", "b", "c"} {
+		appendRunEvent(ctx, "run", RunEvent{Type: EventNode, Message: v})
+	}
+
+	if events := getRunEvents(ctx, "run", 1); len(events) != 2 || events[0].Message != "b" || events[1].Message != "c" {
+		t.Errorf("events from 1 = %+v", events)
+	}
+	if events := getRunEvents(ctx, "run", 3); len(events) != 0 {
+		t.Errorf("events from the end = %+v", events)
+	}
+
+	clearRunEvents(ctx, "run")
+	appendRunEvent(ctx, "run", RunEvent{Type: EventNode, Message: "d"})
+
+	if events := getRunEvents(ctx, "run", 0); len(events) != 1 || events[0].Message != "d" {
+		t.Errorf("events after clearing = %+v", events)
+	}
+
+	// Events appended at the same time each get a position of their own
+	clearRunEvents(ctx, "run")
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			appendRunEvent(ctx, "run", RunEvent{Type: EventNode, Message: strconv.Itoa(i)})
+		}(i)
+	}
+	wg.Wait()
+
+	seen := make(map[string]bool)
+	for _, v := range getRunEvents(ctx, "run", 0) {
+		seen[v.Message] = true
+	}
+	if len(seen) != 20 {
+		t.Errorf("%d of 20 concurrent events were kept", len(seen))
+	}
+}
@@ -10,6 +10,11 @@ import (
 )
 
 func GenerateExcelFile(ctx context.Context, hex string, sheetCells [][]SheetCell, w io.Writer) {
+	logError(ctx, WriteExcel(w, sheetCells))
+}
+
+// WriteExcel writes the cells to w as the only sheet of a workbook
+func WriteExcel(w io.Writer, sheetCells [][]SheetCell) error {
 	var file *xlsx.File
 	var sheet *xlsx.Sheet
 	var row *xlsx.Row
@@ -18,8 +23,8 @@ func GenerateExcelFile(ctx context.Context, hex string, sheetCells [][]SheetCell
 
 	file = xlsx.NewFile()
 	sheet, err = file.AddSheet("Sheet1")
-	if !logError(ctx, err) {
-		return
+	if err != nil {
+		return err
 	}
 	sheet.SheetFormat.DefaultColWidth = 11.25
 
@@ -63,5 +68,5 @@ func GenerateExcelFile(ctx context.Context, hex string, sheetCells [][]SheetCell
 	// 	return
 	// }
 
-	file.Write(w)
+	return file.Write(w)
 }
@@ -0,0 +1,195 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/component"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+	"github.com/AlphaHat/gcp-alpha-hat/platform/log"
+)
+
+// EntityDataFn retrieves a single series for one entity. An error means the
+// fetch may be retried, unless it is errNotFound.
+type EntityDataFn func(context.Context, EntityMeta) (Series, error)
+
+// errNotFound means the provider has no data for the entity
+var errNotFound = errors.New("no data found")
+
+// FetchWorkers is the number of entities fetched at once within a single step
+var FetchWorkers = 8
+
+// FetchRetryBudget is the number of retries shared by all the entities of a step
+var FetchRetryBudget = 10
+
+// FetchRetryDelay is the pause before an entity is retried
+var FetchRetryDelay = 500 * time.Millisecond
+
+// ProviderRateLimits is the maximum number of requests per second for each data source
+var ProviderRateLimits = map[string]float64{
+	component.QuandlOpenData: 20,
+	component.Sharadar:       20,
+	component.SECHarmonized:  20,
+	component.Damodaran:      10,
+}
+
+// DefaultRateLimit applies to any data source not in ProviderRateLimits
+var DefaultRateLimit = 10.0
+
+type rateLimiter struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// Wait blocks until the next request slot for the provider is available
+func (r *rateLimiter) Wait(ctx context.Context) error {
+	r.mutex.Lock()
+	now := time.Now()
+	if r.next.Before(now) {
+		r.next = now
+	}
+	slot := r.next
+	r.next = r.next.Add(r.interval)
+	r.mutex.Unlock()
+
+	delay := slot.Sub(now)
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+var limiterMutex sync.Mutex
+var providerLimiters = make(map[string]*rateLimiter)
+
+// limiterFor returns the limiter shared by all the runs fetching from a provider
+func limiterFor(provider string) *rateLimiter {
+	limiterMutex.Lock()
+	defer limiterMutex.Unlock()
+
+	if l, ok := providerLimiters[provider]; ok {
+		return l
+	}
+
+	rate, ok := ProviderRateLimits[provider]
+	if !ok || rate <= 0 {
+		rate = DefaultRateLimit
+	}
+
+	l := &rateLimiter{interval: time.Duration(float64(time.Second) / rate)}
+	providerLimiters[provider] = l
+
+	return l
+}
+
+// RateLimited makes the requests fn sends to the provider wait for a slot
+// from its limiter. Data fn finds in the cache doesn't wait.
+func RateLimited(provider string, fn EntityDataFn) EntityDataFn {
+	limiter := limiterFor(provider)
+
+	return func(ctx context.Context, e EntityMeta) (Series, error) {
+		return fn(platform.WithThrottle(ctx, limiter.Wait), e)
+	}
+}
+
+// fetchEntities calls fn for every entity using a bounded pool of workers.
+// Failed fetches are retried until the shared retry budget runs out, and then
+// the result isn't complete. The result is in the same order as the entities
+// regardless of completion order.
+func fetchEntities(ctx context.Context, entities []SingleEntityData, fn EntityDataFn) ([]Series, bool) {
+	results := make([]Series, len(entities))
+	budget := int32(FetchRetryBudget)
+	var failed int32
+
+	workers := FetchWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > len(entities) {
+		workers = len(entities)
+	}
+
+	// Report progress roughly every 5% of the entities
+	reportEvery := int32(len(entities)/20 + 1)
+	var done int32
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				var ok bool
+				results[i], ok = fetchWithRetry(ctx, entities[i].Meta, fn, &budget)
+				if !ok {
+					atomic.StoreInt32(&failed, 1)
+				}
+				results[i].Data = truncateAfterAsOf(ctx, results[i].Data)
+
+				n := atomic.AddInt32(&done, 1)
+				if n%reportEvery == 0 || int(n) == len(entities) {
+					progressEntities(ctx, int(n), len(entities))
+				}
+			}
+		}()
+	}
+
+	for i, _ := range entities {
+		if ctx.Err() != nil {
+			results[i] = Series{Data: make([]DataPoint, 0)}
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+
+	wg.Wait()
+
+	return results, failed == 0
+}
+
+// fetchWithRetry is false when the fetch still failed once the budget ran out
+func fetchWithRetry(ctx context.Context, e EntityMeta, fn EntityDataFn, budget *int32) (Series, bool) {
+	for {
+		s, err := fn(ctx, e)
+
+		if err == nil || ctx.Err() != nil {
+			return s, true
+		}
+
+		if err == errNotFound {
+			warnRun(ctx, "No data for "+e.Name)
+			return s, true
+		}
+
+		if atomic.AddInt32(budget, -1) < 0 {
+			log.Errorf(ctx, "fetch of %s failed and the retry budget is exhausted: %s", e.Name, err)
+			warnRun(ctx, "No data for "+e.Name)
+			return s, false
+		}
+
+		log.Infof(ctx, "retrying fetch of %s: %s", e.Name, err)
+
+		select {
+		case <-time.After(FetchRetryDelay):
+		case <-ctx.Done():
+			return s, true
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package run
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/component"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+)
+
+func TestRateLimitOnlyThrottlesRequests(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "example.com"), 0755)
+	ioutil.WriteFile(filepath.Join(dir, "example.com", "data"), []byte("1"), 0644)
+
+	services := platform.Local(nil)
+	services.Fetch = platform.NewDirFetcher(dir)
+	ctx := platform.WithServices(context.Background(), services)
+
+	ProviderRateLimits["test"] = 20
+	t.Cleanup(func() {
+		delete(ProviderRateLimits, "test")
+		limiterMutex.Lock()
+		delete(providerLimiters, "test")
+		limiterMutex.Unlock()
+	})
+
+	cached := RateLimited("test", func(ctx context.Context, e EntityMeta) (Series, error) {
+		return Series{}, nil
+	})
+	start := time.Now()
+	for i := 0; i < 50; i++ {
+		cached(ctx, EntityMeta{})
+	}
+	if d := time.Since(start); d > 40*time.Millisecond {
+		t.Errorf("50 cached fetches took %s", d)
+	}
+
+	fetched := RateLimited("test", func(ctx context.Context, e EntityMeta) (Series, error) {
+		resp, err := platform.Client(ctx).Get("http://example.com/data")
+		if err == nil {
+			resp.Body.Close()
+		}
+		return Series{}, err
+	})
+	start = time.Now()
+	for i := 0; i < 4; i++ {
+		if _, err := fetched(ctx, EntityMeta{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 100*time.Millisecond {
+		t.Errorf("4 requests at 20 a second took only %s", d)
+	}
+}
+
+func TestNotFoundIsNotRetried(t *testing.T) {
+	ctx := platform.WithServices(context.Background(), platform.Local(nil))
+
+	calls := 0
+	missing := func(ctx context.Context, e EntityMeta) (Series, error) {
+		calls++
+		return Series{}, errNotFound
+	}
+	budget := int32(FetchRetryBudget)
+	if _, ok := fetchWithRetry(ctx, EntityMeta{Name: "Missing"}, missing, &budget); !ok || calls != 1 || budget != int32(FetchRetryBudget) {
+		t.Errorf("not found: %d calls, budget %d", calls, budget)
+	}
+
+	calls = 0
+	defer func(d time.Duration) { FetchRetryDelay = d }(FetchRetryDelay)
+	FetchRetryDelay = time.Millisecond
+	flaky := func(ctx context.Context, e EntityMeta) (Series, error) {
+		calls++
+		if calls < 3 {
+			return Series{}, errors.New("timeout")
+		}
+		return Series{}, nil
+	}
+	if _, ok := fetchWithRetry(ctx, EntityMeta{Name: "Flaky"}, flaky, &budget); !ok || calls != 3 || budget != int32(FetchRetryBudget)-2 {
+		t.Errorf("transient: %d calls, budget %d", calls, budget)
+	}
+}
+
+func TestExhaustedBudgetIsNotMemoized(t *testing.T) {
+	ctx := platform.WithServices(context.Background(), platform.Local(nil))
+
+	defer func(memo bool, budget int) { MemoEnabled, FetchRetryBudget = memo, budget }(MemoEnabled, FetchRetryBudget)
+	MemoEnabled, FetchRetryBudget = true, 0
+
+	calls := 0
+	failing := func(ctx context.Context, e EntityMeta) (Series, error) {
+		calls++
+		return Series{}, errors.New("timeout")
+	}
+
+	RegisterStep(ComputationStep{
+		Type: "Test Exhausted Fetch",
+		ComputeFn: func(c []component.QueryComponent) StepFnType {
+			return func(ctx context.Context, m []MultiEntityData) MultiEntityData {
+				universe := MultiEntityData{EntityData: []SingleEntityData{{Meta: EntityMeta{Name: "A"}}}}
+				return GetUniverseData(failing)(ctx, []MultiEntityData{universe})
+			}
+		},
+	})
+	e := ExecutionNode{Type: "Test Exhausted Fetch"}
+
+	if med := e.Execute(ctx, "run", ""); !med.Incomplete {
+		t.Error("the result isn't marked incomplete")
+	}
+	e.Execute(ctx, "run", "")
+
+	if calls != 2 {
+		t.Errorf("%d fetches, want the second run to fetch again", calls)
+	}
+}
@@ -0,0 +1,53 @@
+package run
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+)
+
+// ExecuteLocally runs a tree in the current process rather than on a worker.
+// The error describes the node that failed when the result has one.
+func ExecuteLocally(ctx context.Context, t ExecutionNode, id string) (MultiEntityData, *RunTrace, error) {
+	runCtx, failure := withRunFailure(ctx)
+	runCtx, trace := withRunTrace(runCtx)
+	runCtx, _ = withRunProgress(runCtx, id, t)
+
+	med := t.Execute(runCtx, id, "")
+
+	if med.Error != "" {
+		node, nodeErr := failure.get()
+		if node != "" {
+			return med, trace, errors.New(node + ": " + nodeErr)
+		}
+		return med, trace, errors.New(med.Error)
+	}
+
+	return med, trace, nil
+}
+
+// ConvertToSheet lays the data out the way it is shown in the preview and
+// the Excel download
+func ConvertToSheet(m MultiEntityData) Sheet {
+	return convertMultiEntityDataToSheet(m, false)
+}
+
+func WriteCSV(w io.Writer, sheet Sheet) error {
+	cw := csv.NewWriter(w)
+
+	for _, row := range sheet {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = v.Value
+		}
+
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+
+	return cw.Error()
+}
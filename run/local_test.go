@@ -0,0 +1,57 @@
+package run
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/component"
+)
+
+// The daily test step returns an entity with a value for every day of 2026
+// up to October 10th
+func init() {
+	RegisterStep(ComputationStep{
+		Type:        "Test Daily",
+		MaxChildren: UnlimitedChildren,
+		ComputeFn: func(c []component.QueryComponent) StepFnType {
+			return func(ctx context.Context, m []MultiEntityData) MultiEntityData {
+				s := Series{Meta: SeriesMeta{Label: "Price"}}
+				for d := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC); d.Month() < 10 || d.Day() <= 10; d = d.AddDate(0, 0, 1) {
+					s.Data = append(s.Data, DataPoint{d, 1})
+				}
+
+				return MultiEntityData{EntityData: []SingleEntityData{{Meta: EntityMeta{Name: "Daily"}, Data: []Series{s}}}}
+			}
+		},
+	})
+}
+
+func TestExecuteLocallyAsOf(t *testing.T) {
+	tree := ExecutionNode{
+		Type:      component.TimeSlice,
+		Arguments: []component.QueryComponent{{QueryComponentCanonicalName: "YTD"}},
+		Children:  []ExecutionNode{{Type: "Test Daily"}},
+	}
+
+	lastDay := func(ctx context.Context) time.Time {
+		med, _, err := ExecuteLocally(ctx, tree, "asof")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		points := med.EntityData[0].Data[0].Data
+		return points[len(points)-1].Time
+	}
+
+	// The year to date ends on the business day before the as-of date
+	asOf := time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)
+	if got, want := lastDay(WithAsOf(testContext(t), asOf)), time.Date(2026, 3, 13, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("as of %v the data ends on %v, want %v", asOf, got, want)
+	}
+
+	asOf = time.Date(2026, 12, 31, 0, 0, 0, 0, time.UTC)
+	if got, want := lastDay(WithAsOf(testContext(t), asOf)), time.Date(2026, 10, 10, 0, 0, 0, 0, time.UTC); !got.Equal(want) {
+		t.Errorf("as of %v the data ends on %v, want %v", asOf, got, want)
+	}
+}
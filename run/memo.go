@@ -0,0 +1,208 @@
+package run
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/component"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+	"github.com/AlphaHat/gcp-alpha-hat/platform/log"
+)
+
+// MemoEnabled turns on the reuse of results for identical subtrees
+var MemoEnabled = true
+
+// MemoTimeout is how long a subtree result can be reused
+var MemoTimeout = time.Hour * 4
+
+// maxLocalMemoBytes bounds the encoded size of the results kept in this
+// instance's memory
+var maxLocalMemoBytes = 64 << 20
+
+// maxMemoBytes is the largest result that is kept. Anything larger would push
+// most of the other results out of the local memo.
+var maxMemoBytes = 8 << 20
+
+// maxCacheItemBytes is the largest value memcache accepts
+const maxCacheItemBytes = 1 << 20
+
+// memoBytesPerPoint is roughly the encoded size of a data point, used to skip
+// results that are too large before encoding them
+const memoBytesPerPoint = 48
+
+type memoEntry struct {
+	stored time.Time
+	data   []byte
+}
+
+var memoMutex sync.Mutex
+var localMemo = make(map[string]memoEntry)
+var localMemoBytes int
+
+type normalizedNode struct {
+	Type      string
+	Arguments []component.QueryComponent
+	Children  []normalizedNode
+}
+
+// normalizeNode replaces references with the nodes they refer to so that a
+// shared subtree hashes the same as a repeated one
+func normalizeNode(e ExecutionNode, nodes map[string]ExecutionNode, depth int) normalizedNode {
+	if target, ok := nodes[e.Ref]; e.Ref != "" && ok && depth < len(nodes)+1 {
+		return normalizeNode(target, nodes, depth+1)
+	}
+
+	n := normalizedNode{Type: e.Type}
+
+	n.Arguments = make([]component.QueryComponent, len(e.Arguments))
+	for i, v := range e.Arguments {
+		// The id is only used by the front end to track the component
+		v.QueryComponentId = 0
+		n.Arguments[i] = v
+	}
+
+	n.Children = make([]normalizedNode, len(e.Children))
+	for i, v := range e.Children {
+		n.Children[i] = normalizeNode(v, nodes, depth)
+	}
+
+	return n
+}
+
+// Hash identifies the result of a subtree. Two subtrees with the same steps,
+// arguments and children evaluated on the same as-of date give the same data,
+// as long as both or neither are point in time runs, which drop later data.
+// References are resolved against nodes.
+func (e ExecutionNode) Hash(ctx context.Context, nodes map[string]ExecutionNode) string {
+	b, _ := json.Marshal(normalizeNode(e, nodes, 0))
+
+	h := sha256.New()
+	h.Write(b)
+	h.Write([]byte(AsOf(ctx).Format("2006-01-02")))
+	if _, ok := pointInTime(ctx); ok {
+		h.Write([]byte("point in time"))
+	}
+
+	return fmt.Sprintf("%x", h.Sum(nil))
+}
+
+func memoKey(hash string) string {
+	return "memo:" + hash
+}
+
+// lookupMemo returns a copy of a previously computed result for the subtree
+func lookupMemo(ctx context.Context, hash string) (MultiEntityData, bool) {
+	var m MultiEntityData
+
+	if !MemoEnabled {
+		return m, false
+	}
+
+	memoMutex.Lock()
+	entry, ok := localMemo[hash]
+	if ok && time.Since(entry.stored) > MemoTimeout {
+		deleteMemo(hash)
+		ok = false
+	}
+	memoMutex.Unlock()
+
+	if !ok {
+		value, err := platform.CacheGet(ctx, memoKey(hash))
+		if err != nil {
+			return m, false
+		}
+		entry = memoEntry{time.Now(), value}
+	}
+
+	if err := json.Unmarshal(entry.data, &m); err != nil {
+		log.Errorf(ctx, "lookupMemo err = %s", err)
+		return m, false
+	}
+
+	return m, true
+}
+
+// storeMemo keeps a result both locally and in memcache so that other runs
+// can use it. Failed or incomplete results and those over maxMemoBytes aren't
+// kept, and only those that fit in memcache are shared with other instances.
+func storeMemo(ctx context.Context, hash string, m MultiEntityData) {
+	if !MemoEnabled || m.Error != "" || m.Incomplete {
+		return
+	}
+
+	if memoPoints(m)*memoBytesPerPoint > maxMemoBytes {
+		log.Infof(ctx, "storeMemo skipped %s, it has %d points", hash, memoPoints(m))
+		return
+	}
+
+	b, err := json.Marshal(m)
+	if err != nil {
+		log.Errorf(ctx, "storeMemo err = %s", err)
+		return
+	}
+
+	if len(b) > maxMemoBytes {
+		log.Infof(ctx, "storeMemo skipped %s, it is %d bytes", hash, len(b))
+		return
+	}
+
+	memoMutex.Lock()
+	deleteMemo(hash)
+	for len(localMemo) > 0 && localMemoBytes+len(b) > maxLocalMemoBytes {
+		evictOldestMemo()
+	}
+	localMemo[hash] = memoEntry{time.Now(), b}
+	localMemoBytes += len(b)
+	memoMutex.Unlock()
+
+	if len(b) > maxCacheItemBytes {
+		return
+	}
+
+	err = platform.CacheSet(ctx, memoKey(hash), b, MemoTimeout)
+	if err != nil {
+		log.Infof(ctx, "storeMemo cache err = %s", err)
+	}
+}
+
+// memoPoints counts the data and category points of a result
+func memoPoints(m MultiEntityData) int {
+	n := 0
+
+	for _, v := range m.EntityData {
+		n += len(v.Category.Data)
+
+		for _, v2 := range v.Data {
+			n += len(v2.Data)
+		}
+	}
+
+	return n
+}
+
+// deleteMemo must be called with memoMutex held
+func deleteMemo(hash string) {
+	if entry, ok := localMemo[hash]; ok {
+		localMemoBytes -= len(entry.data)
+		delete(localMemo, hash)
+	}
+}
+
+// evictOldestMemo must be called with memoMutex held
+func evictOldestMemo() {
+	var oldestKey string
+	var oldest time.Time
+
+	for k, v := range localMemo {
+		if oldestKey == "" || v.stored.Before(oldest) {
+			oldestKey = k
+			oldest = v.stored
+		}
+	}
+
+	deleteMemo(oldestKey)
+}
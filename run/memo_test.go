@@ -0,0 +1,64 @@
+package run
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+)
+
+func TestLocalMemoIsBoundedByBytes(t *testing.T) {
+	ctx := platform.WithServices(context.Background(), platform.Local(nil))
+
+	defer func(total int, item int) { maxLocalMemoBytes, maxMemoBytes = total, item }(maxLocalMemoBytes, maxMemoBytes)
+	maxLocalMemoBytes, maxMemoBytes = 2000, 1000
+
+	memoMutex.Lock()
+	localMemo, localMemoBytes = make(map[string]memoEntry), 0
+	memoMutex.Unlock()
+
+	result := func(points int) MultiEntityData {
+		s := Series{Data: make([]DataPoint, points)}
+		for i := range s.Data {
+			s.Data[i] = DataPoint{Time: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i), Data: float64(i)}
+		}
+		return MultiEntityData{EntityData: []SingleEntityData{{Data: []Series{s}}}}
+	}
+
+	for i := 0; i < 10; i++ {
+		storeMemo(ctx, "small"+strconv.Itoa(i), result(5))
+	}
+
+	memoMutex.Lock()
+	if localMemoBytes > maxLocalMemoBytes || len(localMemo) == 0 || len(localMemo) == 10 {
+		t.Errorf("%d results take %d bytes", len(localMemo), localMemoBytes)
+	}
+	memoMutex.Unlock()
+
+	if _, ok := lookupMemo(ctx, "small9"); !ok {
+		t.Errorf("the latest result was evicted")
+	}
+
+	storeMemo(ctx, "large", result(100))
+	if _, ok := lookupMemo(ctx, "large"); ok {
+		t.Errorf("a result over maxMemoBytes was kept")
+	}
+}
+
+func TestHashMarksPointInTimeRuns(t *testing.T) {
+	e := ExecutionNode{Type: "Test Step"}
+	live := context.Background()
+	today := WithAsOf(live, time.Now())
+
+	if e.Hash(live, nil) == e.Hash(today, nil) {
+		t.Error("a point in time run as of today shares the hash of a live run")
+	}
+	if e.Hash(today, nil) != e.Hash(WithAsOf(live, time.Now()), nil) {
+		t.Error("runs as of the same day have different hashes")
+	}
+	if e.Hash(today, nil) == e.Hash(WithAsOf(live, time.Now().AddDate(0, 0, -1)), nil) {
+		t.Error("runs as of different days share a hash")
+	}
+}
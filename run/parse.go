@@ -1,12 +1,13 @@
 package run
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"math"
 	"strings"
 	"time"
 
+	"github.com/AlphaHat/gcp-alpha-hat/component"
 	"github.com/AlphaHat/gcp-alpha-hat/parse"
 )
 
@@ -168,7 +169,8 @@ func evaluateLabel(e *parse.Expression, s *SingleEntityData, seriesNum int) (str
 		case parse.TypeElse:
 			return stack[0], nil
 		case parse.TypeFunctionCall:
-			stack[top] = code.Str + "(" + stack[top-1] + ")"
+			top = top - len(code.Args)
+			stack[top] = code.Str + "(" + strings.Join(stack[top:top+len(code.Args)], ", ") + ")"
 			top++
 			continue
 		}
@@ -264,7 +266,7 @@ func evaluateUnits(e *parse.Expression, s *SingleEntityData, seriesNum int) (str
 			return stack[0], nil
 		case parse.TypeFunctionCall:
 			functionName := code.Str
-			valence := getValence(functionName)
+			valence := len(code.Args)
 			top = top - valence
 			if valence == 2 {
 				stack[top] = functionUnits2(functionName, stack[top], stack[top+1])
@@ -279,250 +281,153 @@ func evaluateUnits(e *parse.Expression, s *SingleEntityData, seriesNum int) (str
 	return stack[0], nil
 }
 
+// formulaContext evaluates a formula against the series of an entity
+type formulaContext struct {
+	s         *SingleEntityData
+	this      []DataPoint
+	seriesNum int
+	index     int
+}
+
+// dataPoints lets formulas read a []DataPoint
+type dataPoints []DataPoint
+
+func (d dataPoints) Len() int {
+	return len(d)
+}
+
+func (d dataPoints) Time(i int) time.Time {
+	return d[i].Time
+}
+
+func (d dataPoints) Value(i int) float64 {
+	return d[i].Data
+}
+
+func (c *formulaContext) Series(n int) (parse.Series, error) {
+	if n < 0 || n >= len(c.s.Data) {
+		return nil, errors.New("index out bounds")
+	}
+
+	return dataPoints(c.s.Data[n].Data), nil
+}
+
+func (c *formulaContext) Current() (parse.Series, error) {
+	return c.Series(c.seriesNum)
+}
+
+func (c *formulaContext) This() parse.Series {
+	return dataPoints(c.this)
+}
+
+func (c *formulaContext) Category(i int) string {
+	return c.s.Category.LookupCategory(c.s.Data[c.seriesNum].Data[i].Time)
+}
+
+func (c *formulaContext) Index() int {
+	return c.index
+}
+
+func (c *formulaContext) Function(name string, scalar bool) (parse.Function, bool) {
+	return parse.LookupFunction(name, scalar)
+}
+
 func evaluateExpression(e *parse.Expression, s *SingleEntityData, this []DataPoint, seriesNum int, currentIndex int) (float64, error) {
-	stack, top := make([]float64, len(e.Code)), 0
-	booleanStack := make([]bool, len(e.Code))
-	arrayStack, arrayStackTop := make([][]DataPoint, len(e.Code)), 0
+	return e.Evaluate(&formulaContext{s, this, seriesNum, currentIndex})
+}
 
-	var string1, string2 string
-	for i := 0; i < e.Top; i++ {
-		code := e.Code[i]
-		switch code.T {
-		case parse.TypeNumber:
-			stack[top] = code.Float
-			top++
-			continue
-		case parse.TypeString:
-			if string1 == "" {
-				string1 = strings.TrimSpace(code.Str)
-			} else {
-				string2 = strings.TrimSpace(code.Str)
-			}
-			continue
-		case parse.TypeIdentifierCategory:
-			if seriesNum >= len(s.Data) {
-				return 0, errors.New("index out bounds")
-			}
-			val := s.Data[seriesNum].Data
-			length := len(val)
-			idx, _, err := code.EvaluateIndex(currentIndex, length)
-			if err == nil {
-				t := val[idx].Time // Series identified by seriesNum
-				category := s.Category.LookupCategory(t)
-
-				if string1 == "" {
-					string1 = strings.TrimSpace(category)
-				} else {
-					string2 = strings.TrimSpace(category)
-				}
-			}
+func parseTimeSeriesTransformation(expression string) (*parse.Expression, error) {
+	return parse.ParseFolded(expression)
+}
 
-			continue
-		case parse.TypeIdentifierSpecific:
-			// This will access a specific series
-			if code.Int >= len(s.Data) {
-				return 0, errors.New("index out bounds")
-			}
-			val := s.Data[code.Int].Data
-			length := len(val)
-			idx, _, err := code.EvaluateIndex(currentIndex, length)
-			if err == nil {
-				stack[top] = val[idx].Data // Specific series identified by code.Int
-			} else {
-				return 0, err
-			}
-			top++
-			continue
-		case parse.TypeIdentifierGeneral:
-			// This will access the *current* series (similar to the *current* time)
-			if seriesNum >= len(s.Data) {
-				return 0, errors.New("index out bounds")
-			}
-			val := s.Data[seriesNum].Data
-			length := len(val)
-			idx, _, err := code.EvaluateIndex(currentIndex, length)
-			if err == nil {
-				stack[top] = val[idx].Data // Series identified by seriesNum
-			} else {
-				return 0, err
-			}
-			top++
-			continue
-		case parse.TypeIdentifierThis:
-			// This will access the *current* series (similar to the *current* time)
-			val := this
-			length := len(val)
-			idx, _, err := code.EvaluateIndex(currentIndex, length)
-			if err == nil {
-				stack[top] = val[idx].Data // Series identified by seriesNum
-			} else {
-				return 0, err
-			}
-			top++
-			continue
-		case parse.TypeIdentifierSpecificRange:
-			// This will access a specific series
-			if code.Int >= len(s.Data) {
-				return 0, errors.New("index out bounds")
-			}
-			val := s.Data[code.Int].Data
-			length := len(val)
+// computeFormula runs the TimeSeriesFormula step. Fields the formula names are
+// looked up in each entity. An entity without them, such as a ticker that has
+// no data, is left unchanged with a warning, and the step only fails when no
+// entity has them.
+func computeFormula(c []component.QueryComponent) StepFnType {
+	formula := c[0].QueryComponentOriginalString
+	label := c[1].QueryComponentOriginalString
 
-			idx1, idx2, err := code.EvaluateIndex(currentIndex, length)
-			if err == nil {
-				// fmt.Printf(" idx1=%v, idx2=%v ", idx1, idx2)
-				arrayStack[arrayStackTop] = val[idx1:(1 + idx2)]
-			} else {
-				return 0, err
-			}
-			arrayStackTop++
-			continue
-		case parse.TypeIdentifierGeneralRange:
-			// This will access the *current* series (similar to the *current* time)
-			if seriesNum >= len(s.Data) {
-				return 0, errors.New("index out bounds")
-			}
-			val := s.Data[seriesNum].Data
-			length := len(val)
+	return func(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
+		m := mArr[0]
 
-			idx1, idx2, err := code.EvaluateIndex(currentIndex, length)
-			if err == nil {
-				// fmt.Printf(" idx1=%v, idx2=%v ", idx1, idx2)
-				arrayStack[arrayStackTop] = val[idx1:(1 + idx2)]
-			} else {
-				return 0, err
-			}
-			arrayStackTop++
-			continue
-		case parse.TypeIdentifierThisRange:
-			// This will access the *computed* series
-			val := this
-			length := len(val)
-
-			idx1, idx2, err := code.EvaluateIndex(currentIndex, length)
-			if err == nil {
-				arrayStack[arrayStackTop] = val[idx1:(1 + idx2)]
-			} else {
-				return 0, err
-			}
-			arrayStackTop++
-			continue
-		case parse.TypeNegation:
-			stack[top-1] = -1 * stack[top-1]
-			continue
-		case parse.TypeTrue:
-			booleanStack[top] = true
-			top++
-			continue
-		case parse.TypeFalse:
-			booleanStack[top] = false
-			top++
-			continue
-		case parse.TypeNot:
-			booleanStack[top-1] = !booleanStack[top-1]
-			continue
-		case parse.TypeStringEqual:
-			booleanStack[top] = strings.ToLower(string1) == strings.ToLower(string2)
-			string1, string2 = "", ""
-			top++
-			continue
-		case parse.TypeStringNotEqual:
-			booleanStack[top] = strings.ToLower(string1) != strings.ToLower(string2)
-			string1, string2 = "", ""
-			top++
-			continue
-		case parse.TypeTimeEqual:
-			booleanStack[top] = currentIndex == 0
-			top++
-			continue
+		e, err := parseTimeSeriesTransformation(formula)
+		if err != nil || e == nil {
+			return m
 		}
 
-		switch code.T {
-		case parse.TypeAdd:
-			stack[top-2] = stack[top-2] + stack[top-1]
-		case parse.TypeSubtract:
-			stack[top-2] = stack[top-2] - stack[top-1]
-		case parse.TypeMultiply:
-			stack[top-2] = stack[top-2] * stack[top-1]
-		case parse.TypeDivide:
-			stack[top-2] = stack[top-2] / stack[top-1]
-		case parse.TypeModulus:
-			stack[top-2] = math.Mod(stack[top-2], stack[top-1])
-		case parse.TypeExponentiation:
-			stack[top-2] = math.Pow(stack[top-2], stack[top-1])
-		case parse.TypeLogicalEqual:
-			booleanStack[top-2] = booleanStack[top-2] == booleanStack[top-1]
-		case parse.TypeLogicalNotEqual:
-			booleanStack[top-2] = booleanStack[top-2] != booleanStack[top-1]
-		case parse.TypeAnd:
-			booleanStack[top-2] = booleanStack[top-2] && booleanStack[top-1]
-		case parse.TypeOr:
-			booleanStack[top-2] = booleanStack[top-2] || booleanStack[top-1]
-		case parse.TypeEqual:
-			booleanStack[top-2] = stack[top-2] == stack[top-1]
-		case parse.TypeNotEqual:
-			booleanStack[top-2] = stack[top-2] != stack[top-1]
-		case parse.TypeGreaterThan:
-			booleanStack[top-2] = stack[top-2] > stack[top-1]
-		case parse.TypeGreaterThanEqual:
-			booleanStack[top-2] = stack[top-2] >= stack[top-1]
-		case parse.TypeLessThan:
-			booleanStack[top-2] = stack[top-2] < stack[top-1]
-		case parse.TypeLessThanEqual:
-			booleanStack[top-2] = stack[top-2] <= stack[top-1]
-		case parse.TypeThen:
-			if booleanStack[0] {
-				// Continue on to the next operation
-			} else {
-				// Branch to the else
-				i = e.FindElseAfter(i)
+		skipped := make([]string, 0)
+
+		for i, v := range m.EntityData {
+			if ctx.Err() != nil {
+				return stoppedData(ctx)
 			}
-		case parse.TypeElse:
-			return stack[0], nil
-		case parse.TypeFunctionCall:
-			// Identify valence of the function
-			// Send variadic arguments to the function
-			functionName := code.Str
-			valence := getValence(functionName)
-			arrayStackTop = arrayStackTop - valence
-			if valence == 2 {
-				stack[top] = runFunction2(functionName, arrayStack[arrayStackTop], arrayStack[arrayStackTop+1])
-			} else {
-				stack[top] = runFunction1(functionName, arrayStack[arrayStackTop])
+
+			resolved, err := e.Resolve(fieldLookup(v))
+			if err != nil {
+				if v.Meta.Name != "" {
+					skipped = append(skipped, v.Meta.Name+": "+err.Error())
+				} else {
+					skipped = append(skipped, err.Error())
+				}
+				continue
 			}
-			top++
-			continue
+
+			m.EntityData[i] = convertExpressionToFunction(formula, label, resolved)(v)
 		}
-		top--
+
+		if len(skipped) > 0 && len(skipped) == len(m.EntityData) {
+			return MultiEntityData{Error: skipped[0]}
+		} else if len(skipped) > 0 {
+			warnRun(ctx, skippedMessage(skipped))
+		}
+
+		return m
 	}
-	return stack[0], nil
 }
 
-func parseTimeSeriesTransformation(expression string) (*parse.Expression, error) {
-	calc := &parse.Calculator{Buffer: strings.ToLower(expression)}
-	calc.Init()
-	calc.Expression.Init(expression)
+// maxSkippedShown bounds the entities named in the warning of computeFormula
+const maxSkippedShown = 5
 
-	err := calc.Parse()
+func skippedMessage(skipped []string) string {
+	message := fmt.Sprintf("The formula left %d entities unchanged. ", len(skipped))
 
-	if err == nil {
-		calc.Execute()
-		return &calc.Expression, nil
+	if len(skipped) > maxSkippedShown {
+		return message + strings.Join(skipped[:maxSkippedShown], "; ") + fmt.Sprintf(" and %d more", len(skipped)-maxSkippedShown)
 	}
-	return nil, err
+
+	return message + strings.Join(skipped, "; ")
 }
 
-func formulaToFunction(expression string, label string) func(SingleEntityData) SingleEntityData {
-	e, err := parseTimeSeriesTransformation(expression)
+// fieldLookup finds the series a formula names as [name] or field("name").
+// Labels are tried before vendor codes, ignoring case since the formula has
+// been lowercased.
+func fieldLookup(s SingleEntityData) func(string) (int, error) {
+	return func(name string) (int, error) {
+		for _, byVendorCode := range []bool{false, true} {
+			matches := make([]int, 0)
+			for i, v := range s.Data {
+				key := v.Meta.Label
+				if byVendorCode {
+					key = v.Meta.VendorCode
+				}
 
-	if err == nil && e != nil {
-		fn := convertExpressionToFunction(expression, label, e)
-		return fn
-	} else {
-		// zlog.LogError("formulaToFunction", err)
-	}
+				if key != "" && strings.EqualFold(strings.TrimSpace(key), name) {
+					matches = append(matches, i)
+				}
+			}
 
-	return func(s SingleEntityData) SingleEntityData {
-		return s
+			if len(matches) == 1 {
+				return matches[0], nil
+			} else if len(matches) > 1 {
+				candidates := make([]string, len(matches))
+				for j, n := range matches {
+					candidates[j] = fmt.Sprintf("val%d (%s)", n+1, s.Data[n].Meta.Label)
+				}
+				return -1, errors.New("[" + name + "] is ambiguous, it matches " + strings.Join(candidates, ", "))
+			}
+		}
+
+		return -1, errors.New("No field named [" + name + "]")
 	}
 }
@@ -0,0 +1,61 @@
+package run
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/component"
+)
+
+func TestFieldLookup(t *testing.T) {
+	s := SingleEntityData{Data: []Series{
+		{Meta: SeriesMeta{Label: "Price", VendorCode: "PX_LAST"}},
+		{Meta: SeriesMeta{Label: "Short Interest", VendorCode: "SI"}},
+		{Meta: SeriesMeta{Label: "price", VendorCode: "PX_CLOSE"}},
+	}}
+	lookup := fieldLookup(s)
+
+	if n, err := lookup("short interest"); err != nil || n != 1 {
+		t.Errorf("short interest = %v, %v", n, err)
+	}
+	if n, err := lookup("px_close"); err != nil || n != 2 {
+		t.Errorf("px_close = %v, %v", n, err)
+	}
+	if _, err := lookup("price"); err == nil || !strings.Contains(err.Error(), "val1 (Price), val3 (price)") {
+		t.Errorf("price should be ambiguous, got %v", err)
+	}
+	if _, err := lookup("volume"); err == nil {
+		t.Error("volume should not be found")
+	}
+}
+
+func TestFormulaSkipsEntitiesWithoutField(t *testing.T) {
+	points := []DataPoint{{Time: time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), Data: 10}}
+	data := func() MultiEntityData {
+		return MultiEntityData{EntityData: []SingleEntityData{
+			{Meta: EntityMeta{Name: "AAPL"}, Data: []Series{{Data: points, Meta: SeriesMeta{Label: "Price"}}}},
+			{Meta: EntityMeta{Name: "NODATA"}, Data: []Series{{}}},
+		}}
+	}
+	formula := func(f string) []component.QueryComponent {
+		return []component.QueryComponent{{QueryComponentOriginalString: f}, {QueryComponentOriginalString: "Double"}}
+	}
+
+	m := computeFormula(formula("[price] * 2"))(context.Background(), []MultiEntityData{data()})
+	if m.Error != "" {
+		t.Fatalf("one entity without the field failed the step: %s", m.Error)
+	}
+	if len(m.EntityData[0].Data) != 2 || m.EntityData[0].Data[1].Data[0].Data != 20 {
+		t.Errorf("AAPL = %+v, want its price doubled", m.EntityData[0].Data)
+	}
+	if len(m.EntityData[1].Data) != 1 {
+		t.Errorf("NODATA should be unchanged, got %d series", len(m.EntityData[1].Data))
+	}
+
+	m = computeFormula(formula("[volume] * 2"))(context.Background(), []MultiEntityData{data()})
+	if !strings.Contains(m.Error, "No field named [volume]") {
+		t.Errorf("a field no entity has should fail the step, got %q", m.Error)
+	}
+}
@@ -0,0 +1,277 @@
+package run
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+	"github.com/AlphaHat/gcp-alpha-hat/track"
+)
+
+// defaultStepEstimate is used for steps that haven't been timed before
+var defaultStepEstimate = time.Second
+
+// stepTimingWeight is how much the latest timing counts towards a step's estimate
+const stepTimingWeight = 0.3
+
+// runProgress follows the nodes of a run so that progress can be reported as
+// a fraction of the tree rather than just started or finished
+type runProgress struct {
+	mutex    sync.Mutex
+	id       string
+	total    int
+	pending  map[string]time.Duration
+	partial  map[string]float64
+	stage    map[string]string
+	timedKey map[string]string
+	// children and root give the shape of the tree for the ETA, and shared
+	// holds the ids of the nodes registered under their sharedPath
+	children map[string][]string
+	root     string
+	shared   map[string]bool
+}
+
+type progressKey struct{}
+
+func stepTimingKey(e ExecutionNode) string {
+	if len(e.Arguments) > 0 {
+		return "steptime:" + e.Type + ":" + e.Arguments[0].QueryComponentCanonicalName
+	}
+
+	return "steptime:" + e.Type
+}
+
+// withRunProgress counts the nodes in the tree and looks up how long each of
+// their steps has taken in the past
+func withRunProgress(ctx context.Context, id string, e ExecutionNode) (context.Context, *runProgress) {
+	p := &runProgress{
+		id:       id,
+		pending:  make(map[string]time.Duration),
+		partial:  make(map[string]float64),
+		stage:    make(map[string]string),
+		timedKey: make(map[string]string),
+		children: make(map[string][]string),
+		shared:   make(map[string]bool),
+	}
+
+	p.root = p.addNodes("root", e)
+	p.total = len(p.pending)
+
+	keys := make([]string, 0, len(p.timedKey))
+	for _, v := range p.timedKey {
+		keys = append(keys, v)
+	}
+
+	items, err := platform.CacheGetMulti(ctx, keys)
+	if err == nil {
+		for path, key := range p.timedKey {
+			if value, ok := items[key]; ok {
+				if ms, err := strconv.ParseFloat(string(value), 64); err == nil {
+					p.pending[path] = time.Duration(ms * float64(time.Millisecond))
+				}
+			}
+		}
+	}
+
+	return context.WithValue(ctx, progressKey{}, p), p
+}
+
+// addNodes registers the node and its descendants and returns the node's
+// path. A node with an id is executed under its sharedPath, so the first one
+// with each id is registered there. References take no time of their own.
+func (p *runProgress) addNodes(path string, e ExecutionNode) string {
+	if e.Id != "" && !p.shared[e.Id] {
+		p.shared[e.Id] = true
+		path = sharedPath(e.Id)
+	}
+
+	if e.Ref != "" {
+		p.pending[path] = 0
+	} else {
+		p.pending[path] = defaultStepEstimate
+		p.timedKey[path] = stepTimingKey(e)
+	}
+
+	keys := make([]string, len(e.Children))
+	for i, v := range e.Children {
+		keys[i] = p.addNodes(path+".children["+strconv.Itoa(i)+"]", v)
+	}
+	p.children[path] = keys
+
+	return path
+}
+
+func progressFromContext(ctx context.Context) (*runProgress, bool) {
+	p, ok := ctx.Value(progressKey{}).(*runProgress)
+
+	return p, ok
+}
+
+// details must be called with the mutex held
+func (p *runProgress) details(message string) track.TrackData {
+	done := float64(p.total - len(p.pending))
+
+	for path, _ := range p.pending {
+		done += p.partial[path]
+	}
+
+	eta := p.remaining(p.root)
+
+	pct := 0.0
+	if p.total > 0 {
+		pct = done / float64(p.total)
+	}
+
+	// Only the worker marks a run as complete, once the results are stored
+	if pct > 0.99 {
+		pct = 0.99
+	}
+
+	return track.TrackData{
+		Message:         message,
+		PercentComplete: pct,
+		NodesCompleted:  p.total - len(p.pending),
+		NodesTotal:      p.total,
+		EtaSeconds:      eta.Seconds(),
+	}
+}
+
+// update stores the progress of the run. It must be called with the mutex
+// held so that the updates of a run are written in the order they were made.
+func (p *runProgress) update(ctx context.Context, message string) {
+	track.UpdateDetails(ctx, p.id, p.details(message))
+}
+
+// remaining estimates the time left for a node and its descendants. Siblings
+// are run MaxParallelChildren at a time, so they take the longer of the
+// slowest sibling and their total spread over the workers.
+func (p *runProgress) remaining(path string) time.Duration {
+	var own time.Duration
+	if estimate, ok := p.pending[path]; ok {
+		own = time.Duration(float64(estimate) * (1 - p.partial[path]))
+	}
+
+	var slowest, total time.Duration
+	for _, v := range p.children[path] {
+		r := p.remaining(v)
+		total += r
+		if r > slowest {
+			slowest = r
+		}
+	}
+
+	workers := MaxParallelChildren
+	if workers < 1 {
+		workers = 1
+	}
+
+	if spread := total / time.Duration(workers); spread > slowest {
+		return own + spread
+	}
+
+	return own + slowest
+}
+
+// remove deletes the pending entry of the node at path and, with subtree, of
+// the nodes beneath it. It must be called with the mutex held.
+func (p *runProgress) remove(path string, subtree bool) bool {
+	removed := false
+
+	for k, _ := range p.pending {
+		if k == path || (subtree && strings.HasPrefix(k, path+".")) {
+			delete(p.pending, k)
+			delete(p.partial, k)
+			delete(p.stage, k)
+			removed = true
+		}
+	}
+
+	return removed
+}
+
+// progressNodeStarted reports that the step of a node is about to run
+func progressNodeStarted(ctx context.Context, id string, e ExecutionNode) {
+	p, ok := progressFromContext(ctx)
+
+	if !ok {
+		track.Update(ctx, id, e.Describe(), 0)
+		return
+	}
+
+	p.mutex.Lock()
+	p.stage[nodePath(ctx)] = e.Describe()
+	p.update(ctx, e.Describe())
+	p.mutex.Unlock()
+}
+
+// progressNodeFinished marks a node and, when its result came from the cache,
+// all the nodes beneath it as done
+func progressNodeFinished(ctx context.Context, id string, e ExecutionNode, message string, elapsed time.Duration, cacheHit bool) {
+	p, ok := progressFromContext(ctx)
+
+	if !ok {
+		track.Update(ctx, id, message, 0)
+		return
+	}
+
+	path := nodePath(ctx)
+
+	p.mutex.Lock()
+	p.remove(path, cacheHit)
+	p.update(ctx, message)
+	p.mutex.Unlock()
+
+	if !cacheHit {
+		recordStepTiming(ctx, stepTimingKey(e), elapsed)
+	}
+}
+
+// progressSharedUsed marks the place a shared result was used in as done,
+// along with anything beneath it when the node there was a second one with
+// the same id
+func progressSharedUsed(ctx context.Context, message string) {
+	p, ok := progressFromContext(ctx)
+
+	if !ok {
+		return
+	}
+
+	p.mutex.Lock()
+	if p.remove(nodePath(ctx), true) {
+		p.update(ctx, message)
+	}
+	p.mutex.Unlock()
+}
+
+// progressEntities reports how many of the entities of a data fetch are done
+func progressEntities(ctx context.Context, done int, total int) {
+	p, ok := progressFromContext(ctx)
+
+	if !ok || total == 0 {
+		return
+	}
+
+	path := nodePath(ctx)
+
+	p.mutex.Lock()
+	p.partial[path] = float64(done) / float64(total)
+	p.update(ctx, fmt.Sprintf("%s (%d of %d)", p.stage[path], done, total))
+	p.mutex.Unlock()
+}
+
+// recordStepTiming keeps a moving average of how long each step takes
+func recordStepTiming(ctx context.Context, key string, elapsed time.Duration) {
+	ms := float64(elapsed) / float64(time.Millisecond)
+
+	if value, err := platform.CacheGet(ctx, key); err == nil {
+		if previous, err := strconv.ParseFloat(string(value), 64); err == nil {
+			ms = previous*(1-stepTimingWeight) + ms*stepTimingWeight
+		}
+	}
+
+	platform.CacheSet(ctx, key, []byte(strconv.FormatFloat(ms, 'f', 0, 64)), 0)
+}
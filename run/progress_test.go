@@ -0,0 +1,38 @@
+package run
+
+import (
+	"testing"
+	"time"
+)
+
+func TestProgressEtaAllowsForParallelChildren(t *testing.T) {
+	ctx := testContext(t)
+
+	defer func(n int, d time.Duration) { MaxParallelChildren, defaultStepEstimate = n, d }(MaxParallelChildren, defaultStepEstimate)
+	MaxParallelChildren, defaultStepEstimate = 2, time.Second
+
+	e := testNode("root", 0, testNode("a", 0), testNode("b", 0), testNode("c", 0, testNode("d", 0), testNode("e", 0)))
+	_, p := withRunProgress(ctx, "run", e)
+
+	// The root, then a, b and c two at a time with d and e running before c
+	if eta := p.details("").EtaSeconds; eta != 3 {
+		t.Errorf("eta = %vs, want 3s", eta)
+	}
+}
+
+func TestProgressCompletesWithSharedNodes(t *testing.T) {
+	ctx := testContext(t)
+
+	shared := testNode("shared", 0, testNode("leaf", 0))
+	shared.Id = "x"
+	e := testNode("root", 0, shared, ExecutionNode{Ref: "x"}, ExecutionNode{Ref: "x"})
+
+	ctx, p := withRunProgress(ctx, "run", e)
+	e.Execute(ctx, "run", "")
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if len(p.pending) != 0 {
+		t.Errorf("nodes still pending after the run: %v", p.pending)
+	}
+}
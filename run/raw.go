@@ -0,0 +1,235 @@
+package run
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+var errUnreadableResult = errors.New("The stored result could not be read")
+
+// maxRawPageSize bounds the entities returned on a page of raw data
+const maxRawPageSize = 5000
+
+// rawFilter selects the part of a run's raw data a client asked for. Entities
+// and fields are repeated query parameters so that names may contain commas.
+type rawFilter struct {
+	Entities map[string]bool
+	Fields   map[string]bool
+	Start    time.Time
+	End      time.Time
+	Cursor   int
+	Limit    int
+	NDJSON   bool
+}
+
+func stringSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+
+	return set
+}
+
+// parseRawFilter reads the entity, field, start, end, cursor, limit and
+// format query parameters
+func parseRawFilter(r *http.Request) (rawFilter, error) {
+	var f rawFilter
+	var err error
+
+	r.ParseForm()
+
+	f.Entities = stringSet(r.Form["entity"])
+	f.Fields = stringSet(r.Form["field"])
+
+	if s := r.FormValue("start"); s != "" {
+		if f.Start, err = parseAsOf(s); err != nil {
+			return f, errors.New("Invalid start date " + s)
+		}
+	}
+
+	if s := r.FormValue("end"); s != "" {
+		if f.End, err = parseAsOf(s); err != nil {
+			return f, errors.New("Invalid end date " + s)
+		}
+	}
+
+	if s := r.FormValue("cursor"); s != "" {
+		if f.Cursor, err = strconv.Atoi(s); err != nil || f.Cursor < 0 {
+			return f, errors.New("Invalid cursor " + s)
+		}
+	}
+
+	if s := r.FormValue("limit"); s != "" {
+		if f.Limit, err = strconv.Atoi(s); err != nil || f.Limit < 1 || f.Limit > maxRawPageSize {
+			return f, errors.New("The limit must be between 1 and " + strconv.Itoa(maxRawPageSize))
+		}
+	}
+
+	switch format := r.FormValue("format"); format {
+	case "", "json":
+	case "ndjson":
+		f.NDJSON = true
+	default:
+		return f, errors.New("Unknown format " + format)
+	}
+
+	return f, nil
+}
+
+// isSet tells whether the client asked for anything but the whole result
+func (f rawFilter) isSet() bool {
+	return f.Entities != nil || f.Fields != nil || !f.Start.IsZero() || !f.End.IsZero() || f.Cursor > 0 || f.Limit > 0 || f.NDJSON
+}
+
+func (f rawFilter) inWindow(t time.Time) bool {
+	return (f.Start.IsZero() || !t.Before(f.Start)) && (f.End.IsZero() || !t.After(f.End))
+}
+
+func (f rawFilter) filterEntity(e SingleEntityData) SingleEntityData {
+	filtered := SingleEntityData{Meta: e.Meta, Category: e.Category, Data: make([]Series, 0, len(e.Data))}
+
+	for _, v := range e.Data {
+		if f.Fields != nil && !f.Fields[v.Meta.Label] && !f.Fields[v.Meta.VendorCode] {
+			continue
+		}
+
+		if !f.Start.IsZero() || !f.End.IsZero() {
+			points := make([]DataPoint, 0, len(v.Data))
+			for _, p := range v.Data {
+				if f.inWindow(p.Time) {
+					points = append(points, p)
+				}
+			}
+			v.Data = points
+		}
+
+		filtered.Data = append(filtered.Data, v)
+	}
+
+	if !f.Start.IsZero() || !f.End.IsZero() {
+		points := make([]CategoryPoint, 0, len(e.Category.Data))
+		for _, p := range e.Category.Data {
+			if f.inWindow(p.Time) {
+				points = append(points, p)
+			}
+		}
+		filtered.Category.Data = points
+	}
+
+	return filtered
+}
+
+// apply returns the page of entities selected by the filter, along with the
+// cursor of the next page, which is empty on the last one
+func (f rawFilter) apply(m MultiEntityData) (MultiEntityData, string) {
+	selected := make([]SingleEntityData, 0)
+
+	for _, v := range m.EntityData {
+		if f.Entities == nil || f.Entities[v.Meta.Name] || f.Entities[v.Meta.UniqueId] {
+			selected = append(selected, v)
+		}
+	}
+
+	next := ""
+
+	if f.Cursor >= len(selected) {
+		selected = selected[:0]
+	} else {
+		selected = selected[f.Cursor:]
+	}
+
+	if f.Limit > 0 && len(selected) > f.Limit {
+		selected = selected[:f.Limit]
+		next = strconv.Itoa(f.Cursor + f.Limit)
+	}
+
+	m.EntityData = make([]SingleEntityData, len(selected))
+	for i, v := range selected {
+		m.EntityData[i] = f.filterEntity(v)
+	}
+
+	return m, next
+}
+
+// rawHeader is the first line of an NDJSON result, so that the title and
+// error of the result aren't lost
+type rawHeader struct {
+	Title               string
+	Error               string `json:",omitempty"`
+	GraphicalPreference string `json:",omitempty"`
+}
+
+// writeNDJSON writes the header and then an entity per line, flushing as it
+// goes so that clients can render the first entities before the last ones
+// arrive
+func writeNDJSON(w io.Writer, m MultiEntityData) error {
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+
+	if err := enc.Encode(rawHeader{m.Title, m.Error, m.GraphicalPreference}); err != nil {
+		return err
+	}
+
+	for _, v := range m.EntityData {
+		if err := enc.Encode(v); err != nil {
+			return err
+		}
+
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	return nil
+}
+
+// writeRawData writes the part of a run's raw data the request asks for. The
+// cursor of the next page, if any, is in the X-Next-Cursor header.
+func writeRawData(w http.ResponseWriter, r *http.Request, data []byte) error {
+	f, err := parseRawFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil
+	}
+
+	if !f.isSet() {
+		if !json.Valid(data) {
+			http.Error(w, errUnreadableResult.Error(), http.StatusInternalServerError)
+			return errUnreadableResult
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, err := w.Write(append(data, '\n'))
+		return err
+	}
+
+	var med MultiEntityData
+	if err := json.Unmarshal(data, &med); err != nil {
+		http.Error(w, errUnreadableResult.Error(), http.StatusInternalServerError)
+		return err
+	}
+
+	med, next := f.apply(med)
+
+	if next != "" {
+		w.Header().Set("X-Next-Cursor", next)
+	}
+
+	if f.NDJSON {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		return writeNDJSON(w, med)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+
+	return json.NewEncoder(w).Encode(med)
+}
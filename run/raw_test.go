@@ -0,0 +1,176 @@
+package run
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/db"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+)
+
+func TestParseRawFilter(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		query string
+		want  rawFilter
+		fails bool
+	}{
+		{"", rawFilter{}, false},
+		{"entity=AAPL&entity=Alphabet,+Inc.", rawFilter{Entities: map[string]bool{"AAPL": true, "Alphabet, Inc.": true}}, false},
+		{"field=Price", rawFilter{Fields: map[string]bool{"Price": true}}, false},
+		{"start=2026-10-01&end=2026-10-05", rawFilter{Start: day(1), End: day(5)}, false},
+		{"cursor=10&limit=5", rawFilter{Cursor: 10, Limit: 5}, false},
+		{"format=json", rawFilter{}, false},
+		{"format=ndjson", rawFilter{NDJSON: true}, false},
+		{"start=yesterday", rawFilter{}, true},
+		{"end=2026-13-01", rawFilter{}, true},
+		{"cursor=-1", rawFilter{}, true},
+		{"cursor=abc", rawFilter{}, true},
+		{"limit=0", rawFilter{}, true},
+		{"limit=5001", rawFilter{}, true},
+		{"format=xml", rawFilter{}, true},
+	}
+
+	for _, v := range tests {
+		f, err := parseRawFilter(httptest.NewRequest("GET", "/raw?"+v.query, nil))
+
+		if v.fails {
+			if err == nil {
+				t.Errorf("%q should fail", v.query)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%q failed: %s", v.query, err)
+		} else if !reflect.DeepEqual(f, v.want) {
+			t.Errorf("%q = %+v, want %+v", v.query, f, v.want)
+		}
+	}
+}
+
+func TestRawFilterApply(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC)
+	}
+	entity := func(name string, id string) SingleEntityData {
+		return SingleEntityData{
+			Meta: EntityMeta{Name: name, UniqueId: id},
+			Data: []Series{
+				{Meta: SeriesMeta{Label: "Price"}, Data: []DataPoint{{day(1), 1}, {day(2), 2}, {day(3), 3}}},
+				{Meta: SeriesMeta{Label: "Volume", VendorCode: "VOL"}, Data: []DataPoint{{day(1), 10}}},
+			},
+			Category: CategorySeries{Data: []CategoryPoint{{day(1), 1}, {day(3), 1}}},
+		}
+	}
+	m := MultiEntityData{EntityData: []SingleEntityData{entity("A", "a"), entity("B", "b"), entity("C", "c"), entity("D", "d")}}
+
+	tests := []struct {
+		name     string
+		filter   rawFilter
+		entities []string
+		fields   []string
+		points   int
+		next     string
+	}{
+		{"everything", rawFilter{}, []string{"A", "B", "C", "D"}, []string{"Price", "Volume"}, 3, ""},
+		{"by name or id", rawFilter{Entities: map[string]bool{"B": true, "d": true}}, []string{"B", "D"}, []string{"Price", "Volume"}, 3, ""},
+		{"by label", rawFilter{Fields: map[string]bool{"Price": true}}, []string{"A", "B", "C", "D"}, []string{"Price"}, 3, ""},
+		{"by vendor code", rawFilter{Fields: map[string]bool{"VOL": true}}, []string{"A", "B", "C", "D"}, []string{"Volume"}, 1, ""},
+		{"window", rawFilter{Start: day(2), End: day(3)}, []string{"A", "B", "C", "D"}, []string{"Price", "Volume"}, 2, ""},
+		{"first page", rawFilter{Limit: 3}, []string{"A", "B", "C"}, []string{"Price", "Volume"}, 3, "3"},
+		{"last page", rawFilter{Cursor: 3, Limit: 3}, []string{"D"}, []string{"Price", "Volume"}, 3, ""},
+		{"exact page", rawFilter{Cursor: 2, Limit: 2}, []string{"C", "D"}, []string{"Price", "Volume"}, 3, ""},
+		{"past the end", rawFilter{Cursor: 10}, []string{}, nil, 0, ""},
+		{"page of a subset", rawFilter{Entities: map[string]bool{"a": true, "c": true, "d": true}, Cursor: 1, Limit: 1}, []string{"C"}, []string{"Price", "Volume"}, 3, "2"},
+	}
+
+	for _, v := range tests {
+		got, next := v.filter.apply(m)
+
+		names := make([]string, 0)
+		for _, e := range got.EntityData {
+			names = append(names, e.Meta.Name)
+		}
+		if !reflect.DeepEqual(names, v.entities) {
+			t.Errorf("%s: entities = %v, want %v", v.name, names, v.entities)
+		}
+		if next != v.next {
+			t.Errorf("%s: next = %q, want %q", v.name, next, v.next)
+		}
+
+		if len(got.EntityData) == 0 {
+			continue
+		}
+
+		e := got.EntityData[0]
+		labels := make([]string, 0)
+		for _, s := range e.Data {
+			labels = append(labels, s.Meta.Label)
+		}
+		if !reflect.DeepEqual(labels, v.fields) {
+			t.Errorf("%s: fields = %v, want %v", v.name, labels, v.fields)
+		}
+		if len(e.Data[0].Data) != v.points {
+			t.Errorf("%s: %d points, want %d", v.name, len(e.Data[0].Data), v.points)
+		}
+	}
+
+	// The window applies to the categories too, and the source isn't changed
+	got, _ := rawFilter{Start: day(2)}.apply(m)
+	if len(got.EntityData[0].Category.Data) != 1 || len(m.EntityData[0].Category.Data) != 2 {
+		t.Errorf("categories = %v, source = %v", got.EntityData[0].Category.Data, m.EntityData[0].Category.Data)
+	}
+}
+
+func TestWriteRawDataFailsOnBadData(t *testing.T) {
+	for _, query := range []string{"", "?limit=1"} {
+		w := httptest.NewRecorder()
+		err := writeRawData(w, httptest.NewRequest("GET", "/raw"+query, nil), []byte("{not json"))
+
+		if err == nil || w.Code != http.StatusInternalServerError {
+			t.Errorf("%q got %d, %v, want a 500 and an error", query, w.Code, err)
+		}
+	}
+}
+
+func TestWriteNDJSONKeepsTitleAndError(t *testing.T) {
+	m := MultiEntityData{Title: "Prices", Error: "A failed", EntityData: []SingleEntityData{{Meta: EntityMeta{Name: "B"}}}}
+
+	var buf bytes.Buffer
+	if err := writeNDJSON(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || lines[0] != `{"Title":"Prices","Error":"A failed"}` {
+		t.Errorf("got %q", lines)
+	}
+}
+
+func TestRawHandlerWithoutStoredResult(t *testing.T) {
+	ctx := platform.WithServices(context.Background(), platform.Local(nil))
+
+	id := db.DatabaseInsert(ctx, db.RunTree, &TreeDummy{Tree: []byte(`{}`)}, "")
+	createRunRecord(ctx, id)
+	setRunState(ctx, id, RunRunning, "", "")
+	setRunState(ctx, id, RunCompleted, "", "")
+
+	for _, query := range []string{"", "&limit=1"} {
+		w := httptest.NewRecorder()
+		RawHandler(ctx, w, httptest.NewRequest("GET", "/raw?:query="+id+query, nil))
+
+		if w.Code != http.StatusNotFound {
+			t.Errorf("%q got %d, want a 404", query, w.Code)
+		}
+	}
+}
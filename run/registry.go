@@ -0,0 +1,144 @@
+package run
+
+import (
+	"errors"
+	"regexp"
+	"sync"
+
+	"github.com/AlphaHat/gcp-alpha-hat/component"
+)
+
+type ArgumentKind string
+
+const (
+	ArgNumber   ArgumentKind = "number"
+	ArgDate     ArgumentKind = "date"
+	ArgField    ArgumentKind = "field"
+	ArgUniverse ArgumentKind = "universe"
+	ArgText     ArgumentKind = "text"
+)
+
+// ArgumentSchema describes one argument a step expects
+type ArgumentSchema struct {
+	Name     string       `json:"name"`
+	Kind     ArgumentKind `json:"kind"`
+	Optional bool         `json:"optional"`
+}
+
+type stepKey struct {
+	Type component.MajorType
+	Name string
+}
+
+// StepRegistry holds the computation steps that trees can use. Steps are
+// indexed by major type and name, and the first step registered for a major
+// type is used when no name matches.
+type StepRegistry struct {
+	mutex    sync.RWMutex
+	steps    []ComputationStep
+	byName   map[stepKey]int
+	fallback map[component.MajorType]int
+}
+
+func NewStepRegistry() *StepRegistry {
+	return &StepRegistry{
+		steps:    make([]ComputationStep, 0),
+		byName:   make(map[stepKey]int),
+		fallback: make(map[component.MajorType]int),
+	}
+}
+
+// Steps is the registry used when executing trees
+var Steps = NewStepRegistry()
+
+// RegisterStep adds a step to the registry used when executing trees. Packages
+// providing their own steps should call it from an init function.
+func RegisterStep(step ComputationStep) error {
+	return Steps.Register(step)
+}
+
+func (r *StepRegistry) Register(step ComputationStep) error {
+	if step.ComputeFn == nil {
+		return errors.New("No compute function for the step " + string(step.Type) + " " + step.Name)
+	}
+
+	if step.Arguments == nil {
+		step.Arguments = make([]ArgumentSchema, 0)
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	key := stepKey{step.Type, step.Name}
+
+	if _, ok := r.byName[key]; ok {
+		return errors.New("A computation step is already registered for " + string(step.Type) + " " + step.Name)
+	}
+
+	r.steps = append(r.steps, step)
+	r.byName[key] = len(r.steps) - 1
+
+	if _, ok := r.fallback[step.Type]; !ok {
+		r.fallback[step.Type] = len(r.steps) - 1
+	}
+
+	return nil
+}
+
+// Lookup finds the step with exactly this major type and name
+func (r *StepRegistry) Lookup(major component.MajorType, name string) (ComputationStep, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if i, ok := r.byName[stepKey{major, name}]; ok {
+		return r.steps[i], true
+	}
+
+	return ComputationStep{}, false
+}
+
+// LookupOrDefault finds the step for the major type and name, falling back to
+// the first step of the major type. exact is false when the fallback was used.
+func (r *StepRegistry) LookupOrDefault(major component.MajorType, c []component.QueryComponent) (step ComputationStep, exact bool, found bool) {
+	if len(c) > 0 {
+		if step, ok := r.Lookup(major, c[0].QueryComponentCanonicalName); ok {
+			return step, true, true
+		}
+	}
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	if i, ok := r.fallback[major]; ok {
+		return r.steps[i], false, true
+	}
+
+	return ComputationStep{}, false, false
+}
+
+// All returns the steps in the order they were registered
+func (r *StepRegistry) All() []ComputationStep {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	steps := make([]ComputationStep, len(r.steps))
+	copy(steps, r.steps)
+
+	return steps
+}
+
+var placeholderRegexp = regexp.MustCompile(`\{(Number|Date)\}`)
+
+// Placeholders lists the parameters in the name of a step such as the
+// {Number} in "Lag {Number}"
+func (s ComputationStep) Placeholders() []string {
+	return placeholderRegexp.FindAllString(s.Name, -1)
+}
+
+// UnlimitedChildren is the MaxChildren of steps that take any number of children
+const UnlimitedChildren = -1
+
+// AcceptsChildren reports whether a node of this step can have n children
+func (s ComputationStep) AcceptsChildren(n int) bool {
+	return n >= s.MinChildren && (s.MaxChildren == UnlimitedChildren || n <= s.MaxChildren)
+}
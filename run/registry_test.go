@@ -0,0 +1,120 @@
+package run
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AlphaHat/gcp-alpha-hat/component"
+)
+
+func TestBuiltInStepsDeclareThemselves(t *testing.T) {
+	for _, v := range ComputationsSteps {
+		name := string(v.Type) + " " + v.Name
+
+		if v.Description == "" {
+			t.Errorf("%s has no description", name)
+		}
+
+		numbers := 0
+		for _, arg := range v.Arguments {
+			if arg.Kind == ArgNumber {
+				numbers++
+			}
+		}
+		if numbers != len(v.Placeholders()) {
+			t.Errorf("%s declares %d number arguments for %d placeholders", name, numbers, len(v.Placeholders()))
+		}
+
+		if want := builtInMaxChildren(v); v.MaxChildren != want {
+			t.Errorf("%s takes up to %d children, want %d", name, v.MaxChildren, want)
+		}
+	}
+}
+
+// builtInMaxChildren is 0 for the universes, 2 for the regressions, unlimited
+// for the steps combining any number of inputs and 1 for every other step
+func builtInMaxChildren(s ComputationStep) int {
+	switch {
+	case s.Type == component.GetUniverse || s.Type == component.CustomQuandlCode:
+		return 0
+	case s.Name == "Regression" || s.Name == "Alpha using {Number}-Month Regression":
+		return 2
+	case s.Type == component.CombineData:
+		return UnlimitedChildren
+	}
+
+	return 1
+}
+
+func TestStepChildren(t *testing.T) {
+	tests := []struct {
+		major    component.MajorType
+		name     string
+		children int
+		accepts  bool
+	}{
+		{component.GetUniverse, "", 0, true},
+		{component.GetUniverse, "", 1, false},
+		{component.GetData, "", 0, false},
+		{component.GetData, "", 1, true},
+		{component.GetData, "", 2, false},
+		{component.TimeSeriesFormula, "", 2, false},
+		{component.TimeSeriesTransformation, "Resample To Lowest Frequency", 3, false},
+		{component.CombineData, "Union", 1, false},
+		{component.CombineData, "Union", 3, true},
+		{component.CombineData, "Regression", 1, false},
+		{component.CombineData, "Regression", 2, true},
+		{component.CombineData, "Regression", 3, false},
+	}
+
+	for _, v := range tests {
+		step, ok := Steps.Lookup(v.major, v.name)
+		if !ok {
+			t.Errorf("no step %s %s", v.major, v.name)
+			continue
+		}
+
+		if got := step.AcceptsChildren(v.children); got != v.accepts {
+			t.Errorf("%s %s AcceptsChildren(%d) = %v, want %v", v.major, v.name, v.children, got, v.accepts)
+		}
+	}
+}
+
+func TestRegisterDefaults(t *testing.T) {
+	r := NewStepRegistry()
+
+	if err := r.Register(ComputationStep{Type: "Custom"}); err == nil {
+		t.Error("a step without a compute function should not register")
+	}
+
+	step := ComputationStep{Type: "Custom", ComputeFn: WrapNoArguments(flatten)}
+	if err := r.Register(step); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.Register(step); err == nil {
+		t.Error("the same step should not register twice")
+	}
+
+	got, ok := r.Lookup("Custom", "")
+	if !ok {
+		t.Fatal("the step was not registered")
+	}
+	if got.Arguments == nil || got.Description != "" || got.MinChildren != 0 || got.MaxChildren != 0 {
+		t.Errorf("got %+v, want what the step declared", got)
+	}
+}
+
+func TestValidateRejectsExtraChildren(t *testing.T) {
+	regression := []component.QueryComponent{{QueryComponentCanonicalName: "Regression"}}
+	child := ExecutionNode{Type: string(component.GetUniverse)}
+
+	e := ExecutionNode{Type: string(component.CombineData), Arguments: regression, Children: []ExecutionNode{child, child}}
+	if nodeErr, ok := validateNode("root", e); !ok {
+		t.Errorf("a regression of 2 children fails with %s", nodeErr.Error)
+	}
+
+	e.Children = append(e.Children, child)
+	if nodeErr, ok := validateNode("root", e); ok || !strings.Contains(nodeErr.Error, "2 children but has 3") {
+		t.Errorf("a regression of 3 children got %q", nodeErr.Error)
+	}
+}
@@ -7,9 +7,11 @@ import (
 	"fmt"
 	"math"
 	"net/http"
+	"net/url"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/AlphaHat/gcp-alpha-hat/build"
@@ -17,13 +19,13 @@ import (
 	"github.com/AlphaHat/gcp-alpha-hat/data"
 	"github.com/AlphaHat/gcp-alpha-hat/db"
 	"github.com/AlphaHat/gcp-alpha-hat/list"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+	"github.com/AlphaHat/gcp-alpha-hat/platform/log"
 	"github.com/AlphaHat/gcp-alpha-hat/term"
 	"github.com/AlphaHat/gcp-alpha-hat/timeseries"
 	"github.com/AlphaHat/gcp-alpha-hat/track"
 	"github.com/AlphaHat/gcp-alpha-hat/zstats"
 	"github.com/AlphaHat/regression"
-	"google.golang.org/appengine/log"
-	"google.golang.org/appengine/taskqueue"
 )
 
 // Type Definitions
@@ -91,12 +93,18 @@ type MultiEntityData struct {
 	Title               string
 	Error               string
 	GraphicalPreference string
+	// Incomplete is set when some of the data couldn't be fetched
+	Incomplete bool `json:",omitempty"`
 }
 
+// ExecutionNode is a step of a query. A node with an Id can be used by other
+// nodes by adding a child with a Ref to that id instead of repeating it.
 type ExecutionNode struct {
 	Type      string
 	Arguments []component.QueryComponent
 	Children  []ExecutionNode
+	Id        string `json:",omitempty"`
+	Ref       string `json:",omitempty"`
 }
 
 type EntityPlusDataPoint struct {
@@ -293,7 +301,26 @@ func (c CategorySeries) LookupCategory(date time.Time) string {
 //	return a
 //}
 
+// GetTitle describes the node, resolving references against the nodes of
+// the subtree. TitleWith resolves them against the whole tree.
 func (e ExecutionNode) GetTitle() string {
+	return e.TitleWith(e.NodeIds())
+}
+
+// TitleWith is GetTitle with references resolved against nodes. A reference
+// that can't be resolved, or that is part of a cycle, is titled with its id.
+func (e ExecutionNode) TitleWith(nodes map[string]ExecutionNode) string {
+	return e.title(nodes, 0)
+}
+
+func (e ExecutionNode) title(nodes map[string]ExecutionNode, depth int) string {
+	if e.Ref != "" {
+		if target, ok := nodes[e.Ref]; ok && depth < len(nodes)+1 {
+			return target.title(nodes, depth+1)
+		}
+		return e.Ref
+	}
+
 	if len(e.Children) == 0 {
 		if len(e.Arguments) > 0 {
 			return e.Arguments[0].QueryComponentOriginalString
@@ -305,25 +332,32 @@ func (e ExecutionNode) GetTitle() string {
 		//if e.Type == string(component.GetUniverse) ||
 		//	e.Type == string(component.GetData) ||
 		//	e.Type == string(component.Classification) {
-		//	return e.Children[0].GetTitle() + " " + e.Arguments[0].QueryComponentOriginalString
+		//	return e.Children[0].title(nodes, depth) + " " + e.Arguments[0].QueryComponentOriginalString
 		//} else {
-		//	return e.Arguments[0].QueryComponentOriginalString + " " + e.Children[0].GetTitle()
+		//	return e.Arguments[0].QueryComponentOriginalString + " " + e.Children[0].title(nodes, depth)
 		//}
 		if len(e.Arguments) > 1 {
-			return e.Children[0].GetTitle() + " → " + e.Arguments[0].QueryComponentOriginalString + " " + e.Arguments[1].QueryComponentOriginalString
+			return e.Children[0].title(nodes, depth) + " → " + e.Arguments[0].QueryComponentOriginalString + " " + e.Arguments[1].QueryComponentOriginalString
 		}
-		return e.Children[0].GetTitle() + " → " + e.Arguments[0].QueryComponentOriginalString
+		return e.Children[0].title(nodes, depth) + " → " + e.Arguments[0].QueryComponentOriginalString
 	}
 
-	if len(e.Children) == 2 {
-		if e.Arguments[0].QueryComponentOriginalString == "Union" {
-			return e.Children[0].GetTitle() + ", " + e.Children[1].GetTitle()
-		} else {
-			return e.Arguments[0].QueryComponentOriginalString + " of (" + e.Children[0].GetTitle() + ") against (" + e.Children[1].GetTitle() + ")"
-		}
+	titles := make([]string, len(e.Children))
+	for i, v := range e.Children {
+		titles[i] = v.title(nodes, depth)
 	}
 
-	return ""
+	if len(e.Arguments) == 0 || e.Arguments[0].QueryComponentOriginalString == "Union" {
+		return strings.Join(titles, ", ")
+	}
+
+	// The first child is combined against each of the others in turn
+	others := "(" + strings.Join(titles[1:], "), (") + ")"
+	if len(titles) > 2 {
+		others = "(" + strings.Join(titles[1:len(titles)-1], "), (") + ") and (" + titles[len(titles)-1] + ")"
+	}
+
+	return e.Arguments[0].QueryComponentOriginalString + " of (" + titles[0] + ") against " + others
 }
 
 func (e *ExecutionNode) ParseTree(ctx context.Context, terms *term.TermData) {
@@ -420,33 +454,116 @@ func parseArguments(ctx context.Context, c []component.QueryComponent, terms *te
 }
 
 func (e ExecutionNode) Execute(ctx context.Context, id string, Title string) MultiEntityData {
+	d, ok := dagFromContext(ctx)
+	if !ok {
+		ctx, d = withDAG(ctx, e)
+	}
+
+	if d.cycleErr != nil {
+		return MultiEntityData{Title: e.TitleWith(d.nodes), Error: d.cycleErr.Error()}
+	}
+
+	if e.Ref == "" && e.Id == "" {
+		return e.execute(ctx, id, Title)
+	}
+
+	nodeId := e.Id
+	node := e
+
+	if e.Ref != "" {
+		nodeId = e.Ref
+		node, ok = d.nodes[e.Ref]
+
+		if !ok {
+			return MultiEntityData{Title: e.TitleWith(d.nodes), Error: "No node with id " + e.Ref}
+		}
+	}
+
+	med := d.shared(ctx, id, nodeId, node)
+	progressSharedUsed(ctx, "Using shared result "+nodeId)
+
+	if Title != "" {
+		med.Title = Title
+	}
+
+	return med
+}
+
+func (e ExecutionNode) execute(ctx context.Context, id string, Title string) MultiEntityData {
 	stepFn := findComputationStep(e.Type, e.Arguments)
 
-	var data []MultiEntityData = make([]MultiEntityData, len(e.Children), len(e.Children))
+	var nodes map[string]ExecutionNode
+	if d, ok := dagFromContext(ctx); ok {
+		nodes = d.nodes
+	}
 
-	for i, v := range e.Children {
-		data[i] = v.Execute(ctx, id, "")
+	if err := ctx.Err(); err != nil {
+		return MultiEntityData{Title: e.TitleWith(nodes), Error: err.Error()}
 	}
 
-	if len(e.Arguments) > 0 {
-		track.Update(ctx, id, e.Type+": "+e.Arguments[0].QueryComponentOriginalString, 0)
-		log.Infof(ctx, "Execute: %s : %s", e.Type, e.Arguments[0].QueryComponentOriginalString)
-	} else {
-		track.Update(ctx, id, e.Type, 0)
-		log.Infof(ctx, "Execute: %s", e.Type)
+	nodeStart := time.Now()
+
+	hash := e.Hash(ctx, nodes)
+
+	if med, ok := lookupMemo(ctx, hash); ok {
+		progressNodeFinished(ctx, id, e, "Reused cached result: "+e.TitleWith(nodes), 0, true)
+		log.Infof(ctx, "Execute: %s reused cached result %s", e.Type, hash)
+		recordTrace(ctx, newTraceNode(ctx, e, nil), nodeStart, nodeStart, med, true)
+		recordNodeEvent(ctx, id, e, med, true)
+
+		if Title == "" {
+			med.Title = e.TitleWith(nodes)
+		} else {
+			med.Title = Title
+		}
+
+		return med
 	}
 
+	data := e.executeChildren(ctx, id)
+
+	progressNodeStarted(ctx, id, e)
+	log.Infof(ctx, "Execute: %s", e.Describe())
+
+	traceNode := newTraceNode(ctx, e, data)
+	timer := time.Now()
+
 	var med MultiEntityData
 	if stepFn != nil {
-		timer := time.Now()
-		med = stepFn(e.Arguments)(ctx, data)
+		stepCtx, cancel := context.WithTimeout(ctx, StepTimeout)
+		med = e.runStep(stepCtx, stepFn, data)
+		cancel()
 		log.Infof(ctx, "time taken was %v", time.Since(timer))
+
+		// Anything a step returns after being stopped may be incomplete
+		if ctx.Err() != nil {
+			med = stoppedData(ctx)
+		} else if stepCtx.Err() == context.DeadlineExceeded {
+			med = MultiEntityData{Error: stepTimeoutError(e)}
+			noteTimeout(ctx, e.Describe(), med.Error)
+		}
 	} else if len(data) > 0 {
 		med = data[0]
 	}
 
+	// A result built on incomplete data is incomplete too
+	for _, v := range data {
+		if v.Incomplete {
+			med.Incomplete = true
+		}
+	}
+
+	if med.Error != "" {
+		noteFailure(ctx, e.Describe(), med.Error)
+	}
+
+	recordTrace(ctx, traceNode, nodeStart, timer, med, false)
+	progressNodeFinished(ctx, id, e, "Finished "+e.Describe(), time.Since(timer), false)
+	recordNodeEvent(ctx, id, e, med, false)
+	storeMemo(ctx, hash, med)
+
 	if Title == "" {
-		med.Title = e.GetTitle()
+		med.Title = e.TitleWith(nodes)
 	} else {
 		med.Title = Title
 	}
@@ -458,6 +575,92 @@ func (e ExecutionNode) Execute(ctx context.Context, id string, Title string) Mul
 	return med
 }
 
+// Describe names the step of the node for progress messages and logs
+func (e ExecutionNode) Describe() string {
+	if len(e.Arguments) > 0 {
+		return e.Type + ": " + e.Arguments[0].QueryComponentOriginalString
+	}
+
+	return e.Type
+}
+
+// runStep turns a panic inside a step into an error on the result so that a
+// single bad step doesn't take down the sibling nodes running alongside it
+func (e ExecutionNode) runStep(ctx context.Context, stepFn func([]component.QueryComponent) StepFnType, data []MultiEntityData) (med MultiEntityData) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf(ctx, "Execute: %s panicked: %v", e.Describe(), r)
+			med = MultiEntityData{Error: fmt.Sprintf("%s failed: %v", e.Describe(), r)}
+		}
+	}()
+
+	return stepFn(e.Arguments)(ctx, data)
+}
+
+// MaxParallelChildren bounds how many nodes of a run are executed at once
+var MaxParallelChildren = 4
+
+type limiterKey struct{}
+
+// withRunLimiter shares MaxParallelChildren-1 extra goroutines between all the
+// nodes of a run, so that concurrency doesn't grow with the depth of the tree
+func withRunLimiter(ctx context.Context) (context.Context, chan struct{}) {
+	if slots, ok := ctx.Value(limiterKey{}).(chan struct{}); ok {
+		return ctx, slots
+	}
+
+	size := MaxParallelChildren - 1
+	if size < 0 {
+		size = 0
+	}
+
+	slots := make(chan struct{}, size)
+
+	return context.WithValue(ctx, limiterKey{}, slots), slots
+}
+
+// executeChildren runs the children of a node concurrently. The children do not
+// depend on each other so only the result order needs to be kept. A child
+// that finds every goroutine of the run taken is executed in this one, which
+// keeps nodes waiting on their children from holding up the rest of the run.
+func (e ExecutionNode) executeChildren(ctx context.Context, id string) []MultiEntityData {
+	var data []MultiEntityData = make([]MultiEntityData, len(e.Children), len(e.Children))
+
+	ctx, slots := withRunLimiter(ctx)
+	var wg sync.WaitGroup
+
+	for i, v := range e.Children {
+		if err := ctx.Err(); err != nil {
+			data[i] = MultiEntityData{Error: err.Error()}
+			continue
+		}
+
+		childCtx := withNodePath(ctx, childPath(ctx, i))
+
+		if i == len(e.Children)-1 {
+			data[i] = v.Execute(childCtx, id, "")
+			continue
+		}
+
+		select {
+		case slots <- struct{}{}:
+			wg.Add(1)
+			go func(i int, v ExecutionNode, childCtx context.Context) {
+				defer wg.Done()
+				defer func() { <-slots }()
+
+				data[i] = v.Execute(childCtx, id, "")
+			}(i, v, childCtx)
+		default:
+			data[i] = v.Execute(childCtx, id, "")
+		}
+	}
+
+	wg.Wait()
+
+	return data
+}
+
 func createSeriesArray(dates []time.Time, data []float64) []DataPoint {
 	var dp = make([]DataPoint, len(dates))
 
@@ -880,15 +1083,34 @@ type ComputationStep struct {
 	Type          component.MajorType
 	Name          string
 	DefaultString string
+	Description   string
+	Examples      []string
+	Arguments     []ArgumentSchema
+	MinChildren   int
+	MaxChildren   int
 	ArgCheckFn    func(MultiEntityData, []component.QueryComponent) ([]component.QueryComponent, error)
 	ComputeFn     func([]component.QueryComponent) StepFnType
 }
 
+func init() {
+	for _, v := range ComputationsSteps {
+		if err := RegisterStep(v); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// ComputationsSteps are the built in steps. They are added to Steps when the package is initialized.
+// Only the steps that combine any number of inputs take more than two children.
 var ComputationsSteps []ComputationStep = []ComputationStep{
 	ComputationStep{
 		Type:          component.GetUniverse,
 		Name:          "",
 		DefaultString: "",
+		Description:   "Gets the members of a universe",
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Universe", ArgUniverse, false}},
+		MinChildren:   0,
+		MaxChildren:   0,
 		ArgCheckFn:    verifyUniverse,
 		ComputeFn:     WrapUniverseArgument(getUniverse),
 	},
@@ -903,6 +1125,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CustomQuandlCode,
 		Name:          "",
 		DefaultString: "",
+		Description:   "Gets a universe from a custom Quandl code",
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Quandl Code", ArgUniverse, false}},
+		MinChildren:   0,
+		MaxChildren:   0,
 		ArgCheckFn:    verifyQuandlCode,
 		ComputeFn:     WrapUniverseArgument(getUniverse),
 	},
@@ -910,13 +1136,22 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesFormula,
 		Name:          "",
 		DefaultString: "",
+		Description:   "Adds a field computed from a formula over the other fields",
+		Examples:      []string{"val / val[t-1] - 1"},
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Formula", ArgText, false}, ArgumentSchema{"Field Name", ArgText, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyFormula,
-		ComputeFn:     WrapStringArgumentTS2(formulaToFunction),
+		ComputeFn:     computeFormula,
 	},
 	ComputationStep{
 		Type:          component.Ratio,
 		Name:          "",
 		DefaultString: "",
+		Description:   "Divides every other entity by the named entity",
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Entity", ArgText, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyFreeText,
 		ComputeFn:     RatioData,
 	},
@@ -924,6 +1159,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.RemoveData,
 		Name:          "",
 		DefaultString: "",
+		Description:   "Removes a field",
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Field", ArgField, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyRemoveData,
 		ComputeFn:     WrapStringArgumentTS(removeNamedField),
 	},
@@ -931,6 +1170,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.KeepData,
 		Name:          "",
 		DefaultString: "",
+		Description:   "Keeps only a field",
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Field", ArgField, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyRemoveData,
 		ComputeFn:     WrapStringArgumentTS(keepNamedField),
 	},
@@ -938,6 +1181,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.RenameEntity,
 		Name:          "",
 		DefaultString: "",
+		Description:   "Adds text to the name of every entity",
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Text", ArgText, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyRenameEntity,
 		ComputeFn:     WrapStringArgumentTS(renameEntity),
 	},
@@ -945,6 +1192,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSlice,
 		Name:          "",
 		DefaultString: "",
+		Description:   "Keeps the data within a time range",
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Time Range", ArgDate, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyTimeRange,
 		ComputeFn:     TimeSlicer,
 	},
@@ -952,6 +1203,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.GetData,
 		Name:          "",
 		DefaultString: "",
+		Description:   "Gets a field for every member of the universe",
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Field", ArgField, false}, ArgumentSchema{"Time Range", ArgDate, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyDataField,
 		ComputeFn:     WrapUniverseData(GetUniverseData, getData),
 	},
@@ -959,6 +1214,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.GetBulkData,
 		Name:          "",
 		DefaultString: "",
+		Description:   "Gets alternative data for the whole universe in a single query",
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Query", ArgField, false}, ArgumentSchema{"Time Range", ArgDate, false}, ArgumentSchema{"Parameters", ArgText, true}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyDataField,
 		ComputeFn:     GetBulkData,
 	},
@@ -966,6 +1225,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.SetWeights,
 		Name:          "",
 		DefaultString: "",
+		Description:   "Sets the weights of the universe from a field",
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Field", ArgField, false}, ArgumentSchema{"Time Range", ArgDate, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyDataField,
 		ComputeFn:     WrapUniverseData(SetWeights, getData),
 	},
@@ -987,6 +1250,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.GraphicalPreference,
 		Name:          "Scatter",
 		DefaultString: "Scatter",
+		Description:   "Charts the result as a scatter plot",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Scatter", "Scatter"),
 		ComputeFn:     WrapNoArguments(GraphicalPreference("Scatter")),
 	},
@@ -994,6 +1261,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.GraphicalPreference,
 		Name:          "Histogram",
 		DefaultString: "Histogram",
+		Description:   "Charts the result as a histogram",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Histogram", "Histogram"),
 		ComputeFn:     WrapNoArguments(GraphicalPreference("Histogram")),
 	},
@@ -1001,6 +1272,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.GraphicalPreference,
 		Name:          "Column",
 		DefaultString: "Column",
+		Description:   "Charts the result as columns",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Column", "Column"),
 		ComputeFn:     WrapNoArguments(GraphicalPreference("Column")),
 	},
@@ -1008,6 +1283,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.GraphicalPreference,
 		Name:          "Heatmap",
 		DefaultString: "Heatmap",
+		Description:   "Charts the result as a heatmap",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Heatmap", "Heatmap"),
 		ComputeFn:     WrapNoArguments(GraphicalPreference("Heatmap")),
 	},
@@ -1015,6 +1294,11 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Lag {Number}",
 		DefaultString: "Lag 1",
+		Description:   "Moves each value a number of periods later",
+		Examples:      []string{"Lag 1", "Lag 5"},
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Periods", ArgNumber, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Lag {Number}", "Lag 1"),
 		ComputeFn:     WrapNumericalArgumentTS(tsLag),
 	},
@@ -1022,6 +1306,11 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CrossEntityAggregation,
 		Name:          "> {Number}",
 		DefaultString: "> 0",
+		Description:   "Keeps the values above a number",
+		Examples:      []string{"> 0"},
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Threshold", ArgNumber, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("> {Number}", "> 0"),
 		ComputeFn:     WrapNumericalArgument(greaterXaggregator),
 	},
@@ -1029,6 +1318,11 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CrossEntityAggregation,
 		Name:          "< {Number}",
 		DefaultString: "< 0",
+		Description:   "Keeps the values below a number",
+		Examples:      []string{"< 0"},
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Threshold", ArgNumber, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("< {Number}", "< 0"),
 		ComputeFn:     WrapNumericalArgument(lessXaggregator),
 	},
@@ -1036,6 +1330,11 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CrossEntityAggregation,
 		Name:          "= {Number}",
 		DefaultString: "= 0",
+		Description:   "Keeps the values equal to a number",
+		Examples:      []string{"= 0"},
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Value", ArgNumber, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("= {Number}", "= 0"),
 		ComputeFn:     WrapNumericalArgument(equalXaggregator),
 	},
@@ -1043,6 +1342,11 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Annualized {Number}-Day Standard Deviation",
 		DefaultString: "Annualized 30-Day Standard Deviation",
+		Description:   "Computes the rolling annualized standard deviation",
+		Examples:      []string{"Annualized 30-Day Standard Deviation"},
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Days", ArgNumber, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Annualized {Number}-Day Standard Deviation", "Annualized 30-Day Standard Deviation"),
 		ComputeFn:     WrapNumericalArgumentTS(tsStdDev),
 	},
@@ -1050,6 +1354,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Remove Data, Keep Universe Weights",
 		DefaultString: "Remove Data, Keep Universe Weights",
+		Description:   "Removes every series but the weights",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Remove Data, Keep Universe Weights", "Remove Data, Keep Universe Weights"),
 		ComputeFn:     WrapNoArguments(ComputeTS(tsRemoveData)),
 	},
@@ -1057,6 +1365,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TransformWeights,
 		Name:          "Remove Weights, Keep Data",
 		DefaultString: "Remove Weights, Keep Data",
+		Description:   "Removes the weights and keeps the data",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Remove Weights, Keep Data", "Remove Weights, Keep Data"),
 		ComputeFn:     WrapNoArguments(ComputeTS(tsRemoveWeights)),
 	},
@@ -1064,20 +1376,33 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TransformWeights,
 		Name:          "Remove Filtered Data",
 		DefaultString: "Remove Filtered Data",
+		Description:   "Removes the data from the periods the weights are zero",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Remove Filtered Data", "Remove Filtered Data"),
-		ComputeFn:     WrapNoArguments(ComputeTS(tsRemoveFilteredData)),
+		ComputeFn:     WrapNoArguments(ComputeTSAsOf(tsRemoveFilteredData)),
 	},
 	ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Align Data To Zero",
 		DefaultString: "Align Data To Zero",
+		Description:   "Aligns each event to start on the same day",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Align Data To Zero", "Align Data To Zero"),
-		ComputeFn:     WrapNoArgumentTSMulti(AlignEvent),
+		ComputeFn:     WrapNoArguments(ComputeTSMultiAsOf(AlignEvent)),
 	},
 	ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Align {Number} Days Before, {Number} Days After",
 		DefaultString: "Align 15 Days Before, 30 Days After",
+		Description:   "Aligns each event to a window around the day it starts",
+		Examples:      []string{"Align 15 Days Before, 30 Days After"},
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Days Before", ArgNumber, false}, ArgumentSchema{"Days After", ArgNumber, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Align {Number} Days Before, {Number} Days After", "Align 15 Days Before, 30 Days After"),
 		ComputeFn:     WrapNumericalArgumentTS2Multi(AlignEventBeforeAfter(false)),
 	},
@@ -1085,6 +1410,11 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Market Align {Number} Days Before, {Number} Days After",
 		DefaultString: "Market Align 15 Days Before, 30 Days After",
+		Description:   "Aligns each event to a window of market days around the day it starts",
+		Examples:      []string{"Market Align 15 Days Before, 30 Days After"},
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Days Before", ArgNumber, false}, ArgumentSchema{"Days After", ArgNumber, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Market Align {Number} Days Before, {Number} Days After", "Market Align 15 Days Before, 30 Days After"),
 		ComputeFn:     WrapNumericalArgumentTS2Multi(AlignEventBeforeAfter(true)),
 	},
@@ -1092,6 +1422,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Hacky Align Quarter for SSS",
 		DefaultString: "Hacky Align Quarter for SSS",
+		Description:   "Aligns each event to the 90 days after it starts, keeping its dates",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Hacky Align Quarter for SSS", "Hacky Align Quarter for SSS"),
 		ComputeFn:     WrapNoArguments(AlignEventBeforeAfterPreserveDates),
 	},
@@ -1099,6 +1433,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CombineData,
 		Name:          "Union",
 		DefaultString: "Union",
+		Description:   "Keeps the entities that are in any input",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   2,
+		MaxChildren:   UnlimitedChildren,
 		ArgCheckFn:    verifyNoArguments("Union", "Union"),
 		ComputeFn:     WrapNoArguments(UnionData),
 	},
@@ -1106,6 +1444,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CombineData,
 		Name:          "Difference",
 		DefaultString: "Difference",
+		Description:   "Subtracts each later input from the first",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   2,
+		MaxChildren:   UnlimitedChildren,
 		ArgCheckFn:    verifyNoArguments("Difference", "Difference"),
 		ComputeFn:     WrapNoArguments(DifferenceData),
 	},
@@ -1113,6 +1455,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CombineData,
 		Name:          "Intersection",
 		DefaultString: "Intersection",
+		Description:   "Keeps the entities that are in every input",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   2,
+		MaxChildren:   UnlimitedChildren,
 		ArgCheckFn:    verifyNoArguments("Intersection", "Intersection"),
 		ComputeFn:     WrapNoArguments(IntersectData),
 	},
@@ -1120,6 +1466,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CombineData,
 		Name:          "Exclusion",
 		DefaultString: "Exclusion",
+		Description:   "Keeps the entities of the first input that are in none of the others",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   2,
+		MaxChildren:   UnlimitedChildren,
 		ArgCheckFn:    verifyNoArguments("Exclusion", "Exclusion"),
 		ComputeFn:     WrapNoArguments(ExcludeData),
 	},
@@ -1127,6 +1477,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CombineData,
 		Name:          "Regression",
 		DefaultString: "Regression",
+		Description:   "Regresses the series of the first input on the second",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   2,
+		MaxChildren:   2,
 		ArgCheckFn:    verifyNoArguments("Regression", "Regression"),
 		ComputeFn:     WrapNoArguments(Regression),
 	},
@@ -1134,6 +1488,11 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CombineData,
 		Name:          "Alpha using {Number}-Month Regression",
 		DefaultString: "Alpha using 12-Month Regression",
+		Description:   "Computes the rolling alpha of the first input against the second",
+		Examples:      []string{"Alpha using 12-Month Regression"},
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Months", ArgNumber, false}},
+		MinChildren:   2,
+		MaxChildren:   2,
 		ArgCheckFn:    verifyNoArguments("Alpha using {Number}-Month Regression", "Alpha using 12-Month Regression"),
 		ComputeFn:     WrapNumericalArgumentCombine(Alpha),
 	},
@@ -1141,6 +1500,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Percentage Change",
 		DefaultString: "Percentage Change",
+		Description:   "Computes the change from one period to the next",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Percentage Change", "Percentage Change"),
 		ComputeFn:     WrapNoArguments(ComputeTS(tsTotalReturn())),
 	},
@@ -1155,6 +1518,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Cumulative Change",
 		DefaultString: "Cumulative Change",
+		Description:   "Computes the change since the start of each series",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Cumulative Change", "Cumulative Change"),
 		ComputeFn:     WrapNoArguments(ComputeTS(tsCumulativeChange())),
 	},
@@ -1162,6 +1529,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Indexed to Beginning",
 		DefaultString: "Indexed to Beginning",
+		Description:   "Divides each series by its first value",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Indexed to Beginning", "Indexed to Beginning"),
 		ComputeFn:     WrapNoArguments(ComputeTS(tsIndexedToBeginning())),
 	},
@@ -1169,6 +1540,11 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Indexed to Average of First {Number} Days",
 		DefaultString: "Indexed to Average of First 7 Days",
+		Description:   "Divides each series by its average over the first days",
+		Examples:      []string{"Indexed to Average of First 7 Days"},
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Days", ArgNumber, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Indexed to Average of First {Number} Days", "Indexed to Average of First 7 Days"),
 		ComputeFn:     WrapNumericalArgumentTS(tsAverageOfFirstDays),
 	},
@@ -1176,6 +1552,11 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Indexed to Median of First {Number} Days",
 		DefaultString: "Indexed to Median of First 7 Days",
+		Description:   "Divides each series by its median over the first days",
+		Examples:      []string{"Indexed to Median of First 7 Days"},
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Days", ArgNumber, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Indexed to Median of First {Number} Days", "Indexed to Median of First 7 Days"),
 		ComputeFn:     medianOfFirstDays,
 	},
@@ -1183,6 +1564,11 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "{Number}-Day Forward Return",
 		DefaultString: "30-Day Forward Return",
+		Description:   "Computes the return over the following days",
+		Examples:      []string{"30-Day Forward Return"},
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Days", ArgNumber, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("{Number}-Day Forward Return", "30-Day Forward Return"),
 		ComputeFn:     WrapNumericalArgumentTS(tsForwardReturn),
 	},
@@ -1190,6 +1576,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          component.Daily,
 		DefaultString: component.Daily,
+		Description:   "Resamples each series to days",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments(component.Daily, component.Daily),
 		ComputeFn:     WrapNoArguments(AlignCalendar(tsDaily)),
 	},
@@ -1197,6 +1587,11 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Sample Every {Number} Periods",
 		DefaultString: "Sample Every 7 Periods",
+		Description:   "Keeps one value in every number of periods",
+		Examples:      []string{"Sample Every 7 Periods"},
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Periods", ArgNumber, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Sample Every {Number} Periods", "Sample Every 7 Periods"),
 		ComputeFn:     WrapNumericalArgumentTS(tsSampleEveryNumPeriods),
 	},
@@ -1204,6 +1599,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Resample To Lowest Frequency",
 		DefaultString: "Resample To Lowest Frequency",
+		Description:   "Resamples every series to the dates of the least frequent one",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Resample To Lowest Frequency", "Resample To Lowest Frequency"),
 		ComputeFn:     WrapNoArguments(ResampleToLowestFrequency),
 	},
@@ -1211,6 +1610,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          component.Weekly,
 		DefaultString: component.Weekly,
+		Description:   "Resamples each series to weeks",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments(component.Weekly, component.Weekly),
 		ComputeFn:     WrapNoArguments(AlignCalendar(tsByWeek)),
 	},
@@ -1218,6 +1621,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          component.Monthly,
 		DefaultString: component.Monthly,
+		Description:   "Resamples each series to months",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments(component.Monthly, component.Monthly),
 		ComputeFn:     WrapNoArguments(AlignCalendar(tsByMonth)),
 	},
@@ -1225,6 +1632,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          component.Quarterly,
 		DefaultString: component.Quarterly,
+		Description:   "Resamples each series to quarters",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments(component.Quarterly, component.Quarterly),
 		ComputeFn:     WrapNoArguments(AlignCalendar(tsByQuarter)),
 	},
@@ -1232,6 +1643,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          component.Yearly,
 		DefaultString: component.Yearly,
+		Description:   "Resamples each series to years",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments(component.Yearly, component.Yearly),
 		ComputeFn:     WrapNoArguments(AlignCalendar(tsByYear)),
 	},
@@ -1239,13 +1654,21 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          component.AllTime,
 		DefaultString: component.AllTime,
+		Description:   "Resamples each series to a single value at its end",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments(component.AllTime, component.AllTime),
-		ComputeFn:     WrapNoArguments(ComputeTS(tsAllTime())),
+		ComputeFn:     WrapNoArguments(ComputeTSAsOf(tsAllTime)),
 	},
 	ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Latest Data",
 		DefaultString: "Latest Data",
+		Description:   "Keeps the value of each series on the latest day",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Latest Data", "Latest Data"),
 		ComputeFn:     WrapNoArguments(WrapLatestData()),
 	},
@@ -1253,6 +1676,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Align Last Day",
 		DefaultString: "Align Last Day",
+		Description:   "Moves the last value of each series to the latest day",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Align Last Day", "Align Last Day"),
 		ComputeFn:     WrapNoArguments(AlignLastDay()),
 	},
@@ -1260,6 +1687,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "All Data Available",
 		DefaultString: "All Data Available",
+		Description:   "Keeps the dates for which every series has data",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("All Data Available", "All Data Available"),
 		ComputeFn:     WrapNoArguments(AlignFirstDay()),
 	},
@@ -1267,6 +1698,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "CAGR",
 		DefaultString: "CAGR",
+		Description:   "Computes the compound annual growth rate",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("CAGR", "CAGR"),
 		ComputeFn:     WrapNoArguments(ComputeTS(tsCagr)),
 	},
@@ -1274,36 +1709,56 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TransformWeights,
 		Name:          "Use Latest Weights Historically",
 		DefaultString: "Use Latest Weights Historically",
+		Description:   "Uses the latest weights for the whole history",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Use Latest Weights Historically", "Use Latest Weights Historically"),
-		ComputeFn:     WrapNoArguments(ComputeTS(latestWeightsHistorical)),
+		ComputeFn:     WrapNoArguments(ComputeTSAsOf(latestWeightsHistorical)),
 	},
 	ComputationStep{
 		Type:          component.TransformWeights,
 		Name:          "Rebalance Weekly",
 		DefaultString: "Rebalance Weekly",
+		Description:   "Rebalances the weights every week",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Rebalance Weekly", "Rebalance Weekly"),
-		ComputeFn:     WrapNoArguments(ComputeTS(rebalanceWeekly)),
+		ComputeFn:     WrapNoArguments(ComputeTSAsOf(rebalanceWeekly)),
 	},
 	ComputationStep{
 		Type:          component.TransformWeights,
 		Name:          "Rebalance Monthly",
 		DefaultString: "Rebalance Monthly",
+		Description:   "Rebalances the weights every month",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Rebalance Monthly", "Rebalance Monthly"),
-		ComputeFn:     WrapNoArguments(ComputeTS(rebalanceMonthly)),
+		ComputeFn:     WrapNoArguments(ComputeTSAsOf(rebalanceMonthly)),
 	},
 	ComputationStep{
 		Type:          component.TransformWeights,
 		Name:          "Rebalance Quarterly",
 		DefaultString: "Rebalance Quarterly",
+		Description:   "Rebalances the weights every quarter",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Rebalance Quarterly", "Rebalance Quarterly"),
-		ComputeFn:     WrapNoArguments(ComputeTS(rebalanceQuarterly)),
+		ComputeFn:     WrapNoArguments(ComputeTSAsOf(rebalanceQuarterly)),
 	},
 	ComputationStep{
 		Type:          component.TransformWeights,
 		Name:          "Rebalance Yearly",
 		DefaultString: "Rebalance Yearly",
+		Description:   "Rebalances the weights every year",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Rebalance Yearly", "Rebalance Yearly"),
-		ComputeFn:     WrapNoArguments(ComputeTS(rebalanceYearly)),
+		ComputeFn:     WrapNoArguments(ComputeTSAsOf(rebalanceYearly)),
 	},
 	//ComputationStep{
 	//	Type:          component.SameEntityAggregation,
@@ -1316,6 +1771,11 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Number of Days Since < {Number}",
 		DefaultString: "Number of Days Since < 5",
+		Description:   "Counts the days since each series was last below a number",
+		Examples:      []string{"Number of Days Since < 5"},
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Threshold", ArgNumber, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Number of Days Since < {Number}", "Number of Days Since < 5"),
 		ComputeFn:     WrapNumericalArgumentTS(tsDaysSinceDrop),
 	},
@@ -1372,6 +1832,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CrossEntityAggregation,
 		Name:          "Sum",
 		DefaultString: "Sum",
+		Description:   "Adds up the entities",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Sum", "Sum"),
 		ComputeFn:     WrapNoArguments(CrossEntityAggregation(sumAggregator)),
 	},
@@ -1379,6 +1843,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CrossEntityAggregation,
 		Name:          "Average",
 		DefaultString: "Average",
+		Description:   "Averages the entities",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Average", "Average"),
 		ComputeFn:     WrapNoArguments(CrossEntityAggregation(averageAggregator)),
 	},
@@ -1386,6 +1854,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CrossEntityAggregation,
 		Name:          "Flatten",
 		DefaultString: "Flatten",
+		Description:   "Puts the series of every entity into a single entity",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Flatten", "Flatten"),
 		ComputeFn:     WrapNoArguments(flatten),
 	},
@@ -1393,6 +1865,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.TimeSeriesTransformation,
 		Name:          "Company Event to Macro Event",
 		DefaultString: "Company Event to Macro Event",
+		Description:   "Turns the weights of the companies into a single event",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Company Event to Macro Event", "Company Event to Macro Event"),
 		ComputeFn:     WrapNoArguments(WeightsToMacro),
 	},
@@ -1414,6 +1890,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CrossEntityAggregation,
 		Name:          "Count",
 		DefaultString: "Count",
+		Description:   "Counts the entities",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Count", "Count"),
 		ComputeFn:     WrapNoArguments(CrossEntityAggregation(countAggregator)),
 	},
@@ -1421,6 +1901,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CrossEntityAggregation,
 		Name:          "Percent",
 		DefaultString: "Percent",
+		Description:   "Computes the percentage of entities above zero",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Percent", "Percent"),
 		ComputeFn:     WrapNoArguments(CrossEntityAggregation(percentAggregator)),
 	},
@@ -1428,6 +1912,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CrossEntityAggregation,
 		Name:          "Median",
 		DefaultString: "Median",
+		Description:   "Takes the median of the entities",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Median", "Median"),
 		ComputeFn:     WrapNoArguments(CrossEntityAggregation(medianAggregator)),
 	},
@@ -1435,6 +1923,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CrossEntityAggregation,
 		Name:          "Summary Stats",
 		DefaultString: "Summary Stats",
+		Description:   "Computes summary statistics across the entities",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Summary Stats", "Summary Stats"),
 		ComputeFn:     WrapNoArguments(SummaryStats),
 	},
@@ -1442,6 +1934,11 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CrossEntityAggregation,
 		Name:          "Top {Number}",
 		DefaultString: "Top 5",
+		Description:   "Keeps the entities with the largest weights",
+		Examples:      []string{"Top 5"},
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Count", ArgNumber, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Top {Number}", "Top 5"),
 		ComputeFn:     WrapNumericalArgument(topXaggregator),
 	},
@@ -1449,6 +1946,11 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.CrossEntityAggregation,
 		Name:          "Bottom {Number}",
 		DefaultString: "Bottom 5",
+		Description:   "Keeps the entities with the smallest weights",
+		Examples:      []string{"Bottom 5"},
+		Arguments:     []ArgumentSchema{ArgumentSchema{"Count", ArgNumber, false}},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Bottom {Number}", "Bottom 5"),
 		ComputeFn:     WrapNumericalArgument(bottomXaggregator),
 	},
@@ -1456,6 +1958,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.Classification,
 		Name:          "No Classification",
 		DefaultString: "No Classification",
+		Description:   "Removes the categories",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("No Classification", "No Classification"),
 		ComputeFn:     WrapNoArguments(ComputeTS(noClassification)),
 	},
@@ -1463,6 +1969,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.Classification,
 		Name:          "By Company",
 		DefaultString: "By Company",
+		Description:   "Puts each company in its own category",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("By Company", "By Company"),
 		ComputeFn:     WrapNoArguments(ComputeTS(categorizeByCompany)),
 	},
@@ -1470,6 +1980,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.Classification,
 		Name:          "By Sector",
 		DefaultString: "By Sector",
+		Description:   "Groups the companies by sector",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("By Sector", "By Sector"),
 		ComputeFn:     WrapNoArguments(ComputeTS(categorizeBySector(list.Sector))),
 	},
@@ -1477,6 +1991,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.Classification,
 		Name:          "By Industry Group",
 		DefaultString: "By Industry Group",
+		Description:   "Groups the companies by industry group",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("By Industry Group", "By Industry Group"),
 		ComputeFn:     WrapNoArguments(ComputeTS(categorizeBySector(list.IndustryGroup))),
 	},
@@ -1484,6 +2002,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.Classification,
 		Name:          "By Industry",
 		DefaultString: "By Industry",
+		Description:   "Groups the companies by industry",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("By Industry", "By Industry"),
 		ComputeFn:     WrapNoArguments(ComputeTS(categorizeBySector(list.Industry))),
 	},
@@ -1491,6 +2013,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.Classification,
 		Name:          "By Sub Industry",
 		DefaultString: "By Sub Industry",
+		Description:   "Groups the companies by sub industry",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("By Sub Industry", "By Sub Industry"),
 		ComputeFn:     WrapNoArguments(ComputeTS(categorizeBySector(list.SubIndustry))),
 	},
@@ -1505,6 +2031,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.Classification,
 		Name:          "Quartile",
 		DefaultString: "Quartile",
+		Description:   "Groups the entities into quartiles",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Quartile", "Quartile"),
 		ComputeFn:     WrapNoArguments(CrossEntityAggregation(quantileAggregator(4))),
 	},
@@ -1512,6 +2042,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.Classification,
 		Name:          "Quintile",
 		DefaultString: "Quintile",
+		Description:   "Groups the entities into quintiles",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Quintile", "Quintile"),
 		ComputeFn:     WrapNoArguments(CrossEntityAggregation(quantileAggregator(5))),
 	},
@@ -1519,6 +2053,10 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.Classification,
 		Name:          "Decile",
 		DefaultString: "Decile",
+		Description:   "Groups the entities into deciles",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Decile", "Decile"),
 		ComputeFn:     WrapNoArguments(CrossEntityAggregation(quantileAggregator(10))),
 	},
@@ -1526,13 +2064,17 @@ var ComputationsSteps []ComputationStep = []ComputationStep{
 		Type:          component.GraphicalPreference,
 		Name:          "Boxplot",
 		DefaultString: "Boxplot",
+		Description:   "Charts the spread of the entities as a box plot",
+		Arguments:     []ArgumentSchema{},
+		MinChildren:   1,
+		MaxChildren:   1,
 		ArgCheckFn:    verifyNoArguments("Boxplot", "Boxplot"),
 		ComputeFn:     WrapNoArguments(ComposeStepFn(CrossEntityAggregation(boxplotAggregator), GraphicalPreference("Boxplot"))),
 	},
 }
 
 func InsertComputationTerms(terms *term.TermData) {
-	for _, v := range ComputationsSteps {
+	for _, v := range Steps.All() {
 		if v.Name != "" {
 			terms.Insert(v.Name, component.QueryComponent{0, v.Name, v.Name, string(v.Type), v.Name, "", v.DefaultString, nil})
 		}
@@ -1609,7 +2151,11 @@ func ZapierHandler(ctx context.Context, w http.ResponseWriter, r *http.Request,
 func RunHandler(ctx context.Context, w http.ResponseWriter, r *http.Request, title string, terms *term.TermData) {
 	decoder := json.NewDecoder(r.Body)
 	var c ExecutionNode
-	_ = decoder.Decode(&c)
+
+	if err := decoder.Decode(&c); err != nil {
+		http.Error(w, "Unable to decode tree: "+err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	// m, _ := json.Marshal(c)
 	// log.Infof(ctx, "body = %s", m)
@@ -1617,15 +2163,35 @@ func RunHandler(ctx context.Context, w http.ResponseWriter, r *http.Request, tit
 	RunHandlerNoDecoder(ctx, w, r, title, terms, c)
 }
 
+// queueRun has the worker execute the run in the background
+func queueRun(ctx context.Context, id string) error {
+	return platform.AddTask(ctx, "/apiv1/worker", url.Values{"id": {id}}, 2)
+}
+
+// RunHandlerNoDecoder queues the tree. An asof form value of the form
+// YYYY-MM-DD evaluates it as it would have been on that date.
 func RunHandlerNoDecoder(ctx context.Context, w http.ResponseWriter, r *http.Request, title string, terms *term.TermData, c ExecutionNode) {
-	id := runTree(ctx, c, title, terms)
+	var asOf time.Time
 
-	t := taskqueue.NewPOSTTask("/apiv1/worker", map[string][]string{"id": {id}})
-	if t.RetryOptions == nil {
-		t.RetryOptions = &taskqueue.RetryOptions{}
+	if s := r.FormValue("asof"); s != "" {
+		var err error
+		if asOf, err = parseAsOf(s); err != nil {
+			http.Error(w, "Invalid as-of date "+s, http.StatusBadRequest)
+			return
+		}
+	}
+
+	id := runTreeAsOf(ctx, c, title, terms, asOf)
+
+	if id == "" {
+		http.Error(w, "Unable to store tree", http.StatusInternalServerError)
+		return
 	}
-	t.RetryOptions.RetryLimit = 2
-	if _, err := taskqueue.Add(ctx, t, ""); err != nil {
+
+	createRunRecord(ctx, id)
+
+	if err := queueRun(ctx, id); err != nil {
+		setRunState(ctx, id, RunFailed, "Unable to queue run: "+err.Error(), "")
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -1637,18 +2203,20 @@ func ReRunHandler(ctx context.Context, w http.ResponseWriter, r *http.Request, q
 
 	key, err := db.GetFromField(ctx, db.RunData, "RunId", query, &m)
 
-	if logError(ctx, err) && key != nil {
-		db.DatabaseDelete(ctx, key.Encode())
+	if logError(ctx, err) && key != "" {
+		db.DatabaseDelete(ctx, key)
 	} else {
 		log.Errorf(ctx, "No key for %s", query)
 	}
 
-	t := taskqueue.NewPOSTTask("/apiv1/worker", map[string][]string{"id": {query}})
-	if t.RetryOptions == nil {
-		t.RetryOptions = &taskqueue.RetryOptions{}
-	}
-	t.RetryOptions.RetryLimit = 2
-	if _, err := taskqueue.Add(ctx, t, ""); err != nil {
+	// A cancel request left from the last run would stop this one as soon as it
+	// starts, and its events would be replayed to clients of this one
+	clearCancel(ctx, query)
+	clearRunEvents(ctx, query)
+	setRunState(ctx, query, RunQueued, "", "")
+
+	if err := queueRun(ctx, query); err != nil {
+		setRunState(ctx, query, RunFailed, "Unable to queue run: "+err.Error(), "")
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
@@ -1661,21 +2229,90 @@ func WorkerHandler(ctx context.Context, w http.ResponseWriter, r *http.Request)
 
 	var m TreeDummy
 
-	db.GetFromKey(ctx, id, &m)
+	// Failures to load the tree are recorded rather than returned since
+	// retrying the task won't fix them
+	if err := db.GetFromKey(ctx, id, &m); err != nil {
+		log.Errorf(ctx, "Unable to load tree %s: %s", id, err)
+		setRunState(ctx, id, RunFailed, "Unable to load tree: "+err.Error(), "")
+		return
+	}
 
 	var t ExecutionNode
 
-	json.Unmarshal(m.Tree, &t)
+	if err := json.Unmarshal(m.Tree, &t); err != nil {
+		log.Errorf(ctx, "Unable to decode tree %s: %s", id, err)
+		setRunState(ctx, id, RunFailed, "Unable to decode tree: "+err.Error(), "")
+		return
+	}
+
+	if rec, _, found := getRunRecord(ctx, id); found && rec.State == RunCancelled {
+		log.Infof(ctx, "Run %s was cancelled before it started", id)
+		return
+	}
 
 	log.Infof(ctx, "Running id = %s", id)
+	setRunState(ctx, id, RunRunning, "", "")
 	track.Update(ctx, id, "Running", 0)
-	med := t.Execute(ctx, id, "")
+
+	deadlineCtx, cancel := context.WithTimeout(ctx, RunTimeout)
+	defer cancel()
+	go watchForCancel(ctx, deadlineCtx, id, cancel)
+
+	runCtx, failure := withRunFailure(deadlineCtx)
+	if !m.AsOf.IsZero() {
+		runCtx = WithAsOf(runCtx, m.AsOf)
+	}
+	runCtx, trace := withRunTrace(runCtx)
+	runCtx, _ = withRunProgress(runCtx, id, t)
+	med := t.Execute(runCtx, id, "")
+
+	storeTrace(ctx, id, trace)
+
+	if cancelRequested(ctx, id) {
+		// The cancel handler has already recorded the state
+		track.Update(ctx, id, "Cancelled", 1)
+		log.Infof(ctx, "Cancelled id = %s", id)
+		return
+	}
+
+	if deadlineCtx.Err() == context.DeadlineExceeded || failure.hasTimedOut() {
+		node, nodeErr := failure.get()
+		if nodeErr == "" {
+			nodeErr = fmt.Sprintf("Run took longer than %v", RunTimeout)
+		}
+		setRunState(ctx, id, RunTimedOut, nodeErr, node)
+		track.Update(ctx, id, "Timed out", 1)
+		log.Infof(ctx, "Timed out id = %s", id)
+		return
+	}
 
 	var m2 DataDummy
 	m2.RunId = id
-	m2.Data, _ = json.Marshal(med)
 
-	db.DatabaseInsert(ctx, db.RunData, &m2, "")
+	var err error
+	m2.Data, err = json.Marshal(med)
+
+	if err != nil {
+		log.Errorf(ctx, "Unable to encode results for %s: %s", id, err)
+		setRunState(ctx, id, RunFailed, "Unable to encode results: "+err.Error(), "")
+		return
+	}
+
+	if db.DatabaseInsert(ctx, db.RunData, &m2, "") == "" {
+		setRunState(ctx, id, RunFailed, "Unable to store results", "")
+		return
+	}
+
+	if med.Error != "" {
+		node, nodeErr := failure.get()
+		if nodeErr == "" {
+			nodeErr = med.Error
+		}
+		setRunState(ctx, id, RunCompletedWithErrors, nodeErr, node)
+	} else {
+		setRunState(ctx, id, RunCompleted, "", "")
+	}
+
 	track.Update(ctx, id, "Completed", 1)
 	log.Infof(ctx, "Completed id = %s", id)
 }
@@ -1684,6 +2321,7 @@ type TreeDummy struct {
 	Test   string
 	Field2 string
 	Tree   []byte
+	AsOf   time.Time
 }
 
 type DataDummy struct {
@@ -1692,6 +2330,12 @@ type DataDummy struct {
 }
 
 func runTree(ctx context.Context, c ExecutionNode, title string, terms *term.TermData) string {
+	return runTreeAsOf(ctx, c, title, terms, time.Time{})
+}
+
+// runTreeAsOf stores the tree to be run as of a past date, or as of the time
+// it runs when asOf is zero
+func runTreeAsOf(ctx context.Context, c ExecutionNode, title string, terms *term.TermData, asOf time.Time) string {
 	c.ParseTree(ctx, terms)
 
 	var m TreeDummy
@@ -1699,6 +2343,7 @@ func runTree(ctx context.Context, c ExecutionNode, title string, terms *term.Ter
 	m.Tree = temp
 	m.Test = "Zain"
 	m.Field2 = "Hello"
+	m.AsOf = asOf
 
 	id := db.DatabaseInsert(ctx, db.RunTree, &m, "")
 
@@ -1724,9 +2369,9 @@ func TreeHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 func DataHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get(":query")
 
-	details := track.GetDetails(ctx, query)
+	status, complete := runStatus(ctx, query)
 
-	if query != "" && (details.Message == "" || details.PercentComplete >= 0.999) {
+	if query != "" && complete {
 		var m DataDummy
 
 		_, err := db.GetFromField(ctx, db.RunData, "RunId", query, &m)
@@ -1750,8 +2395,7 @@ func DataHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 			})
 		}
 	} else {
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, "%s\n", track.GetData(ctx, query))
+		returnJson(ctx, w, status)
 	}
 }
 
@@ -1773,12 +2417,16 @@ func logError(ctx context.Context, err error) bool {
 	return false
 }
 
+// RawHandler returns the result of a run as JSON. The entity, field, start,
+// end, cursor and limit parameters narrow it down to what a client renders
+// and format=ndjson streams it an entity per line after a line with its title
+// and error.
 func RawHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	query := r.URL.Query().Get(":query")
 
-	details := track.GetDetails(ctx, query)
+	status, complete := runStatus(ctx, query)
 
-	if query != "" && (details.Message == "" || details.PercentComplete >= 0.999) {
+	if query != "" && complete {
 		// type Dummy struct {
 		// 	Data MultiEntityData `bson:"data"`
 		// }
@@ -1786,15 +2434,23 @@ func RawHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 		var m DataDummy
 
 		// db.GetFromKey(ctx, query, &m)
-		db.GetFromField(ctx, db.RunData, "RunId", query, &m)
+		key, err := db.GetFromField(ctx, db.RunData, "RunId", query, &m)
 
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, "%s\n", m.Data)
+
+		if !logError(ctx, err) {
+			http.Error(w, errUnreadableResult.Error(), http.StatusInternalServerError)
+			return
+		} else if key == "" {
+			http.Error(w, "No stored result for "+query, http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Access-Control-Expose-Headers", "X-Next-Cursor")
+		logError(ctx, writeRawData(w, r, m.Data))
 	} else {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Content-Type", "application/json")
-		fmt.Fprintf(w, "%s\n", track.GetData(ctx, query))
+		returnJson(ctx, w, status)
 	}
 }
 
@@ -1845,9 +2501,9 @@ func RawHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 func ExcelHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	hex := r.URL.Query().Get(":query")
 
-	details := track.GetDetails(ctx, hex)
+	status, complete := runStatus(ctx, hex)
 
-	if hex != "" && (details.Message == "" || details.PercentComplete >= 0.999) {
+	if hex != "" && complete {
 		var m DataDummy
 
 		_, err := db.GetFromField(ctx, db.RunData, "RunId", hex, &m)
@@ -1865,7 +2521,7 @@ func ExcelHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 			// http.ServeFile(w, r, "/root/dploy/i/"+hex+".xlsx")
 		}
 	} else {
-		fmt.Fprintf(w, "%s\n", track.GetData(ctx, hex))
+		returnJson(ctx, w, status)
 	}
 }
 
@@ -1891,35 +2547,27 @@ func ExcelHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 // }
 
 func findArgcheck(majorType string, c []component.QueryComponent) (func(MultiEntityData, []component.QueryComponent) ([]component.QueryComponent, error), error) {
-	for _, v := range ComputationsSteps {
-		if majorType == string(v.Type) && len(c) > 0 && c[0].QueryComponentCanonicalName == string(v.Name) {
-			return v.ArgCheckFn, nil
-		}
+	step, exact, found := Steps.LookupOrDefault(component.MajorType(majorType), c)
+
+	if !found {
+		return nil, errors.New("No matching computation step or matching major type found")
 	}
 
-	for _, v := range ComputationsSteps {
-		if majorType == string(v.Type) {
-			return v.ArgCheckFn, errors.New("No matching computation step found")
-		}
+	if !exact {
+		return step.ArgCheckFn, errors.New("No matching computation step found")
 	}
 
-	return nil, errors.New("No matching computation step or matching major type found")
+	return step.ArgCheckFn, nil
 }
 
 func findComputationStep(majorType string, c []component.QueryComponent) func([]component.QueryComponent) StepFnType {
-	for _, v := range ComputationsSteps {
-		if majorType == string(v.Type) && len(c) > 0 && c[0].QueryComponentCanonicalName == string(v.Name) {
-			return v.ComputeFn
-		}
-	}
+	step, _, found := Steps.LookupOrDefault(component.MajorType(majorType), c)
 
-	for _, v := range ComputationsSteps {
-		if majorType == string(v.Type) {
-			return v.ComputeFn
-		}
+	if !found {
+		return nil
 	}
 
-	return nil
+	return step.ComputeFn
 }
 
 func verifyNoArguments(computationName string, defaultString string) func(MultiEntityData, []component.QueryComponent) ([]component.QueryComponent, error) {
@@ -1991,6 +2639,8 @@ func verifyQuandlCode(m MultiEntityData, c []component.QueryComponent) ([]compon
 	return c, nil
 }
 
+// verifyFormula returns a *parse.SyntaxError when the formula doesn't parse,
+// and an error when it calls an unknown function or with the wrong arguments
 func verifyFormula(m MultiEntityData, c []component.QueryComponent) ([]component.QueryComponent, error) {
 	if len(c) < 2 || c[0].QueryComponentType != component.TimeSeriesFormula {
 		return []component.QueryComponent{
@@ -2003,6 +2653,14 @@ func verifyFormula(m MultiEntityData, c []component.QueryComponent) ([]component
 		}, nil
 	}
 
+	e, err := parseTimeSeriesTransformation(c[0].QueryComponentOriginalString)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.CheckCalls(); err != nil {
+		return nil, err
+	}
+
 	return c, nil
 }
 
@@ -2171,10 +2829,8 @@ func retrieveComputationStep(major component.MajorType, c []component.QueryCompo
 		return ComputationStep{}, errors.New("No components specified for the step " + string(major))
 	}
 
-	for _, v := range ComputationsSteps {
-		if v.Type == major && v.Name == c[0].QueryComponentCanonicalName {
-			return v, nil
-		}
+	if step, ok := Steps.Lookup(major, c[0].QueryComponentCanonicalName); ok {
+		return step, nil
 	}
 
 	return ComputationStep{}, errors.New("No computation step found for " + string(major) + " specifically " + c[0].QueryComponentCanonicalName)
@@ -2669,9 +3325,11 @@ func getYearlyDates(startDate time.Time, endDate time.Time) ([]time.Time, bool,
 	return newArr, beginIncomplete, endIncomplete
 }
 
-func getStartEndDatesForEntity(s SingleEntityData) (time.Time, time.Time) {
+// getStartEndDatesForEntity returns the first and last dates of the entity's
+// series. An entity without data starts on the as-of date.
+func getStartEndDatesForEntity(s SingleEntityData, asOf time.Time) (time.Time, time.Time) {
 	var startDate, endDate time.Time
-	startDate = time.Now()
+	startDate = asOf
 
 	for _, v := range s.Data {
 		if len(v.Data) > 0 {
@@ -2688,16 +3346,17 @@ func getStartEndDatesForEntity(s SingleEntityData) (time.Time, time.Time) {
 	return startDate, endDate
 }
 
-func getWeightStartEndDates(w Series) ([]time.Time, []time.Time) {
+// getWeightStartEndDates returns the periods the weights are nonzero. A period
+// still open at the end of the weights ends on the as-of date.
+func getWeightStartEndDates(w Series, asOf time.Time) ([]time.Time, []time.Time) {
 	startDates := make([]time.Time, 0)
 	endDates := make([]time.Time, 0)
-	now := time.Now()
 
 	for i, v := range w.Data {
 		if i == 0 {
 			if v.Data != 0 {
 				startDates = append(startDates, v.Time)
-				endDates = append(endDates, now)
+				endDates = append(endDates, asOf)
 			}
 		} else {
 			if v.Data == 0 && w.Data[i-1].Data != 0 {
@@ -2711,7 +3370,7 @@ func getWeightStartEndDates(w Series) ([]time.Time, []time.Time) {
 			} else if v.Data != 0 && w.Data[i-1].Data == 0 {
 				// This means that this is a new startDate
 				startDates = append(startDates, v.Time)
-				endDates = append(endDates, now)
+				endDates = append(endDates, asOf)
 			}
 		}
 	}
@@ -2838,14 +3497,20 @@ func alignTimePointsToZero(s Series, pivotPoint time.Time) Series {
 }
 
 // SingleEntityData functions
-func AlignEvent(s SingleEntityData) []SingleEntityData {
+func AlignEvent(asOf time.Time) func(SingleEntityData) []SingleEntityData {
+	return func(s SingleEntityData) []SingleEntityData {
+		return alignEvent(s, asOf)
+	}
+}
+
+func alignEvent(s SingleEntityData, asOf time.Time) []SingleEntityData {
 	// Create a new entity
 	var entityArray []SingleEntityData = make([]SingleEntityData, 0)
 
 	weightSeries := getWeightSeries(s.Data)
 
 	// Get all the nonzero periods
-	startDates, endDates := getWeightStartEndDates(weightSeries)
+	startDates, endDates := getWeightStartEndDates(weightSeries, asOf)
 
 	for _, v := range s.Data {
 		if !v.IsWeight {
@@ -3313,11 +3978,17 @@ func tsRemoveWeights(s SingleEntityData) SingleEntityData {
 	return s
 }
 
-func tsRemoveFilteredData(s SingleEntityData) SingleEntityData {
+func tsRemoveFilteredData(asOf time.Time) func(SingleEntityData) SingleEntityData {
+	return func(s SingleEntityData) SingleEntityData {
+		return removeFilteredData(s, asOf)
+	}
+}
+
+func removeFilteredData(s SingleEntityData, asOf time.Time) SingleEntityData {
 	weightSeries := getWeightSeries(s.Data)
 
 	// Get all the nonzero periods
-	startDates, endDates := getWeightStartEndDates(weightSeries)
+	startDates, endDates := getWeightStartEndDates(weightSeries, asOf)
 
 	for i, v := range s.Data {
 		if !v.IsWeight {
@@ -3346,8 +4017,8 @@ func tsRemoveFilteredData(s SingleEntityData) SingleEntityData {
 	return tsRemoveWeights(s)
 }
 
-func earliestDate(s SingleEntityData) time.Time {
-	t := time.Now()
+func earliestDate(s SingleEntityData, asOf time.Time) time.Time {
+	t := asOf
 
 	for _, v := range s.Data {
 		if len(v.Data) > 0 && v.Data[0].Time.Before(t) {
@@ -3358,9 +4029,15 @@ func earliestDate(s SingleEntityData) time.Time {
 	return t
 }
 
-func latestWeightsHistorical(s SingleEntityData) SingleEntityData {
+func latestWeightsHistorical(asOf time.Time) func(SingleEntityData) SingleEntityData {
+	return func(s SingleEntityData) SingleEntityData {
+		return latestWeights(s, asOf)
+	}
+}
+
+func latestWeights(s SingleEntityData, asOf time.Time) SingleEntityData {
 	// Find the earliest date
-	earliestDate := earliestDate(s)
+	earliestDate := earliestDate(s, asOf)
 
 	// Return a data point that takes the last data point in the series
 	// and puts it as the only data point with the date as the first date
@@ -3378,45 +4055,53 @@ func latestWeightsHistorical(s SingleEntityData) SingleEntityData {
 	return s
 }
 
-func rebalanceWeekly(s SingleEntityData) SingleEntityData {
-	startDate, endDate := getStartEndDatesForEntity(s)
-	dates, beginIncomplete, endIncomplete := getWeeklyDatesBetween(startDate, endDate)
+func rebalanceWeekly(asOf time.Time) func(SingleEntityData) SingleEntityData {
+	return func(s SingleEntityData) SingleEntityData {
+		startDate, endDate := getStartEndDatesForEntity(s, asOf)
+		dates, beginIncomplete, endIncomplete := getWeeklyDatesBetween(startDate, endDate)
 
-	s.Data = applyToWeights(s.Data, resampleOnDates(dates, beginIncomplete, endIncomplete), metaTransform(noStringChange, noStringChange, noResampleChange, noResampleChange))
+		s.Data = applyToWeights(s.Data, resampleOnDates(dates, beginIncomplete, endIncomplete), metaTransform(noStringChange, noStringChange, noResampleChange, noResampleChange))
 
-	return s
+		return s
+	}
 }
 
-func rebalanceMonthly(s SingleEntityData) SingleEntityData {
-	startDate, endDate := getStartEndDatesForEntity(s)
-	dates, beginIncomplete, endIncomplete := getMonthlyDates(startDate, endDate)
+func rebalanceMonthly(asOf time.Time) func(SingleEntityData) SingleEntityData {
+	return func(s SingleEntityData) SingleEntityData {
+		startDate, endDate := getStartEndDatesForEntity(s, asOf)
+		dates, beginIncomplete, endIncomplete := getMonthlyDates(startDate, endDate)
 
-	s.Data = applyToWeights(s.Data, resampleOnDates(dates, beginIncomplete, endIncomplete), metaTransform(noStringChange, noStringChange, noResampleChange, noResampleChange))
+		s.Data = applyToWeights(s.Data, resampleOnDates(dates, beginIncomplete, endIncomplete), metaTransform(noStringChange, noStringChange, noResampleChange, noResampleChange))
 
-	return s
+		return s
+	}
 }
 
-func rebalanceQuarterly(s SingleEntityData) SingleEntityData {
-	startDate, endDate := getStartEndDatesForEntity(s)
-	dates, beginIncomplete, endIncomplete := getQuarterlyDates(startDate, endDate)
+func rebalanceQuarterly(asOf time.Time) func(SingleEntityData) SingleEntityData {
+	return func(s SingleEntityData) SingleEntityData {
+		startDate, endDate := getStartEndDatesForEntity(s, asOf)
+		dates, beginIncomplete, endIncomplete := getQuarterlyDates(startDate, endDate)
 
-	s.Data = applyToWeights(s.Data, resampleOnDates(dates, beginIncomplete, endIncomplete), metaTransform(noStringChange, noStringChange, noResampleChange, noResampleChange))
+		s.Data = applyToWeights(s.Data, resampleOnDates(dates, beginIncomplete, endIncomplete), metaTransform(noStringChange, noStringChange, noResampleChange, noResampleChange))
 
-	return s
+		return s
+	}
 }
 
-func rebalanceYearly(s SingleEntityData) SingleEntityData {
-	startDate, endDate := getStartEndDatesForEntity(s)
-	dates, beginIncomplete, endIncomplete := getYearlyDates(startDate, endDate)
+func rebalanceYearly(asOf time.Time) func(SingleEntityData) SingleEntityData {
+	return func(s SingleEntityData) SingleEntityData {
+		startDate, endDate := getStartEndDatesForEntity(s, asOf)
+		dates, beginIncomplete, endIncomplete := getYearlyDates(startDate, endDate)
 
-	s.Data = applyToWeights(s.Data, resampleOnDates(dates, beginIncomplete, endIncomplete), metaTransform(noStringChange, noStringChange, noResampleChange, noResampleChange))
+		s.Data = applyToWeights(s.Data, resampleOnDates(dates, beginIncomplete, endIncomplete), metaTransform(noStringChange, noStringChange, noResampleChange, noResampleChange))
 
-	return s
+		return s
+	}
 }
 
-func tsDaily(endDate time.Time) func(SingleEntityData) SingleEntityData {
+func tsDaily(endDate time.Time, asOf time.Time) func(SingleEntityData) SingleEntityData {
 	return func(s SingleEntityData) SingleEntityData {
-		startDate, _ := getStartEndDatesForEntity(s)
+		startDate, _ := getStartEndDatesForEntity(s, asOf)
 		dates := getDaysBetween(startDate, endDate)
 
 		s.Data = applyToSeries(s.Data, resampleOnDatesFast(dates, false, false), metaTransform(noStringChange, noStringChange, noResampleChange, noResampleChange))
@@ -3425,9 +4110,9 @@ func tsDaily(endDate time.Time) func(SingleEntityData) SingleEntityData {
 	}
 }
 
-func tsByWeek(endDate time.Time) func(SingleEntityData) SingleEntityData {
+func tsByWeek(endDate time.Time, asOf time.Time) func(SingleEntityData) SingleEntityData {
 	return func(s SingleEntityData) SingleEntityData {
-		startDate, _ := getStartEndDatesForEntity(s)
+		startDate, _ := getStartEndDatesForEntity(s, asOf)
 		dates, beginIncomplete, endIncomplete := getWeeklyDatesBetween(startDate, endDate)
 
 		s.Data = applyToSeries(s.Data, resampleOnDatesFast(dates, beginIncomplete, endIncomplete), metaTransform(noStringChange, noStringChange, noResampleChange, noResampleChange))
@@ -3436,9 +4121,9 @@ func tsByWeek(endDate time.Time) func(SingleEntityData) SingleEntityData {
 	}
 }
 
-func tsByMonth(endDate time.Time) func(SingleEntityData) SingleEntityData {
+func tsByMonth(endDate time.Time, asOf time.Time) func(SingleEntityData) SingleEntityData {
 	return func(s SingleEntityData) SingleEntityData {
-		startDate, _ := getStartEndDatesForEntity(s)
+		startDate, _ := getStartEndDatesForEntity(s, asOf)
 		dates, beginIncomplete, endIncomplete := getMonthlyDates(startDate, endDate)
 
 		s.Data = applyToSeries(s.Data, resampleOnDatesFast(dates, beginIncomplete, endIncomplete), metaTransform(noStringChange, noStringChange, noResampleChange, noResampleChange))
@@ -3447,9 +4132,9 @@ func tsByMonth(endDate time.Time) func(SingleEntityData) SingleEntityData {
 	}
 }
 
-func tsByQuarter(endDate time.Time) func(SingleEntityData) SingleEntityData {
+func tsByQuarter(endDate time.Time, asOf time.Time) func(SingleEntityData) SingleEntityData {
 	return func(s SingleEntityData) SingleEntityData {
-		startDate, _ := getStartEndDatesForEntity(s)
+		startDate, _ := getStartEndDatesForEntity(s, asOf)
 		dates, beginIncomplete, endIncomplete := getQuarterlyDates(startDate, endDate)
 
 		s.Data = applyToSeries(s.Data, resampleOnDatesFast(dates, beginIncomplete, endIncomplete), metaTransform(noStringChange, noStringChange, noResampleChange, noResampleChange))
@@ -3458,9 +4143,9 @@ func tsByQuarter(endDate time.Time) func(SingleEntityData) SingleEntityData {
 	}
 }
 
-func tsByYear(endDate time.Time) func(SingleEntityData) SingleEntityData {
+func tsByYear(endDate time.Time, asOf time.Time) func(SingleEntityData) SingleEntityData {
 	return func(s SingleEntityData) SingleEntityData {
-		startDate, _ := getStartEndDatesForEntity(s)
+		startDate, _ := getStartEndDatesForEntity(s, asOf)
 		dates, beginIncomplete, endIncomplete := getYearlyDates(startDate, endDate)
 
 		s.Data = applyToSeries(s.Data, resampleOnDatesFast(dates, beginIncomplete, endIncomplete), metaTransform(noStringChange, noStringChange, noResampleChange, noResampleChange))
@@ -3469,9 +4154,9 @@ func tsByYear(endDate time.Time) func(SingleEntityData) SingleEntityData {
 	}
 }
 
-func tsAllTime() func(SingleEntityData) SingleEntityData {
+func tsAllTime(asOf time.Time) func(SingleEntityData) SingleEntityData {
 	return func(s SingleEntityData) SingleEntityData {
-		_, endDate := getStartEndDatesForEntity(s)
+		_, endDate := getStartEndDatesForEntity(s, asOf)
 		dates := []time.Time{endDate}
 
 		s.Data = applyToSeries(s.Data, resampleOnDatesFast(dates, false, false), metaTransform(noStringChange, noStringChange, noResampleChange, noResampleChange))
@@ -4333,7 +5018,7 @@ func TimeSlicer(c []component.QueryComponent) StepFnType {
 			if len(mArr) > 0 {
 				m := mArr[0]
 
-				startDate, endDate := extractStartEndDate(timeRange)
+				startDate, endDate := extractStartEndDate(ctx, timeRange)
 				lastDataPointOnly := timeRange.QueryComponentCanonicalName == "Last Data Point"
 				allAvailable := timeRange.QueryComponentCanonicalName == "All Available"
 
@@ -4359,11 +5044,11 @@ func TimeSlicer(c []component.QueryComponent) StepFnType {
 	}
 }
 
-func getData(dataField component.QueryComponent, timeRange component.QueryComponent) func(context.Context, EntityMeta) Series {
-	return func(ctx context.Context, e EntityMeta) Series {
+func getData(dataField component.QueryComponent, timeRange component.QueryComponent) EntityDataFn {
+	return func(ctx context.Context, e EntityMeta) (Series, error) {
 		var s Series
 
-		startDate, endDate := extractStartEndDate(timeRange)
+		startDate, endDate := extractStartEndDate(ctx, timeRange)
 		lastDataPointOnly := timeRange.QueryComponentCanonicalName == "Last Data Point"
 		allAvailable := timeRange.QueryComponentCanonicalName == "All Available"
 
@@ -4376,15 +5061,19 @@ func getData(dataField component.QueryComponent, timeRange component.QueryCompon
 
 		universeMember := component.QueryComponent{QueryComponentCanonicalName: e.Name, QueryComponentName: e.Name, QueryComponentType: "Universe", QueryComponentProviderId: e.UniqueId, QueryComponentSource: "Quandl Open Data", QueryComponentOriginalString: e.Name}
 
-		ts, _ := data.GetDataFull(ctx, universeMember, dataField)
+		ts, err := data.GetData(ctx, universeMember, dataField)
 
-		s = convertTsToSeries(ts, e.IsCustom, dataField.QueryComponentOriginalString, lastDataPointOnly, allAvailable, startDate, endDate)
+		s = convertTsToSeries(ctx, ts, e.IsCustom, dataField.QueryComponentOriginalString, lastDataPointOnly, allAvailable, startDate, endDate)
 
-		return s
+		if err == data.ErrNotFound {
+			return s, errNotFound
+		}
+
+		return s, err
 	}
 }
 
-func convertTsToSeries(ts *timeseries.TimeSeries, isCustom bool, fieldName string, lastDataPointOnly bool, allAvailableData bool, startDate time.Time, endDate time.Time) Series {
+func convertTsToSeries(ctx context.Context, ts *timeseries.TimeSeries, isCustom bool, fieldName string, lastDataPointOnly bool, allAvailableData bool, startDate time.Time, endDate time.Time) Series {
 	s := Series{}
 
 	if ts == nil {
@@ -4398,6 +5087,9 @@ func convertTsToSeries(ts *timeseries.TimeSeries, isCustom bool, fieldName strin
 		s.Data[i] = DataPoint{Time: ts.Date[i].UTC(), Data: ts.Data[i]}
 	}
 
+	// The last data point of a point in time run is the last one known then
+	s.Data = truncateAfterAsOf(ctx, s.Data)
+
 	if lastDataPointOnly {
 		// If we only want the last data point
 		if len(s.Data) > 0 {
@@ -4434,6 +5126,9 @@ func convertTsToSeries(ts *timeseries.TimeSeries, isCustom bool, fieldName strin
 }
 
 // Functions that operate on MultiEntityData
+
+// ExcludeData keeps the entities of the first input that are in none of the
+// others
 func ExcludeData(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
 	if len(mArr) == 0 {
 		return MultiEntityData{}
@@ -4441,14 +5136,21 @@ func ExcludeData(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
 		return MultiEntityData{}
 	}
 
+	m := combineLeft(ctx, mArr, excludePair)
+	m.Title = mArr[0].Title + " excluding " + joinTitles(mArr[1:])
+
+	return m
+}
+
+func excludePair(ctx context.Context, a MultiEntityData, b MultiEntityData) MultiEntityData {
 	m := MultiEntityData{}
 	m.EntityData = make([]SingleEntityData, 0)
 
-	m.Title = mArr[0].Title + " excluding " + mArr[1].Title
-	m.Error = mArr[0].Error + ", " + mArr[1].Error
+	m.Title = a.Title + " excluding " + b.Title
+	m.Error = combineErrors(a.Error, b.Error)
 
-	for _, v := range mArr[0].EntityData {
-		ix := indexOfEntity(v.Meta, mArr[1])
+	for _, v := range a.EntityData {
+		ix := indexOfEntity(v.Meta, b)
 
 		//marshalOutput("ix", ix)
 		if ix < 0 {
@@ -4457,7 +5159,7 @@ func ExcludeData(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
 			//marshalOutput("entity doesn't exist. Adding", m.EntityData)
 		} else {
 			// Entity exists, add it to the intersection
-			m.EntityData = append(m.EntityData, mArr[1].EntityData[ix])
+			m.EntityData = append(m.EntityData, b.EntityData[ix])
 			//marshalOutput("entity exists. Adding", m.EntityData)
 
 			for _, v2 := range v.Data {
@@ -4475,6 +5177,7 @@ func ExcludeData(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
 	return m.RemoveSuperflousDataAndWeights(true)
 }
 
+// IntersectData keeps the entities that are in every input
 func IntersectData(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
 	if len(mArr) == 0 {
 		return MultiEntityData{}
@@ -4482,20 +5185,27 @@ func IntersectData(ctx context.Context, mArr []MultiEntityData) MultiEntityData
 		return MultiEntityData{}
 	}
 
+	m := combineLeft(ctx, mArr, intersectPair)
+	m.Title = "Intersection of " + joinTitles(mArr)
+
+	return m
+}
+
+func intersectPair(ctx context.Context, a MultiEntityData, b MultiEntityData) MultiEntityData {
 	m := MultiEntityData{}
 	m.EntityData = make([]SingleEntityData, 0)
 
-	m.Title = "Intersection of " + mArr[0].Title + " and " + mArr[1].Title
-	m.Error = mArr[0].Error + ", " + mArr[1].Error
+	m.Title = "Intersection of " + a.Title + " and " + b.Title
+	m.Error = combineErrors(a.Error, b.Error)
 
-	for _, v := range mArr[1].EntityData {
-		ix := indexOfEntity(v.Meta, mArr[0])
+	for _, v := range b.EntityData {
+		ix := indexOfEntity(v.Meta, a)
 
 		if ix < 0 {
 			// Entity doesn't exist, we don't add
 		} else {
 			// Entity exists, add it to the intersection
-			m.EntityData = append(m.EntityData, mArr[0].EntityData[ix])
+			m.EntityData = append(m.EntityData, a.EntityData[ix])
 
 			for _, v2 := range v.Data {
 				if !v2.IsWeight {
@@ -4637,7 +5347,7 @@ func ResampleToLowestFrequency(ctx context.Context, mArr []MultiEntityData) Mult
 
 	}
 
-	resampleFunc := func(_ time.Time) func(s SingleEntityData) SingleEntityData {
+	resampleFunc := func(_ time.Time, _ time.Time) func(s SingleEntityData) SingleEntityData {
 		return func(s SingleEntityData) SingleEntityData {
 			s.Data = applyToSeries(s.Data, minResampler, metaTransform(noStringChange, noStringChange, noResampleChange, noResampleChange))
 
@@ -4712,16 +5422,26 @@ func RatioData(c []component.QueryComponent) StepFnType {
 	}
 }
 
+// DifferenceData subtracts the first series of each later input from the
+// series of the first input in turn
 func DifferenceData(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
 	if len(mArr) == 0 {
 		return MultiEntityData{}
-	} else if len(mArr) == 1 || len(mArr[1].EntityData) == 0 || len(mArr[1].EntityData[0].Data) == 0 {
+	} else if len(mArr) == 1 {
 		return mArr[0]
 	}
 
-	indexData := mArr[1].EntityData[0].Data[0]
+	return combineLeft(ctx, mArr, differencePair)
+}
+
+func differencePair(ctx context.Context, a MultiEntityData, b MultiEntityData) MultiEntityData {
+	if len(b.EntityData) == 0 || len(b.EntityData[0].Data) == 0 {
+		return a
+	}
+
+	indexData := b.EntityData[0].Data[0]
 
-	for j, s := range mArr[0].EntityData {
+	for j, s := range a.EntityData {
 		for i, _ := range s.Data {
 			if s.Data[i].IsWeight == true {
 				continue
@@ -4743,12 +5463,14 @@ func DifferenceData(ctx context.Context, mArr []MultiEntityData) MultiEntityData
 			)
 		}
 
-		mArr[0].EntityData[j].Data = s.Data
+		a.EntityData[j].Data = s.Data
 	}
 
-	return mArr[0]
+	return a
 }
 
+// UnionData keeps the entities that are in any input, in the order they
+// first appear
 func UnionData(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
 	if len(mArr) == 0 {
 		return MultiEntityData{}
@@ -4756,12 +5478,16 @@ func UnionData(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
 		return mArr[0]
 	}
 
-	m := mArr[0]
+	return combineLeft(ctx, mArr, unionPair)
+}
+
+func unionPair(ctx context.Context, a MultiEntityData, b MultiEntityData) MultiEntityData {
+	m := a
 
-	m.Title = mArr[0].Title + ", " + mArr[1].Title
-	m.Error = mArr[0].Error + ", " + mArr[1].Error
+	m.Title = a.Title + ", " + b.Title
+	m.Error = combineErrors(a.Error, b.Error)
 
-	for _, v := range mArr[1].EntityData {
+	for _, v := range b.EntityData {
 		ix := indexOfEntity(v.Meta, m)
 
 		if ix < 0 {
@@ -4781,6 +5507,42 @@ func UnionData(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
 	return m
 }
 
+// combineLeft combines the inputs two at a time from left to right, so that
+// a, b and c are combined as (a with b) with c
+func combineLeft(ctx context.Context, mArr []MultiEntityData, combine func(context.Context, MultiEntityData, MultiEntityData) MultiEntityData) MultiEntityData {
+	m := mArr[0]
+
+	for _, v := range mArr[1:] {
+		m = combine(ctx, m, v)
+	}
+
+	return m
+}
+
+func combineErrors(a string, b string) string {
+	if a == "" {
+		return b
+	} else if b == "" {
+		return a
+	}
+
+	return a + ", " + b
+}
+
+// joinTitles lists the titles as "a, b and c"
+func joinTitles(mArr []MultiEntityData) string {
+	titles := make([]string, len(mArr))
+	for i, v := range mArr {
+		titles[i] = v.Title
+	}
+
+	if len(titles) < 2 {
+		return strings.Join(titles, "")
+	}
+
+	return strings.Join(titles[:len(titles)-1], ", ") + " and " + titles[len(titles)-1]
+}
+
 func findWeightIndices(e1 SingleEntityData, e2 SingleEntityData) (int, int) {
 	var w1Ix int = -1
 	var w2Ix int = -1
@@ -4952,7 +5714,11 @@ func Alpha(numMonths float64) StepFnType {
 			m.EntityData[i].Data = make([]Series, 0)
 
 			for _, v2 := range v.Data {
-				s := rollingRegression(v2, independent, int(numMonths))
+				if ctx.Err() != nil {
+					return stoppedData(ctx)
+				}
+
+				s := rollingRegression(ctx, v2, independent, int(numMonths))
 
 				m.EntityData[i].Data = append(m.EntityData[i].Data, s)
 
@@ -5044,7 +5810,7 @@ func containsInfOrNan(r regression.Regression) bool {
 	return false
 }
 
-func rollingRegression(s Series, independent MultiEntityData, numMonths int) Series {
+func rollingRegression(ctx context.Context, s Series, independent MultiEntityData, numMonths int) Series {
 	var r regression.Regression
 	var alpha Series
 
@@ -5089,6 +5855,10 @@ func rollingRegression(s Series, independent MultiEntityData, numMonths int) Ser
 
 	// Add additional data points, but remove the first data point
 	for j := i; j < len(s.Data); j++ {
+		if ctx.Err() != nil {
+			break
+		}
+
 		v = s.Data[j]
 		variables, temp = getDataArrayExact(v.Time, independent)
 
@@ -5237,12 +6007,17 @@ func alignDataPoints(dates []time.Time, m MultiEntityData) MultiEntityData {
 	return m
 }
 
-func GetUniverseData(fn func(context.Context, EntityMeta) Series) StepFnType {
+func GetUniverseData(fn EntityDataFn) StepFnType {
 	return func(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
 		m := mArr[0]
 
-		for i, v := range m.EntityData {
-			newData := fn(ctx, v.Meta)
+		fetched, complete := fetchEntities(ctx, m.EntityData, fn)
+		if !complete {
+			m.Incomplete = true
+		}
+
+		for i, _ := range m.EntityData {
+			newData := fetched[i]
 
 			if m.EntityData[i].Meta.IsCustom {
 				m.EntityData[i].Meta.Name = newData.Meta.Label
@@ -5256,12 +6031,17 @@ func GetUniverseData(fn func(context.Context, EntityMeta) Series) StepFnType {
 	}
 }
 
-func SetWeights(fn func(context.Context, EntityMeta) Series) StepFnType {
+func SetWeights(fn EntityDataFn) StepFnType {
 	return func(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
 		m := mArr[0]
 
-		for i, v := range m.EntityData {
-			newData := fn(ctx, v.Meta)
+		fetched, complete := fetchEntities(ctx, m.EntityData, fn)
+		if !complete {
+			m.Incomplete = true
+		}
+
+		for i, _ := range m.EntityData {
+			newData := fetched[i]
 			newData.IsWeight = true
 
 			m.EntityData[i].Data = append(m.EntityData[i].Data, newData)
@@ -5302,19 +6082,19 @@ func GetBulkData(c []component.QueryComponent) StepFnType {
 			}
 		}
 
-		startDate, endDate := extractStartEndDate(timeRange)
+		startDate, endDate := extractStartEndDate(ctx, timeRange)
 		// lastDataPointOnly := timeRange.QueryComponentCanonicalName == "Last Data Point"
 		// allAvailable := timeRange.QueryComponentCanonicalName == "All Available"
 
-		return GetSQLData(ctx, m.GetEntities(), queryName.GetLabel(), paramMap, startDate, endDate)
+		return GetSQLData(ctx, m.GetEntities(), queryName.GetLabel(), paramMap, startDate, endDate).truncateAfterAsOf(ctx)
 	}
 }
 
-func WrapUniverseData(majorFn func(fn func(context.Context, EntityMeta) Series) StepFnType, fn func(component.QueryComponent, component.QueryComponent) func(context.Context, EntityMeta) Series) func([]component.QueryComponent) StepFnType {
+func WrapUniverseData(majorFn func(fn EntityDataFn) StepFnType, fn func(component.QueryComponent, component.QueryComponent) EntityDataFn) func([]component.QueryComponent) StepFnType {
 	return func(c []component.QueryComponent) StepFnType {
 		dataField := c[0]
 
-		dataFn := fn(dataField, c[1])
+		dataFn := RateLimited(dataField.QueryComponentSource, fn(dataField, c[1]))
 
 		return majorFn(dataFn)
 	}
@@ -5330,17 +6110,22 @@ func WrapEvent(majorFn func(fn func(EntityMeta) (Series, CategorySeries)) StepFn
 	}
 }
 
-func extractStartEndDate(c component.QueryComponent) (time.Time, time.Time) {
+func extractStartEndDate(ctx context.Context, c component.QueryComponent) (time.Time, time.Time) {
 	//m, _ := json.Marshal(c)
 	//fmt.Printf("c=%s\n", m)
 
-	c = build.SetTimeRangeParameters(c)
+	c = build.SetTimeRangeParameters(c, AsOf(ctx))
 
 	if len(c.QueryComponentParams) == 2 {
 		startDate, _ := time.Parse("2006-01-02", c.QueryComponentParams[0])
 
 		endDate, _ := time.Parse("2006-01-02", c.QueryComponentParams[1])
 
+		// Explicit ranges can't reach past the date of a point in time run
+		if asOf, ok := pointInTime(ctx); ok && endDate.After(asOf) {
+			endDate = asOf
+		}
+
 		return startDate, endDate
 	}
 
@@ -5385,7 +6170,7 @@ func WrapUniverseArgument(fn func(context.Context, component.QueryComponent) []E
 	}
 }
 
-func WrapPortfolioArgument(membersFn func(context.Context, component.QueryComponent) []EntityMeta, weightsFn func(component.QueryComponent) func(context.Context, EntityMeta) Series) func([]component.QueryComponent) StepFnType {
+func WrapPortfolioArgument(membersFn func(context.Context, component.QueryComponent) []EntityMeta, weightsFn func(component.QueryComponent) EntityDataFn) func([]component.QueryComponent) StepFnType {
 	return func(c []component.QueryComponent) StepFnType {
 		return ComposeStepFn(
 			GetUniverse(c[0], membersFn),
@@ -5405,6 +6190,10 @@ func WrapLatestData() StepFnType {
 		// Run the resampling on all the series
 
 		for i, _ := range m.EntityData {
+			if ctx.Err() != nil {
+				return stoppedData(ctx)
+			}
+
 			m.EntityData[i].Data = resampleSeriesArray(m.EntityData[i].Data, resampleFn)
 		}
 
@@ -5505,15 +6294,21 @@ func WeightsToMacro(ctx context.Context, mArr []MultiEntityData) MultiEntityData
 	return m
 }
 
-func AlignCalendar(tsFunc func(time.Time) func(SingleEntityData) SingleEntityData) StepFnType {
+// AlignCalendar resamples each entity with the function for the last day of
+// the data and the date the run is evaluated on
+func AlignCalendar(tsFunc func(time.Time, time.Time) func(SingleEntityData) SingleEntityData) StepFnType {
 	return func(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
 		m := mArr[0]
 
 		lastDay := m.LastDay()
 
-		alignFn := tsFunc(lastDay)
+		alignFn := tsFunc(lastDay, AsOf(ctx))
 
 		for i, v := range m.EntityData {
+			if ctx.Err() != nil {
+				return stoppedData(ctx)
+			}
+
 			m.EntityData[i] = alignFn(v)
 		}
 
@@ -5521,11 +6316,22 @@ func AlignCalendar(tsFunc func(time.Time) func(SingleEntityData) SingleEntityDat
 	}
 }
 
+// ComputeTSAsOf is ComputeTS for functions of the date the run is evaluated on
+func ComputeTSAsOf(tsFunc func(time.Time) func(SingleEntityData) SingleEntityData) StepFnType {
+	return func(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
+		return ComputeTS(tsFunc(AsOf(ctx)))(ctx, mArr)
+	}
+}
+
 func ComputeTS(tsFunc func(SingleEntityData) SingleEntityData) StepFnType {
 	return func(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
 		m := mArr[0]
 
 		for i, v := range m.EntityData {
+			if ctx.Err() != nil {
+				return stoppedData(ctx)
+			}
+
 			m.EntityData[i] = tsFunc(v)
 		}
 
@@ -5551,12 +6357,24 @@ func flatten(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
 	return m2
 }
 
+// ComputeTSMultiAsOf is ComputeTSMulti for functions of the date the run is
+// evaluated on
+func ComputeTSMultiAsOf(tsFunc func(time.Time) func(SingleEntityData) []SingleEntityData) StepFnType {
+	return func(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
+		return ComputeTSMulti(tsFunc(AsOf(ctx)))(ctx, mArr)
+	}
+}
+
 func ComputeTSMulti(tsFunc func(SingleEntityData) []SingleEntityData) StepFnType {
 	return func(ctx context.Context, mArr []MultiEntityData) MultiEntityData {
 		m := mArr[0]
 		temp := make([]SingleEntityData, 0)
 
 		for _, v := range m.EntityData {
+			if ctx.Err() != nil {
+				return stoppedData(ctx)
+			}
+
 			temp = append(temp, tsFunc(v)...)
 		}
 
@@ -0,0 +1,126 @@
+package run
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/component"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+)
+
+var testStepRunning, testStepMaxRunning int32
+
+// The test step sleeps for the number of milliseconds in its second argument
+// and returns an entity named after its first, or fails when that starts with
+// "fail"
+func init() {
+	RegisterStep(ComputationStep{
+		Type:        "Test Step",
+		MaxChildren: UnlimitedChildren,
+		ComputeFn: func(c []component.QueryComponent) StepFnType {
+			return func(ctx context.Context, m []MultiEntityData) MultiEntityData {
+				n := atomic.AddInt32(&testStepRunning, 1)
+				defer atomic.AddInt32(&testStepRunning, -1)
+
+				for {
+					max := atomic.LoadInt32(&testStepMaxRunning)
+					if n <= max || atomic.CompareAndSwapInt32(&testStepMaxRunning, max, n) {
+						break
+					}
+				}
+
+				ms, _ := strconv.Atoi(c[1].QueryComponentCanonicalName)
+				time.Sleep(time.Duration(ms) * time.Millisecond)
+
+				name := c[0].QueryComponentCanonicalName
+				if strings.HasPrefix(name, "fail") {
+					return MultiEntityData{Error: name + " failed"}
+				}
+
+				return MultiEntityData{EntityData: []SingleEntityData{{Meta: EntityMeta{Name: name}}}}
+			}
+		},
+	})
+}
+
+func testNode(name string, ms int, children ...ExecutionNode) ExecutionNode {
+	return ExecutionNode{
+		Type: "Test Step",
+		Arguments: []component.QueryComponent{
+			{QueryComponentCanonicalName: name},
+			{QueryComponentCanonicalName: strconv.Itoa(ms)},
+		},
+		Children: children,
+	}
+}
+
+func testContext(t *testing.T) context.Context {
+	memo := MemoEnabled
+	MemoEnabled = false
+	t.Cleanup(func() { MemoEnabled = memo })
+
+	return platform.WithServices(context.Background(), platform.Local(nil))
+}
+
+func TestExecuteChildrenKeepsOrder(t *testing.T) {
+	ctx := testContext(t)
+
+	// The first children finish last
+	e := testNode("parent", 0, testNode("a", 40), testNode("b", 30), testNode("c", 20), testNode("d", 10), testNode("e", 0))
+
+	data := e.executeChildren(ctx, "run")
+
+	for i, v := range data {
+		if want := string(rune('a' + i)); len(v.EntityData) != 1 || v.EntityData[0].Meta.Name != want {
+			t.Errorf("child %d = %+v, want %s", i, v.EntityData, want)
+		}
+	}
+}
+
+func TestExecuteChildrenPropagatesErrors(t *testing.T) {
+	ctx := testContext(t)
+
+	e := testNode("parent", 0, testNode("a", 10), testNode("fail b", 0), testNode("c", 0))
+
+	data := e.executeChildren(ctx, "run")
+
+	if data[0].Error != "" || data[2].Error != "" {
+		t.Errorf("siblings of the failing child failed: %q %q", data[0].Error, data[2].Error)
+	}
+	if data[1].Error != "fail b failed" {
+		t.Errorf("error of the failing child = %q", data[1].Error)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	for i, v := range e.executeChildren(cancelled, "run") {
+		if v.Error != context.Canceled.Error() {
+			t.Errorf("child %d of a cancelled run = %+v", i, v)
+		}
+	}
+}
+
+func TestExecuteChildrenLimitsTheRun(t *testing.T) {
+	ctx := testContext(t)
+
+	defer func(n int) { MaxParallelChildren = n }(MaxParallelChildren)
+	MaxParallelChildren = 3
+	atomic.StoreInt32(&testStepMaxRunning, 0)
+
+	// Each of the four subtrees would run four leaves at once with a limit per node
+	subtree := func(name string) ExecutionNode {
+		return testNode(name, 0, testNode(name+"1", 20), testNode(name+"2", 20), testNode(name+"3", 20), testNode(name+"4", 20))
+	}
+	e := testNode("root", 0, subtree("a"), subtree("b"), subtree("c"), subtree("d"))
+
+	e.Execute(ctx, "run", "")
+
+	if max := atomic.LoadInt32(&testStepMaxRunning); max > 3 {
+		t.Errorf("%d steps ran at once with a limit of 3", max)
+	}
+}
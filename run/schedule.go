@@ -0,0 +1,344 @@
+package run
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/db"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+	"github.com/AlphaHat/gcp-alpha-hat/platform/log"
+)
+
+// Schedule re-runs a saved tree whenever its cron expression matches in its
+// time zone
+type Schedule struct {
+	ScheduleId string
+	TreeId     string
+	Cron       string
+	TimeZone   string
+	Enabled    bool
+	Created    time.Time
+	LastRun    time.Time
+	NextRun    time.Time
+	Versions   int
+}
+
+// ScheduledRun is one version of the results of a schedule
+type ScheduledRun struct {
+	ScheduleId string
+	Version    int
+	RunId      string
+	Scheduled  time.Time
+	Queued     time.Time
+}
+
+// ScheduledRunStatus is a version along with the state of its run
+type ScheduledRunStatus struct {
+	ScheduledRun
+	State string
+}
+
+func newScheduleId() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+
+	return hex.EncodeToString(b)
+}
+
+func (s Schedule) location() (*time.Location, error) {
+	if s.TimeZone == "" {
+		return time.UTC, nil
+	}
+
+	return time.LoadLocation(s.TimeZone)
+}
+
+// next works out when the schedule is due after t
+func (s Schedule) next(t time.Time) (time.Time, error) {
+	c, err := parseCron(s.Cron)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	loc, err := s.location()
+	if err != nil {
+		return time.Time{}, errors.New("Unknown time zone " + s.TimeZone)
+	}
+
+	next := c.Next(t.In(loc))
+	if next.IsZero() {
+		return next, errors.New("The schedule " + s.Cron + " never runs")
+	}
+
+	return next.UTC(), nil
+}
+
+// CreateSchedule attaches a schedule to the saved tree with the given id
+func CreateSchedule(ctx context.Context, treeId string, cron string, timeZone string, now time.Time) (Schedule, error) {
+	s := Schedule{
+		ScheduleId: newScheduleId(),
+		TreeId:     treeId,
+		Cron:       cron,
+		TimeZone:   timeZone,
+		Enabled:    true,
+		Created:    now,
+	}
+
+	var tree TreeDummy
+	if err := db.GetFromKey(ctx, treeId, &tree); err != nil {
+		return s, errors.New("No saved tree " + treeId)
+	}
+
+	next, err := s.next(now)
+	if err != nil {
+		return s, err
+	}
+	s.NextRun = next
+
+	if db.DatabaseInsert(ctx, db.Schedule, &s, "") == "" {
+		return s, errors.New("Unable to store the schedule")
+	}
+
+	return s, nil
+}
+
+func getSchedule(ctx context.Context, id string) (Schedule, string, bool) {
+	var s Schedule
+
+	key, err := db.GetFromField(ctx, db.Schedule, "ScheduleId", id, &s)
+
+	if !logError(ctx, err) || key == "" {
+		return s, "", false
+	}
+
+	return s, key, true
+}
+
+// TriggerDueSchedules queues a run for every enabled schedule due at now and
+// returns how many were queued. Every schedule is looked at on each tick. A
+// schedule that missed several times while nothing was ticking runs once.
+func TriggerDueSchedules(ctx context.Context, now time.Time) (int, error) {
+	schedules := make([]Schedule, 0)
+
+	if err := platform.From(ctx).Store.FindAll(ctx, db.Schedule, "", "", 0, &schedules); err != nil {
+		return 0, err
+	}
+
+	triggered := 0
+
+	for _, v := range schedules {
+		if !v.Enabled || v.NextRun.After(now) {
+			continue
+		}
+
+		if err := triggerSchedule(ctx, v.ScheduleId, now); err == errNotDue {
+			continue
+		} else if err != nil {
+			log.Errorf(ctx, "Unable to trigger schedule %s: %s", v.ScheduleId, err)
+			continue
+		}
+
+		triggered++
+	}
+
+	return triggered, nil
+}
+
+// errNotDue is returned by triggerSchedule when another tick got to the
+// schedule first or it has been disabled
+var errNotDue = errors.New("The schedule is not due")
+
+// triggerSchedule copies the saved tree to a new run so that every version
+// keeps its own results
+func triggerSchedule(ctx context.Context, id string, now time.Time) error {
+	s, key, found := getSchedule(ctx, id)
+	if !found {
+		return errors.New("No schedule " + id)
+	}
+
+	var tree TreeDummy
+	if err := db.GetFromKey(ctx, s.TreeId, &tree); err != nil {
+		return err
+	}
+
+	// The schedule is checked and moved on in a transaction so that
+	// overlapping ticks queue each version once. It moves on before the run
+	// is queued so that a failure doesn't make every following tick retry it.
+	var scheduled time.Time
+	store := platform.From(ctx).Store
+	err := store.RunInTransaction(ctx, func(tc context.Context) error {
+		if err := store.Get(tc, key, &s); err != nil {
+			return err
+		}
+
+		if !s.Enabled || s.NextRun.After(now) {
+			return errNotDue
+		}
+
+		next, err := s.next(now)
+		if err != nil {
+			return err
+		}
+
+		scheduled = s.NextRun
+		s.LastRun = now
+		s.NextRun = next
+		s.Versions++
+
+		return store.Update(tc, key, &s)
+	})
+	if err != nil {
+		return err
+	}
+
+	runId := db.DatabaseInsert(ctx, db.RunTree, &tree, "")
+	if runId == "" {
+		return errors.New("Unable to store the tree for version " + strconv.Itoa(s.Versions))
+	}
+
+	createRunRecord(ctx, runId)
+
+	version := ScheduledRun{
+		ScheduleId: s.ScheduleId,
+		Version:    s.Versions,
+		RunId:      runId,
+		Scheduled:  scheduled,
+		Queued:     now,
+	}
+	db.DatabaseInsert(ctx, db.ScheduledRun, &version, "")
+
+	if err := queueRun(ctx, runId); err != nil {
+		setRunState(ctx, runId, RunFailed, "Unable to queue run: "+err.Error(), "")
+		return err
+	}
+
+	log.Infof(ctx, "Schedule %s queued version %d as %s", s.ScheduleId, s.Versions, runId)
+
+	return nil
+}
+
+// ScheduleHistory lists the versions of a schedule, latest first
+func ScheduleHistory(ctx context.Context, id string) ([]ScheduledRunStatus, error) {
+	runs := make([]ScheduledRun, 0)
+
+	if err := db.GetAllFromField(ctx, db.ScheduledRun, "ScheduleId", id, &runs); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].Version > runs[j].Version
+	})
+
+	history := make([]ScheduledRunStatus, len(runs))
+	for i, v := range runs {
+		history[i].ScheduledRun = v
+		if rec, _, found := getRunRecord(ctx, v.RunId); found {
+			history[i].State = rec.State
+		}
+	}
+
+	return history, nil
+}
+
+// RunScheduler triggers due schedules every interval until ctx is done. It
+// stands in for the cron job that calls ScheduleTickHandler when the app runs
+// outside of App Engine.
+func RunScheduler(ctx context.Context, interval time.Duration, now func() time.Time) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := TriggerDueSchedules(ctx, now()); err != nil {
+			log.Errorf(ctx, "RunScheduler err = %s", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type scheduleRequest struct {
+	TreeId   string `json:"tree_id"`
+	Cron     string `json:"cron"`
+	TimeZone string `json:"time_zone"`
+}
+
+// ScheduleHandler creates a schedule from a JSON body with the tree id, the
+// cron expression and the time zone
+func ScheduleHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var req scheduleRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "Unable to decode schedule: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	s, err := CreateSchedule(ctx, req.TreeId, req.Cron, req.TimeZone, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	returnJson(ctx, w, s)
+}
+
+// ScheduleStatusHandler returns a schedule and its versions
+func ScheduleStatusHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get(":query")
+
+	s, _, found := getSchedule(ctx, id)
+	if !found {
+		http.Error(w, "No schedule found for "+id, http.StatusNotFound)
+		return
+	}
+
+	history, err := ScheduleHistory(ctx, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	returnJson(ctx, w, struct {
+		Schedule
+		History []ScheduledRunStatus
+	}{s, history})
+}
+
+// DisableScheduleHandler stops a schedule from queueing more runs while
+// keeping its history
+func DisableScheduleHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	id := r.URL.Query().Get(":query")
+
+	s, key, found := getSchedule(ctx, id)
+	if !found {
+		http.Error(w, "No schedule found for "+id, http.StatusNotFound)
+		return
+	}
+
+	s.Enabled = false
+	db.DatabaseUpdate(ctx, &s, key)
+
+	returnJson(ctx, w, s)
+}
+
+// ScheduleTickHandler is called every minute by cron to queue the runs of
+// due schedules
+func ScheduleTickHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	n, err := TriggerDueSchedules(ctx, time.Now())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	returnJson(ctx, w, map[string]int{"triggered": n})
+}
@@ -0,0 +1,165 @@
+package run
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/db"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+)
+
+func TestCronNext(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("no time zone data")
+	}
+
+	cases := []struct {
+		cron string
+		from time.Time
+		want time.Time
+	}{
+		{"0 9 * * mon", time.Date(2026, 10, 14, 12, 0, 0, 0, ny), time.Date(2026, 10, 19, 9, 0, 0, 0, ny)},
+		{"*/15 * * * *", time.Date(2026, 10, 14, 12, 7, 30, 0, time.UTC), time.Date(2026, 10, 14, 12, 15, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2026, 12, 5, 0, 0, 0, 0, time.UTC), time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"30 6 1,15 * 5", time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC), time.Date(2026, 10, 15, 6, 30, 0, 0, time.UTC)},
+	}
+
+	for _, v := range cases {
+		c, err := parseCron(v.cron)
+		if err != nil {
+			t.Fatalf("parseCron(%q) err = %s", v.cron, err)
+		}
+
+		if got := c.Next(v.from); !got.Equal(v.want) {
+			t.Errorf("%q after %s = %s, want %s", v.cron, v.from, got, v.want)
+		}
+	}
+
+	if _, err := parseCron("0 25 * * *"); err == nil {
+		t.Errorf("parseCron accepted hour 25")
+	}
+}
+
+func TestSchedulerQueuesVersions(t *testing.T) {
+	var mutex sync.Mutex
+	queued := make([]string, 0)
+
+	services := platform.Local(map[string]platform.TaskFn{
+		"/apiv1/worker": func(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			queued = append(queued, r.FormValue("id"))
+			mutex.Unlock()
+		},
+	})
+	ctx := platform.WithServices(context.Background(), services)
+
+	treeId := db.DatabaseInsert(ctx, db.RunTree, &TreeDummy{Tree: []byte(`{"Type":"Get Universe"}`)}, "")
+
+	start := time.Date(2026, 10, 12, 8, 0, 0, 0, time.UTC)
+	s, err := CreateSchedule(ctx, treeId, "0 9 * * 1", "UTC", start)
+	if err != nil {
+		t.Fatalf("CreateSchedule err = %s", err)
+	}
+
+	if n, _ := TriggerDueSchedules(ctx, start.Add(30*time.Minute)); n != 0 {
+		t.Errorf("triggered %d runs before the schedule was due", n)
+	}
+
+	// The local loop stands in for cron, with a clock that moves a week a tick
+	var clockMutex sync.Mutex
+	now := start
+	clock := func() time.Time {
+		clockMutex.Lock()
+		defer clockMutex.Unlock()
+		now = now.AddDate(0, 0, 7)
+		return now
+	}
+
+	loopCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		RunScheduler(loopCtx, time.Millisecond, clock)
+		close(done)
+	}()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		history, _ := ScheduleHistory(ctx, s.ScheduleId)
+		if len(history) >= 2 {
+			break
+		}
+		if time.Now().After(deadline) {
+			cancel()
+			t.Fatalf("the scheduler queued %d versions in 10s", len(history))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	services.Queue.(*platform.LocalQueue).Wait()
+
+	history, err := ScheduleHistory(ctx, s.ScheduleId)
+	if err != nil {
+		t.Fatalf("ScheduleHistory err = %s", err)
+	}
+
+	if history[0].Version <= history[1].Version {
+		t.Errorf("history is not latest first: %d then %d", history[0].Version, history[1].Version)
+	}
+
+	if history[0].RunId == history[1].RunId || history[0].RunId == treeId {
+		t.Errorf("versions should have runs of their own, got %s and %s", history[0].RunId, history[1].RunId)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if len(queued) != len(history) {
+		t.Errorf("queued %d runs for %d versions", len(queued), len(history))
+	}
+}
+
+func TestOverlappingTicksQueueOnce(t *testing.T) {
+	services := platform.Local(map[string]platform.TaskFn{
+		"/apiv1/worker": func(ctx context.Context, w http.ResponseWriter, r *http.Request) {},
+	})
+	ctx := platform.WithServices(context.Background(), services)
+
+	treeId := db.DatabaseInsert(ctx, db.RunTree, &TreeDummy{Tree: []byte(`{"Type":"Get Universe"}`)}, "")
+
+	start := time.Date(2026, 10, 12, 8, 0, 0, 0, time.UTC)
+	s, err := CreateSchedule(ctx, treeId, "0 9 * * 1", "UTC", start)
+	if err != nil {
+		t.Fatalf("CreateSchedule err = %s", err)
+	}
+
+	due := start.Add(2 * time.Hour)
+	counts := make(chan int, 4)
+	var wg sync.WaitGroup
+	for i := 0; i < cap(counts); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n, _ := TriggerDueSchedules(ctx, due)
+			counts <- n
+		}()
+	}
+	wg.Wait()
+	close(counts)
+	services.Queue.(*platform.LocalQueue).Wait()
+
+	total := 0
+	for n := range counts {
+		total += n
+	}
+
+	history, _ := ScheduleHistory(ctx, s.ScheduleId)
+	if total != 1 || len(history) != 1 {
+		t.Errorf("overlapping ticks triggered %d runs and %d versions, want 1", total, len(history))
+	}
+}
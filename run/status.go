@@ -0,0 +1,245 @@
+package run
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/db"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+	"github.com/AlphaHat/gcp-alpha-hat/platform/log"
+	"github.com/AlphaHat/gcp-alpha-hat/track"
+)
+
+const (
+	RunQueued    = "queued"
+	RunRunning   = "running"
+	RunFailed    = "failed"
+	RunCancelled = "cancelled"
+	RunTimedOut  = "timed out"
+	RunCompleted = "completed"
+	// RunCompletedWithErrors is a run whose result was stored although some of
+	// its nodes failed, so the partial result can still be served
+	RunCompletedWithErrors = "completed with errors"
+)
+
+// validTransitions lists the states a run can move to from each state. A
+// finished run can be queued again when it is re-run or retried.
+var validTransitions = map[string][]string{
+	RunQueued:    []string{RunRunning, RunFailed, RunCancelled},
+	RunRunning:   []string{RunCompleted, RunCompletedWithErrors, RunFailed, RunCancelled, RunTimedOut, RunQueued},
+	RunFailed:    []string{RunQueued, RunRunning},
+	RunCancelled: []string{RunQueued},
+	RunTimedOut:  []string{RunQueued, RunRunning},
+	RunCompleted: []string{RunQueued},
+
+	RunCompletedWithErrors: []string{RunQueued},
+}
+
+// RunRecord is the persisted state of a run
+type RunRecord struct {
+	RunId       string
+	State       string
+	Queued      time.Time
+	Started     time.Time
+	Finished    time.Time
+	Error       string `datastore:",noindex"`
+	FailingNode string `datastore:",noindex"`
+}
+
+// RunStatus is returned while a run is not complete. It embeds the progress
+// so clients polling for Message and PercentComplete keep working.
+type RunStatus struct {
+	track.TrackData
+	State       string    `json:",omitempty"`
+	Queued      time.Time `json:",omitempty"`
+	Started     time.Time `json:",omitempty"`
+	Finished    time.Time `json:",omitempty"`
+	Error       string    `json:",omitempty"`
+	FailingNode string    `json:",omitempty"`
+}
+
+func canTransition(from string, to string) bool {
+	for _, v := range validTransitions[from] {
+		if v == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runRecordKey is the key of the record of a run, a child of the run's tree
+// so that there is only ever one
+func runRecordKey(ctx context.Context, id string) (string, error) {
+	return platform.From(ctx).Store.NamedKey(ctx, db.RunStatus, id, "state")
+}
+
+func getRunRecord(ctx context.Context, id string) (RunRecord, string, bool) {
+	var rec RunRecord
+
+	key, err := runRecordKey(ctx, id)
+	if !logError(ctx, err) {
+		return rec, "", false
+	}
+
+	err = db.GetFromKey(ctx, key, &rec)
+	if err == platform.ErrNoSuchDocument || !logError(ctx, err) {
+		return rec, "", false
+	}
+
+	return rec, key, true
+}
+
+func createRunRecord(ctx context.Context, id string) {
+	rec := RunRecord{
+		RunId:  id,
+		State:  RunQueued,
+		Queued: time.Now(),
+	}
+
+	key, err := runRecordKey(ctx, id)
+	if logError(ctx, err) {
+		logError(ctx, platform.From(ctx).Store.Put(ctx, key, &rec))
+	}
+}
+
+// setRunState moves a run to a new state, ignoring transitions that are not
+// allowed. The state is checked and changed in a transaction so that two
+// instances can't both move the run on from the same state.
+func setRunState(ctx context.Context, id string, state string, errMessage string, failingNode string) {
+	key, err := runRecordKey(ctx, id)
+	if !logError(ctx, err) {
+		return
+	}
+
+	store := platform.From(ctx).Store
+	err = store.RunInTransaction(ctx, func(tc context.Context) error {
+		var rec RunRecord
+
+		err := store.Get(tc, key, &rec)
+		if err == platform.ErrNoSuchDocument {
+			rec = RunRecord{RunId: id, State: state}
+		} else if err != nil {
+			return err
+		} else if !canTransition(rec.State, state) {
+			log.Warningf(ctx, "Run %s cannot move from %s to %s", id, rec.State, state)
+			return nil
+		}
+
+		rec.State = state
+
+		switch state {
+		case RunQueued:
+			rec.Queued = time.Now()
+			rec.Started = time.Time{}
+			rec.Finished = time.Time{}
+			rec.Error = ""
+			rec.FailingNode = ""
+		case RunRunning:
+			rec.Started = time.Now()
+		default:
+			rec.Finished = time.Now()
+			rec.Error = errMessage
+			rec.FailingNode = failingNode
+		}
+
+		return store.Put(tc, key, &rec)
+	})
+
+	logError(ctx, err)
+}
+
+// runStatus reports the state of a run and whether its results can be served.
+// Runs queued before run records existed fall back to the progress tracker.
+func runStatus(ctx context.Context, id string) (RunStatus, bool) {
+	details := track.GetDetails(ctx, id)
+	rec, _, found := getRunRecord(ctx, id)
+
+	if !found {
+		status := RunStatus{TrackData: details}
+		return status, details.Message == "" || details.PercentComplete >= 0.999
+	}
+
+	status := RunStatus{
+		TrackData:   details,
+		State:       rec.State,
+		Queued:      rec.Queued,
+		Started:     rec.Started,
+		Finished:    rec.Finished,
+		Error:       rec.Error,
+		FailingNode: rec.FailingNode,
+	}
+
+	switch rec.State {
+	case RunQueued:
+		status.Message = "Queued"
+	case RunFailed:
+		status.Message = "Failed: " + rec.Error
+	case RunCancelled:
+		status.Message = "Cancelled"
+	case RunTimedOut:
+		status.Message = "Timed out: " + rec.Error
+	case RunCompletedWithErrors:
+		status.Message = "Completed with errors: " + rec.Error
+	}
+
+	return status, rec.State == RunCompleted || rec.State == RunCompletedWithErrors
+}
+
+type failureKey struct{}
+
+// runFailure keeps the first node of a run that produced an error
+type runFailure struct {
+	mutex    sync.Mutex
+	node     string
+	err      string
+	timedOut bool
+}
+
+func withRunFailure(ctx context.Context) (context.Context, *runFailure) {
+	f := &runFailure{}
+
+	return context.WithValue(ctx, failureKey{}, f), f
+}
+
+func noteFailure(ctx context.Context, node string, err string) {
+	f, ok := ctx.Value(failureKey{}).(*runFailure)
+
+	if !ok || err == "" {
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.node == "" {
+		f.node = node
+		f.err = err
+	}
+}
+
+// noteTimeout records that a step of the run ran out of time
+func noteTimeout(ctx context.Context, node string, err string) {
+	noteFailure(ctx, node, err)
+
+	if f, ok := ctx.Value(failureKey{}).(*runFailure); ok {
+		f.mutex.Lock()
+		f.timedOut = true
+		f.mutex.Unlock()
+	}
+}
+
+func (f *runFailure) hasTimedOut() bool {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.timedOut
+}
+
+func (f *runFailure) get() (string, string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	return f.node, f.err
+}
@@ -0,0 +1,52 @@
+package run
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlphaHat/gcp-alpha-hat/db"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+)
+
+func TestFailedNodeResultIsServed(t *testing.T) {
+	services := platform.Local(map[string]platform.TaskFn{"/apiv1/worker": WorkerHandler})
+	ctx := platform.WithServices(context.Background(), services)
+
+	// The ref can't be resolved, so the root fails but a result is stored
+	id := db.DatabaseInsert(ctx, db.RunTree, &TreeDummy{Tree: []byte(`{"Ref": "missing"}`)}, "")
+	createRunRecord(ctx, id)
+	if err := queueRun(ctx, id); err != nil {
+		t.Fatalf("queueRun err = %s", err)
+	}
+	services.Queue.(*platform.LocalQueue).Wait()
+
+	status, complete := runStatus(ctx, id)
+	if !complete || status.State != RunCompletedWithErrors || status.Error == "" {
+		t.Fatalf("run ended as %+v, complete = %v", status, complete)
+	}
+
+	w := httptest.NewRecorder()
+	RawHandler(ctx, w, httptest.NewRequest(http.MethodGet, "/?:query="+id, nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), status.Error) {
+		t.Errorf("raw result = %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRunHasOneRecord(t *testing.T) {
+	ctx := platform.WithServices(context.Background(), platform.Local(nil))
+
+	id := db.DatabaseInsert(ctx, db.RunTree, &TreeDummy{Tree: []byte(`{}`)}, "")
+	createRunRecord(ctx, id)
+	setRunState(ctx, id, RunRunning, "", "")
+	setRunState(ctx, id, RunQueued, "", "")
+	createRunRecord(ctx, id)
+
+	var all []RunRecord
+	db.GetAllFromField(ctx, db.RunStatus, "RunId", id, &all)
+	if len(all) != 1 || all[0].State != RunQueued {
+		t.Errorf("records = %+v", all)
+	}
+}
@@ -0,0 +1,181 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/AlphaHat/gcp-alpha-hat/db"
+	"github.com/AlphaHat/gcp-alpha-hat/platform/log"
+)
+
+// TraceNode is what happened when a single node of the tree was executed
+type TraceNode struct {
+	Path           string
+	Type           string
+	Name           string
+	Arguments      []string
+	Started        time.Time
+	StepTimeMs     int64
+	TotalTimeMs    int64
+	InputEntities  int
+	InputSeries    int
+	InputDates     int
+	OutputEntities int
+	OutputSeries   int
+	OutputDates    int
+	CacheHit       bool
+	Error          string `json:",omitempty"`
+}
+
+// RunTrace collects the nodes of a run in the order they finished
+type RunTrace struct {
+	mutex sync.Mutex
+	Nodes []TraceNode
+}
+
+type TraceDummy struct {
+	RunId string
+	Trace []byte
+}
+
+type traceKey struct{}
+type pathKey struct{}
+
+func withRunTrace(ctx context.Context) (context.Context, *RunTrace) {
+	t := &RunTrace{Nodes: make([]TraceNode, 0)}
+
+	return context.WithValue(ctx, traceKey{}, t), t
+}
+
+func (t *RunTrace) add(n TraceNode) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.Nodes = append(t.Nodes, n)
+}
+
+func (t *RunTrace) Marshal() ([]byte, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	return json.Marshal(t.Nodes)
+}
+
+// withNodePath records where in the tree the node being executed sits
+func withNodePath(ctx context.Context, path string) context.Context {
+	return context.WithValue(ctx, pathKey{}, path)
+}
+
+func nodePath(ctx context.Context) string {
+	if path, ok := ctx.Value(pathKey{}).(string); ok {
+		return path
+	}
+
+	return "root"
+}
+
+// sharedPath is where a node with an id is executed, whichever of the places
+// it is used in gets to it first
+func sharedPath(nodeId string) string {
+	return "#" + nodeId
+}
+
+func childPath(ctx context.Context, i int) string {
+	return nodePath(ctx) + ".children[" + strconv.Itoa(i) + "]"
+}
+
+// newTraceNode starts the trace entry for a node with the data handed to it
+func newTraceNode(ctx context.Context, e ExecutionNode, data []MultiEntityData) TraceNode {
+	if _, ok := ctx.Value(traceKey{}).(*RunTrace); !ok {
+		return TraceNode{}
+	}
+
+	n := TraceNode{
+		Path:      nodePath(ctx),
+		Type:      e.Type,
+		Arguments: make([]string, len(e.Arguments)),
+		Started:   time.Now(),
+	}
+
+	for i, v := range e.Arguments {
+		n.Arguments[i] = v.QueryComponentOriginalString
+	}
+
+	if len(e.Arguments) > 0 {
+		n.Name = e.Arguments[0].QueryComponentCanonicalName
+	}
+
+	for _, v := range data {
+		n.InputEntities += v.NumEntities()
+		n.InputSeries += v.NumSeries()
+		n.InputDates += v.NumDates()
+	}
+
+	return n
+}
+
+// recordTrace completes the entry for a node and adds it to the run's trace
+func recordTrace(ctx context.Context, n TraceNode, nodeStart time.Time, stepStart time.Time, med MultiEntityData, cacheHit bool) {
+	t, ok := ctx.Value(traceKey{}).(*RunTrace)
+
+	if !ok {
+		return
+	}
+
+	n.StepTimeMs = int64(time.Since(stepStart) / time.Millisecond)
+	n.TotalTimeMs = int64(time.Since(nodeStart) / time.Millisecond)
+	n.OutputEntities = med.NumEntities()
+	n.OutputSeries = med.NumSeries()
+	n.OutputDates = med.NumDates()
+	n.CacheHit = cacheHit
+	n.Error = med.Error
+
+	t.add(n)
+}
+
+// storeTrace replaces the trace of an earlier execution of the run, so that
+// TraceHandler always finds the latest one
+func storeTrace(ctx context.Context, id string, t *RunTrace) {
+	var m TraceDummy
+
+	key, err := db.GetFromField(ctx, db.RunTrace, "RunId", id, &m)
+	if err != nil {
+		log.Errorf(ctx, "Unable to look up the trace of %s: %s", id, err)
+		return
+	}
+
+	m.RunId = id
+	m.Trace, err = t.Marshal()
+
+	if err != nil {
+		log.Errorf(ctx, "Unable to encode trace for %s: %s", id, err)
+		return
+	}
+
+	if key != "" {
+		db.DatabaseUpdate(ctx, &m, key)
+	} else {
+		db.DatabaseInsert(ctx, db.RunTrace, &m, "")
+	}
+}
+
+func TraceHandler(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get(":query")
+
+	var m TraceDummy
+
+	key, err := db.GetFromField(ctx, db.RunTrace, "RunId", query, &m)
+
+	if !logError(ctx, err) || key == "" {
+		http.Error(w, "No trace found for "+query, http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, "%s\n", m.Trace)
+}
@@ -0,0 +1,34 @@
+package run
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AlphaHat/gcp-alpha-hat/db"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+)
+
+func TestTraceOfReRunReplacesTheFirst(t *testing.T) {
+	ctx := platform.WithServices(context.Background(), platform.Local(nil))
+
+	for _, v := range []string{"First", "Second"} {
+		_, trace := withRunTrace(ctx)
+		trace.add(TraceNode{Path: "root", Name: v})
+		storeTrace(ctx, "run", trace)
+	}
+
+	var traces []TraceDummy
+	platform.From(ctx).Store.FindAll(ctx, db.RunTrace, "RunId", "run", 0, &traces)
+	if len(traces) != 1 {
+		t.Errorf("%d traces stored for one run", len(traces))
+	}
+
+	w := httptest.NewRecorder()
+	TraceHandler(ctx, w, httptest.NewRequest(http.MethodGet, "/?:query=run", nil))
+	if !strings.Contains(w.Body.String(), "Second") || strings.Contains(w.Body.String(), "First") {
+		t.Errorf("trace = %s", w.Body.String())
+	}
+}
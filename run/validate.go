@@ -0,0 +1,255 @@
+package run
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AlphaHat/gcp-alpha-hat/component"
+	"github.com/AlphaHat/gcp-alpha-hat/parse"
+	"github.com/AlphaHat/gcp-alpha-hat/term"
+)
+
+// NodeError describes why a single node of a tree cannot be executed
+type NodeError struct {
+	Path              string   `json:"path"`
+	Type              string   `json:"type"`
+	Name              string   `json:"name"`
+	Error             string   `json:"error"`
+	ExpectedArguments []string `json:"expected_arguments"`
+	Suggestions       []string `json:"suggestions"`
+	// Syntax locates the error in a formula argument
+	Syntax *parse.SyntaxError `json:"syntax,omitempty"`
+}
+
+type ValidationResult struct {
+	Valid  bool        `json:"valid"`
+	Errors []NodeError `json:"errors"`
+}
+
+// Validate checks every node of the tree against the computation steps
+// without executing anything
+func (e ExecutionNode) Validate() ValidationResult {
+	result := ValidationResult{Errors: make([]NodeError, 0)}
+
+	nodes := e.NodeIds()
+
+	e.validate("root", nodes, make(map[string]bool), &result)
+
+	if cycle := e.findCycle(nodes); cycle != nil {
+		result.Errors = append(result.Errors, NodeError{
+			Path:  "root",
+			Error: "Nodes refer to each other in a cycle: " + strings.Join(cycle, " → "),
+		})
+	}
+
+	result.Valid = len(result.Errors) == 0
+
+	return result
+}
+
+func (e ExecutionNode) validate(path string, nodes map[string]ExecutionNode, seen map[string]bool, result *ValidationResult) {
+	if e.Ref != "" {
+		if _, ok := nodes[e.Ref]; !ok {
+			result.Errors = append(result.Errors, NodeError{
+				Path:        path,
+				Error:       "No node with id " + e.Ref,
+				Suggestions: suggestStrings(e.Ref, nodeIdList(nodes)),
+			})
+		}
+
+		if e.Type != "" || len(e.Children) > 0 {
+			result.Errors = append(result.Errors, NodeError{
+				Path:  path,
+				Type:  e.Type,
+				Error: "A node referring to " + e.Ref + " can't have its own step or children",
+			})
+		}
+
+		return
+	}
+
+	if e.Id != "" {
+		if seen[e.Id] {
+			result.Errors = append(result.Errors, NodeError{
+				Path:  path,
+				Type:  e.Type,
+				Error: "The id " + e.Id + " is used by more than one node",
+			})
+		}
+		seen[e.Id] = true
+	}
+
+	if nodeErr, ok := validateNode(path, e); !ok {
+		result.Errors = append(result.Errors, nodeErr)
+	}
+
+	for i, v := range e.Children {
+		v.validate(path+".children["+strconv.Itoa(i)+"]", nodes, seen, result)
+	}
+}
+
+func nodeIdList(nodes map[string]ExecutionNode) []string {
+	ids := make([]string, 0, len(nodes))
+
+	for k, _ := range nodes {
+		ids = append(ids, k)
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+func validateNode(path string, e ExecutionNode) (NodeError, bool) {
+	nodeErr := NodeError{Path: path, Type: e.Type}
+	if len(e.Arguments) > 0 {
+		nodeErr.Name = e.Arguments[0].QueryComponentCanonicalName
+	}
+
+	argcheck, err := findArgcheck(e.Type, e.Arguments)
+
+	if argcheck == nil {
+		nodeErr.Error = err.Error()
+		nodeErr.Suggestions = suggestStrings(e.Type, stepTypes())
+		return nodeErr, false
+	}
+
+	if _, err := retrieveComputationStep(component.MajorType(e.Type), e.Arguments); err != nil && !hasUnnamedStep(e.Type) {
+		nodeErr.Error = err.Error()
+		nodeErr.Suggestions = suggestStrings(nodeErr.Name, stepNames(e.Type))
+		return nodeErr, false
+	}
+
+	if step, _, _ := Steps.LookupOrDefault(component.MajorType(e.Type), e.Arguments); !step.AcceptsChildren(len(e.Children)) {
+		nodeErr.Error = fmt.Sprintf("%s takes %s but has %d", e.Describe(), describeChildren(step), len(e.Children))
+		return nodeErr, false
+	}
+
+	components, err := safeArgcheck(argcheck, e.Arguments)
+
+	if err != nil {
+		nodeErr.Error = err.Error()
+		if syntaxErr, ok := err.(*parse.SyntaxError); ok {
+			nodeErr.Syntax = syntaxErr
+		}
+		nodeErr.ExpectedArguments = make([]string, 0, len(components))
+		nodeErr.Suggestions = make([]string, 0, len(components))
+
+		for _, v := range components {
+			nodeErr.ExpectedArguments = append(nodeErr.ExpectedArguments, v.QueryComponentType)
+			if v.QueryComponentOriginalString != "" {
+				nodeErr.Suggestions = append(nodeErr.Suggestions, v.QueryComponentOriginalString)
+			}
+		}
+
+		return nodeErr, false
+	}
+
+	return nodeErr, true
+}
+
+// safeArgcheck guards against argument checks that index past the components supplied
+func safeArgcheck(argcheck func(MultiEntityData, []component.QueryComponent) ([]component.QueryComponent, error), c []component.QueryComponent) (components []component.QueryComponent, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			components = nil
+			err = fmt.Errorf("Invalid arguments: %v", r)
+		}
+	}()
+
+	return argcheck(MultiEntityData{}, c)
+}
+
+func describeChildren(s ComputationStep) string {
+	switch {
+	case s.MaxChildren == UnlimitedChildren:
+		return fmt.Sprintf("at least %d children", s.MinChildren)
+	case s.MinChildren == s.MaxChildren:
+		return fmt.Sprintf("%d children", s.MinChildren)
+	}
+
+	return fmt.Sprintf("%d to %d children", s.MinChildren, s.MaxChildren)
+}
+
+func hasUnnamedStep(majorType string) bool {
+	_, ok := Steps.Lookup(component.MajorType(majorType), "")
+
+	return ok
+}
+
+func stepTypes() []string {
+	typeMap := make(map[string]bool)
+
+	for _, v := range Steps.All() {
+		typeMap[string(v.Type)] = true
+	}
+
+	types := make([]string, 0, len(typeMap))
+	for k, _ := range typeMap {
+		types = append(types, k)
+	}
+	sort.Strings(types)
+
+	return types
+}
+
+func stepNames(majorType string) []string {
+	names := make([]string, 0)
+
+	for _, v := range Steps.All() {
+		if string(v.Type) == majorType && v.Name != "" {
+			names = append(names, v.Name)
+		}
+	}
+
+	return names
+}
+
+const maxSuggestions = 5
+
+// suggestStrings returns the candidates closest to s, with the ones sharing
+// a word with s first
+func suggestStrings(s string, candidates []string) []string {
+	words := strings.Fields(strings.ToLower(s))
+
+	score := func(candidate string) int {
+		lower := strings.ToLower(candidate)
+		n := 0
+		for _, w := range words {
+			if strings.Contains(lower, w) {
+				n++
+			}
+		}
+		return n
+	}
+
+	sorted := make([]string, len(candidates))
+	copy(sorted, candidates)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return score(sorted[i]) > score(sorted[j])
+	})
+
+	if len(sorted) > maxSuggestions {
+		sorted = sorted[:maxSuggestions]
+	}
+
+	return sorted
+}
+
+func ValidateHandler(ctx context.Context, w http.ResponseWriter, r *http.Request, terms *term.TermData) {
+	decoder := json.NewDecoder(r.Body)
+	var c ExecutionNode
+
+	if err := decoder.Decode(&c); err != nil {
+		http.Error(w, "Unable to decode tree: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.ParseTree(ctx, terms)
+
+	returnJson(ctx, w, c.Validate())
+}
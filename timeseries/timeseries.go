@@ -189,7 +189,7 @@ func convertDateStringToDate(dateStart string, dateEnd string) (time.Time, time.
 	return dateStartTime, dateEndTime
 }
 
-func GetYearlyDatesBetween(dateStart string, dateEnd string) []string {
+func GetYearlyDatesBetween(dateStart string, dateEnd string, asOf time.Time) []string {
 	dateStartTime, dateEndTime := convertDateStringToDate(dateStart, dateEnd)
 
 	years := dateEndTime.Year() - dateStartTime.Year() + 1
@@ -205,14 +205,14 @@ func GetYearlyDatesBetween(dateStart string, dateEnd string) []string {
 
 	// If the range includes this year, don't go to the end of the year,
 	// go to the last business day
-	if dateEndTime.Year() == time.Now().Year() {
-		timePoints[len(timePoints)-1] = GetLastBD()
+	if dateEndTime.Year() == asOf.Year() {
+		timePoints[len(timePoints)-1] = GetLastBD(asOf)
 	}
 
 	return timePoints
 }
 
-func GetQuarterlyDatesBetween(dateStart string, dateEnd string) []string {
+func GetQuarterlyDatesBetween(dateStart string, dateEnd string, asOf time.Time) []string {
 	dateStartTime, error1 := ParseDate(dateStart)
 	dateEndTime, error2 := ParseDate(dateEnd)
 
@@ -252,7 +252,7 @@ func GetQuarterlyDatesBetween(dateStart string, dateEnd string) []string {
 	//}
 
 	if dateStartTime != dateEndTime {
-		timePoints = append(timePoints, GetLastBD())
+		timePoints = append(timePoints, GetLastBD(asOf))
 	}
 
 	return timePoints
@@ -268,7 +268,9 @@ func GetPreviousWeekday(currentDate string) string {
 	}
 }
 
-func parseDateAndSnap(date string, snapMethod string) string {
+// parseDateAndSnap puts dates without a year in the last year that makes
+// them no later than asOf
+func parseDateAndSnap(date string, snapMethod string, asOf time.Time) string {
 	var t time.Time
 	var err error
 
@@ -324,8 +326,8 @@ func parseDateAndSnap(date string, snapMethod string) string {
 		}
 
 		// Add the current year
-		t = t.AddDate(time.Now().Year(), 0, 0)
-		if t.After(time.Now()) {
+		t = t.AddDate(asOf.Year(), 0, 0)
+		if t.After(asOf) {
 			// If the current year makes this a future date, set the year back 1
 			t = t.AddDate(-1, 0, 0)
 		}
@@ -338,8 +340,8 @@ func parseDateAndSnap(date string, snapMethod string) string {
 		}
 
 		// Add the current year
-		t = t.AddDate(time.Now().Year(), 0, 0)
-		if t.After(time.Now()) {
+		t = t.AddDate(asOf.Year(), 0, 0)
+		if t.After(asOf) {
 			// If the current year makes this a future date, set the year back 1
 			t = t.AddDate(-1, 0, 0)
 		}
@@ -351,12 +353,12 @@ func parseDateAndSnap(date string, snapMethod string) string {
 		}
 
 		// Add the current year
-		t = t.AddDate(time.Now().Year(), 0, 0)
+		t = t.AddDate(asOf.Year(), 0, 0)
 		switch snapMethod {
 		case "Last CD":
 			t = t.AddDate(0, 1, -1)
 		}
-		if t.After(time.Now()) {
+		if t.After(asOf) {
 			// If the current year makes this a future date, set the year back 1
 			t = t.AddDate(-1, 0, 0)
 		}
@@ -367,12 +369,12 @@ func parseDateAndSnap(date string, snapMethod string) string {
 			return date
 		}
 		// Add the current year
-		t = t.AddDate(time.Now().Year(), 0, 0)
+		t = t.AddDate(asOf.Year(), 0, 0)
 		switch snapMethod {
 		case "Last CD":
 			t = t.AddDate(0, 1, -1)
 		}
-		if t.After(time.Now()) {
+		if t.After(asOf) {
 			// If the current year makes this a future date, set the year back 1
 			t = t.AddDate(-1, 0, 0)
 		}
@@ -399,37 +401,39 @@ func parseDateAndSnap(date string, snapMethod string) string {
 	return t.String()[0:10]
 }
 
-func ParseToFirstCD(date string) string {
-	return parseDateAndSnap(date, "First CD")
+func ParseToFirstCD(date string, asOf time.Time) string {
+	return parseDateAndSnap(date, "First CD", asOf)
 }
 
-func ParseToLastCD(date string) string {
-	return parseDateAndSnap(date, "Last CD")
+func ParseToLastCD(date string, asOf time.Time) string {
+	return parseDateAndSnap(date, "Last CD", asOf)
 }
 
-func GetLastMonths(n int) (string, string) {
-	lastBDstring := GetLastBD()
+func GetLastMonths(n int, asOf time.Time) (string, string) {
+	lastBDstring := GetLastBD(asOf)
 	lastBD, _ := time.Parse("2006-01-02", lastBDstring)
 
 	return lastBD.AddDate(0, n*-1, 0).String()[0:10], lastBDstring
 }
 
-func GetLTM() (string, string) {
-	lastBDstring := GetLastBD()
+func GetLTM(asOf time.Time) (string, string) {
+	lastBDstring := GetLastBD(asOf)
 	lastBD, _ := time.Parse("2006-01-02", lastBDstring)
 
 	return lastBD.AddDate(-1, 0, 0).String()[0:10], lastBDstring
 }
 
-func GetYTD() (string, string) {
-	lastBDstring := GetLastBD()
+func GetYTD(asOf time.Time) (string, string) {
+	lastBDstring := GetLastBD(asOf)
 	lastBD, _ := time.Parse("2006-01-02", lastBDstring)
 
 	return time.Date(lastBD.Year()-1, 12, 31, 0, 0, 0, 0, lastBD.Location()).String()[0:10], lastBDstring
 }
 
-func GetLastBD() string {
-	t := time.Now()
+// GetLastBD is the business day before asOf, which is time.Now() unless
+// reproducing a past run
+func GetLastBD(asOf time.Time) string {
+	t := asOf
 
 	if t.Weekday() == time.Monday {
 		t = t.AddDate(0, 0, -3)
@@ -4,21 +4,25 @@ import (
 	"context"
 	"encoding/json"
 
-	"google.golang.org/appengine/log"
-	"google.golang.org/appengine/memcache"
+	"github.com/AlphaHat/gcp-alpha-hat/platform"
+	"github.com/AlphaHat/gcp-alpha-hat/platform/log"
 )
 
 type TrackData struct {
 	Message         string
 	PercentComplete float64
+	NodesCompleted  int     `json:",omitempty"`
+	NodesTotal      int     `json:",omitempty"`
+	EtaSeconds      float64 `json:",omitempty"`
 }
 
 func Update(ctx context.Context, query string, message string, percentComplete float64) {
-	item := &memcache.Item{
-		Key:    "track:" + query,
-		Object: TrackData{message, percentComplete},
-	}
-	err := memcache.Gob.Set(ctx, item)
+	UpdateDetails(ctx, query, TrackData{Message: message, PercentComplete: percentComplete})
+}
+
+// UpdateDetails stores the progress along with node counts and the estimated time remaining
+func UpdateDetails(ctx context.Context, query string, details TrackData) {
+	err := platform.CacheSetGob(ctx, "track:"+query, details, 0)
 	if err != nil {
 		log.Errorf(ctx, "track.Update err = %s", err)
 	}
@@ -27,7 +31,7 @@ func Update(ctx context.Context, query string, message string, percentComplete f
 
 func GetData(ctx context.Context, query string) []byte {
 	var item0 TrackData
-	_, err := memcache.Gob.Get(ctx, "track:"+query, &item0)
+	err := platform.CacheGetGob(ctx, "track:"+query, &item0)
 
 	if err != nil {
 		log.Errorf(ctx, "track.GetData err = %s", err)
@@ -42,7 +46,7 @@ func GetData(ctx context.Context, query string) []byte {
 
 func GetDetails(ctx context.Context, query string) TrackData {
 	var item0 TrackData
-	_, err := memcache.Gob.Get(ctx, "track:"+query, &item0)
+	err := platform.CacheGetGob(ctx, "track:"+query, &item0)
 
 	if err != nil {
 		log.Infof(ctx, "track.GetDetails err = %s", err)